@@ -0,0 +1,383 @@
+package main
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+const (
+	weightingPriority = "priority"
+	weightingAHP      = "ahp"
+
+	// ahpConsistencyThreshold — предельное отношение согласованности по Саати.
+	ahpConsistencyThreshold = 0.1
+	ahpSuggestionsLimit     = 3
+)
+
+// ahpRandomIndex — случайный индекс согласованности Саати для матриц размера n (индекс = n-1).
+var ahpRandomIndex = []float64{0, 0, 0.58, 0.90, 1.12, 1.24, 1.32, 1.41, 1.45, 1.49, 1.51, 1.48, 1.56, 1.57, 1.59}
+
+// ahpScale — варианты ответа на попарное сравнение: во сколько раз A важнее B.
+var ahpScale = []struct {
+	Value float64
+	Label string
+}{
+	{9, "A ≫ B (9)"},
+	{5, "A > B (5)"},
+	{3, "A ≥ B (3)"},
+	{1, "Равны"},
+	{1.0 / 3, "B ≥ A (3)"},
+	{1.0 / 5, "B > A (5)"},
+	{1.0 / 9, "B ≫ A (9)"},
+}
+
+// ahpPairs перечисляет пары критериев в порядке, в котором их задает бот.
+func ahpPairs(criteria []string) [][2]string {
+	var pairs [][2]string
+	for i := 0; i < len(criteria); i++ {
+		for j := i + 1; j < len(criteria); j++ {
+			pairs = append(pairs, [2]string{criteria[i], criteria[j]})
+		}
+	}
+	return pairs
+}
+
+// computeAHP строит матрицу попарных сравнений, находит веса как главный собственный
+// вектор и считает отношение согласованности. Для несогласованных ответов возвращает
+// сравнения, сильнее всего расходящиеся с итоговыми весами.
+func computeAHP(criteria []string, judgments []AHPJudgment) *AHPResult {
+	n := len(criteria)
+	result := &AHPResult{Weights: make(map[string]float64, n), WeightsByID: make(map[string]float64, n), Consistent: true}
+	if n == 0 {
+		return result
+	}
+
+	index := make(map[string]int, n)
+	for i, name := range criteria {
+		index[name] = i
+	}
+
+	matrix := make([][]float64, n)
+	for i := range matrix {
+		matrix[i] = make([]float64, n)
+		matrix[i][i] = 1
+	}
+	for _, j := range judgments {
+		a, b := index[j.A], index[j.B]
+		matrix[a][b] = j.Value
+		matrix[b][a] = 1 / j.Value
+	}
+
+	weights := make([]float64, n)
+	for i := range weights {
+		weights[i] = 1 / float64(n)
+	}
+	for iter := 0; iter < 100; iter++ {
+		next := make([]float64, n)
+		sum := 0.0
+		for i := 0; i < n; i++ {
+			for k := 0; k < n; k++ {
+				next[i] += matrix[i][k] * weights[k]
+			}
+			sum += next[i]
+		}
+
+		delta := 0.0
+		for i := range next {
+			next[i] /= sum
+			delta += math.Abs(next[i] - weights[i])
+		}
+		weights = next
+		if delta < 1e-10 {
+			break
+		}
+	}
+
+	lambdaMax := 0.0
+	for i := 0; i < n; i++ {
+		row := 0.0
+		for k := 0; k < n; k++ {
+			row += matrix[i][k] * weights[k]
+		}
+		lambdaMax += row / weights[i]
+	}
+	lambdaMax /= float64(n)
+
+	if n > 2 {
+		ri := ahpRandomIndex[len(ahpRandomIndex)-1]
+		if n <= len(ahpRandomIndex) {
+			ri = ahpRandomIndex[n-1]
+		}
+		result.ConsistencyRatio = math.Max(0, (lambdaMax-float64(n))/float64(n-1)/ri)
+	}
+	result.Consistent = result.ConsistencyRatio <= ahpConsistencyThreshold
+
+	for i, name := range criteria {
+		result.Weights[name] = weights[i]
+		result.WeightsByID[criterionID(name)] = weights[i]
+	}
+
+	if !result.Consistent {
+		type deviation struct {
+			judgment AHPJudgment
+			value    float64
+		}
+		var deviations []deviation
+		for _, j := range judgments {
+			implied := weights[index[j.A]] / weights[index[j.B]]
+			deviations = append(deviations, deviation{j, math.Abs(math.Log(j.Value / implied))})
+		}
+		sort.SliceStable(deviations, func(i, k int) bool {
+			return deviations[i].value > deviations[k].value
+		})
+		for i := 0; i < len(deviations) && i < ahpSuggestionsLimit; i++ {
+			result.Revisit = append(result.Revisit, deviations[i].judgment)
+		}
+	}
+
+	return result
+}
+
+func formatAHPJudgment(lang Lang, j AHPJudgment) string {
+	if j.Value >= 1 {
+		return tr(lang, "«%s» важнее «%s» в %.0f раз(а)", criterionLabel(lang, j.A), criterionLabel(lang, j.B), j.Value)
+	}
+	return tr(lang, "«%s» важнее «%s» в %.0f раз(а)", criterionLabel(lang, j.B), criterionLabel(lang, j.A), 1/j.Value)
+}
+
+func formatAHPWeights(lang Lang, criteria []string, result *AHPResult) string {
+	var text strings.Builder
+	text.WriteString(tr(lang, "Веса критериев (AHP):") + "\n")
+	for _, name := range criteria {
+		text.WriteString(fmt.Sprintf("• %s: %.1f%%\n", criterionLabel(lang, name), result.Weights[name]*100))
+	}
+	text.WriteString("\n" + tr(lang, "Отношение согласованности: %.3f", result.ConsistencyRatio))
+	return text.String()
+}
+
+func askWeightingMode(bot *tgbotapi.BotAPI, chatID int64) {
+	state := userStates[chatID]
+	lang := userLang(chatID)
+
+	pairs := len(ahpPairs(state.SelectedCriteria))
+	msg := tgbotapi.NewMessage(chatID, tr(lang, "Как задать важность критериев?")+"\n\n"+
+		tr(lang, "• *Приоритеты 1–5* — оценка каждого критерия отдельно.")+"\n"+
+		tr(lang, "• *Попарное сравнение (AHP)* — %d вопросов «что важнее», веса рассчитываются автоматически.", pairs))
+	msg.ParseMode = "Markdown"
+	msg.ReplyMarkup = tgbotapi.NewInlineKeyboardMarkup(
+		tgbotapi.NewInlineKeyboardRow(
+			tgbotapi.NewInlineKeyboardButtonData(tr(lang, "Приоритеты 1–5"), "wmode_priority"),
+			tgbotapi.NewInlineKeyboardButtonData(tr(lang, "Попарное сравнение"), "wmode_ahp"),
+		),
+	)
+
+	logger.LogTelegramAction("Запрос способа взвешивания", map[string]interface{}{
+		"ChatID": chatID,
+		"Пар":    pairs,
+	})
+	sendMessage(bot, msg)
+}
+
+func handleWeightingModeCallback(bot *tgbotapi.BotAPI, chatID int64, callbackData string) {
+	state := userStates[chatID]
+
+	switch strings.TrimPrefix(callbackData, "wmode_") {
+	case weightingPriority:
+		state.Weighting = weightingPriority
+		state.CriteriaWeights = nil
+		state.AHPJudgments = nil
+		if len(state.CriteriaPriorities) == len(state.SelectedCriteria) {
+			transition(bot, chatID, stateSpecial)
+		} else {
+			startPrioritySelection(bot, chatID)
+		}
+	case weightingAHP:
+		state.Weighting = weightingAHP
+		transition(bot, chatID, stateAHP)
+	}
+}
+
+// startAHP начинает попарные сравнения заново.
+func startAHP(bot *tgbotapi.BotAPI, chatID int64) {
+	state := userStates[chatID]
+	state.CriteriaWeights = nil
+	state.AHPJudgments = make([]AHPJudgment, len(ahpPairs(state.SelectedCriteria)))
+	state.AHPMessageID = 0
+	askNextAHPComparison(bot, chatID)
+}
+
+func askNextAHPComparison(bot *tgbotapi.BotAPI, chatID int64) {
+	state := userStates[chatID]
+
+	pairIndex := -1
+	for i, j := range state.AHPJudgments {
+		if j.Value == 0 {
+			pairIndex = i
+			break
+		}
+	}
+	if pairIndex == -1 {
+		finishAHP(bot, chatID)
+		return
+	}
+
+	askAHPComparison(bot, chatID, pairIndex)
+}
+
+func askAHPComparison(bot *tgbotapi.BotAPI, chatID int64, pairIndex int) {
+	state := userStates[chatID]
+	pair := ahpPairs(state.SelectedCriteria)[pairIndex]
+	lang := userLang(chatID)
+
+	text := tr(lang, "Сравнение %d из %d. Что важнее для вашей системы?", pairIndex+1, len(state.AHPJudgments)) +
+		fmt.Sprintf("\n\n*A:* %s\n*B:* %s\n\n", criterionLabel(lang, pair[0]), criterionLabel(lang, pair[1])) +
+		tr(lang, "3 — немного важнее, 5 — заметно важнее, 9 — несравнимо важнее.")
+
+	var rows [][]tgbotapi.InlineKeyboardButton
+	var row []tgbotapi.InlineKeyboardButton
+	for i, option := range ahpScale {
+		row = append(row, tgbotapi.NewInlineKeyboardButtonData(tr(lang, option.Label), fmt.Sprintf("ahp_%d_%d", pairIndex, i)))
+		if i == 2 || i == 3 || i == len(ahpScale)-1 {
+			rows = append(rows, row)
+			row = nil
+		}
+	}
+	keyboard := tgbotapi.NewInlineKeyboardMarkup(rows...)
+
+	logger.LogTelegramAction("Запрос попарного сравнения", map[string]interface{}{
+		"A": pair[0],
+		"B": pair[1],
+	})
+
+	if state.AHPMessageID != 0 {
+		editMsg := tgbotapi.NewEditMessageTextAndMarkup(chatID, state.AHPMessageID, text, keyboard)
+		editMsg.ParseMode = "Markdown"
+		editMessageText(bot, editMsg)
+		return
+	}
+
+	msg := tgbotapi.NewMessage(chatID, text)
+	msg.ParseMode = "Markdown"
+	msg.ReplyMarkup = keyboard
+	sendTrackedMessage(bot, msg, &state.AHPMessageID)
+}
+
+func handleAHPCallback(bot *tgbotapi.BotAPI, chatID int64, callbackData string) {
+	state := userStates[chatID]
+
+	if callbackData == "ahp_accept" {
+		acceptAHPWeights(bot, chatID)
+		return
+	}
+
+	if strings.HasPrefix(callbackData, "ahprev_") {
+		pairIndex, err := strconv.Atoi(strings.TrimPrefix(callbackData, "ahprev_"))
+		if err != nil || pairIndex < 0 || pairIndex >= len(state.AHPJudgments) {
+			return
+		}
+		state.AHPMessageID = 0
+		askAHPComparison(bot, chatID, pairIndex)
+		return
+	}
+
+	parts := strings.Split(callbackData, "_")
+	if len(parts) != 3 {
+		return
+	}
+	pairIndex, errPair := strconv.Atoi(parts[1])
+	scaleIndex, errScale := strconv.Atoi(parts[2])
+	if errPair != nil || errScale != nil ||
+		pairIndex < 0 || pairIndex >= len(state.AHPJudgments) ||
+		scaleIndex < 0 || scaleIndex >= len(ahpScale) {
+		return
+	}
+
+	pair := ahpPairs(state.SelectedCriteria)[pairIndex]
+	state.AHPJudgments[pairIndex] = AHPJudgment{A: pair[0], B: pair[1], Value: ahpScale[scaleIndex].Value}
+
+	logger.LogTelegramAction("Попарное сравнение", map[string]interface{}{
+		"A":        pair[0],
+		"B":        pair[1],
+		"Значение": ahpScale[scaleIndex].Value,
+	})
+
+	askNextAHPComparison(bot, chatID)
+}
+
+func finishAHP(bot *tgbotapi.BotAPI, chatID int64) {
+	state := userStates[chatID]
+	result := computeAHP(state.SelectedCriteria, state.AHPJudgments)
+
+	logger.LogTelegramAction("Рассчитаны веса AHP", map[string]interface{}{
+		"ChatID": chatID,
+		"Веса":   result.Weights,
+		"CR":     result.ConsistencyRatio,
+	})
+
+	if result.Consistent {
+		acceptAHPWeights(bot, chatID)
+		return
+	}
+
+	pairIndex := make(map[[2]string]int)
+	for i, pair := range ahpPairs(state.SelectedCriteria) {
+		pairIndex[pair] = i
+	}
+
+	lang := userLang(chatID)
+	var text strings.Builder
+	text.WriteString(formatAHPWeights(lang, state.SelectedCriteria, result))
+	text.WriteString(tr(lang, " — больше допустимых %.1f, ответы противоречат друг другу.", ahpConsistencyThreshold) + "\n\n")
+	text.WriteString(tr(lang, "Стоит пересмотреть сравнения:") + "\n")
+
+	var rows [][]tgbotapi.InlineKeyboardButton
+	for i, j := range result.Revisit {
+		text.WriteString(fmt.Sprintf("%d. %s\n", i+1, formatAHPJudgment(lang, j)))
+		rows = append(rows, tgbotapi.NewInlineKeyboardRow(
+			tgbotapi.NewInlineKeyboardButtonData(tr(lang, "Пересмотреть %d", i+1),
+				fmt.Sprintf("ahprev_%d", pairIndex[[2]string{j.A, j.B}])),
+		))
+	}
+	rows = append(rows, tgbotapi.NewInlineKeyboardRow(
+		tgbotapi.NewInlineKeyboardButtonData(tr(lang, "Принять как есть"), "ahp_accept"),
+	))
+
+	state.AHPMessageID = 0
+	msg := tgbotapi.NewMessage(chatID, text.String())
+	msg.ReplyMarkup = tgbotapi.NewInlineKeyboardMarkup(rows...)
+	sendMessage(bot, msg)
+}
+
+func acceptAHPWeights(bot *tgbotapi.BotAPI, chatID int64) {
+	state := userStates[chatID]
+	for _, j := range state.AHPJudgments {
+		if j.Value == 0 {
+			askNextAHPComparison(bot, chatID)
+			return
+		}
+	}
+
+	result := computeAHP(state.SelectedCriteria, state.AHPJudgments)
+	state.CriteriaWeights = result.Weights
+
+	sendMessage(bot, tgbotapi.NewMessage(chatID, formatAHPWeights(userLang(chatID), state.SelectedCriteria, result)))
+	transition(bot, chatID, stateSpecial)
+}
+
+// ahpWeightsComplete сообщает, что веса AHP заданы ровно для выбранных критериев.
+func ahpWeightsComplete(state *UserState) bool {
+	if state.Weighting != weightingAHP || len(state.CriteriaWeights) != len(state.SelectedCriteria) {
+		return false
+	}
+	for _, name := range state.SelectedCriteria {
+		if _, ok := state.CriteriaWeights[name]; !ok {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,40 @@
+package api
+
+// CatalogResponse — активный каталог для клиентов API. Тексты выводятся на языке запроса,
+// идентификаторы и значения специальных критериев — ключи для запросов /api/recommend.
+type CatalogResponse struct {
+	Version        string             `json:"version"`
+	Language       Lang               `json:"language"`
+	PriorityScale  PriorityScale      `json:"priority_scale"`
+	Options        []DeploymentOption `json:"options"`
+	Criteria       []CatalogCriterion `json:"criteria"`
+	VendorCriteria []CatalogCriterion `json:"vendor_criteria,omitempty"`
+}
+
+// PriorityScale — шкала приоритетов критериев, задается в каталоге.
+type PriorityScale struct {
+	Min int `json:"min"`
+	Max int `json:"max"`
+}
+
+// CatalogCriterion — критерий каталога; BaseScores и Scores значений не выводятся,
+// если баллы скрыты.
+type CatalogCriterion struct {
+	ID             string                 `json:"id"`
+	Name           string                 `json:"name"`
+	Category       string                 `json:"category,omitempty"`
+	Description    string                 `json:"description"`
+	IsSpecial      bool                   `json:"is_special"`
+	SpecialPrompt  string                 `json:"special_prompt,omitempty"`
+	SpecialOptions []CatalogSpecialOption `json:"special_options,omitempty"`
+	BaseScores     Scores                 `json:"base_scores,omitempty"`
+}
+
+// CatalogSpecialOption — значение специального критерия: Value передается в special_values,
+// Label — название для отображения.
+type CatalogSpecialOption struct {
+	Value       string `json:"value"`
+	Label       string `json:"label"`
+	Description string `json:"description"`
+	Scores      Scores `json:"scores,omitempty"`
+}
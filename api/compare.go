@@ -0,0 +1,29 @@
+package api
+
+// CompareRequest — тело POST /compare: два результата, сохраненных с ключом API запроса
+// (AnswerIDs), или два запроса на расчет (A и B).
+type CompareRequest struct {
+	AnswerIDs []int64                `json:"answer_ids,omitempty"`
+	A         *RecommendationRequest `json:"a,omitempty"`
+	B         *RecommendationRequest `json:"b,omitempty"`
+}
+
+type CompareResponse struct {
+	A                     *RecommendationResponse `json:"a"`
+	B                     *RecommendationResponse `json:"b"`
+	Criteria              []CriterionDiff         `json:"criteria"`
+	TotalsDelta           Scores                  `json:"totals_delta"`
+	RecommendationChanged bool                    `json:"recommendation_changed"`
+}
+
+// CriterionDiff описывает изменение критерия между вариантами A и B.
+// Дельты считаются как B - A; отсутствующий в варианте критерий дает 0 баллов.
+type CriterionDiff struct {
+	ID            string `json:"id"`
+	Name          string `json:"name"`
+	InA           bool   `json:"in_a"`
+	InB           bool   `json:"in_b"`
+	PriorityA     int    `json:"priority_a"`
+	PriorityB     int    `json:"priority_b"`
+	WeightedDelta Scores `json:"weighted_delta"`
+}
@@ -0,0 +1,3 @@
+// Package api содержит типы запросов и ответов HTTP API сервиса и спецификацию OpenAPI,
+// построенную по этим типам. Пакет используют сервер, пакет client и нагрузочный тест.
+package api
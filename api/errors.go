@@ -0,0 +1,14 @@
+package api
+
+// ValidationError — тело ответа 400 на некорректный запрос.
+type ValidationError struct {
+	Error  string       `json:"error"`
+	Errors []FieldError `json:"errors"`
+}
+
+// FieldError — ошибка в поле запроса. Path — путь к полю в JSON запроса, например
+// "criteria_priorities.latency" или "selected_criteria[2]"; пустой путь относится ко всему запросу.
+type FieldError struct {
+	Path    string `json:"path"`
+	Message string `json:"message"`
+}
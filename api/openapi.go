@@ -0,0 +1,419 @@
+package api
+
+import (
+	"net/http"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Version — версия HTTP API в спецификации OpenAPI, BasePath — префикс ее маршрутов.
+// Пути операций указываются относительно BasePath.
+const (
+	Version  = "1.0.0"
+	BasePath = "/api/v1"
+)
+
+// operation описывает метод API. Request и значения Responses — нулевые значения типов тел;
+// nil в Responses означает ответ без тела, textError — текстовое сообщение об ошибке.
+// Методы, кроме Public, требуют ключ API; ответы 401 и 429 добавляются к ним автоматически.
+type operation struct {
+	Method      string
+	Path        string
+	Summary     string
+	Description string
+	Public      bool
+	Params      []parameter
+	Request     interface{}
+	Optional    bool
+	Responses   map[int]interface{}
+}
+
+type parameter struct {
+	Name        string
+	In          string
+	Type        string
+	Required    bool
+	Description string
+}
+
+// textError — ошибка, которую сервер отдает текстом через http.Error.
+type textError struct{}
+
+// oneOf — ответ одного из нескольких типов.
+type oneOf []interface{}
+
+var sessionIDParam = parameter{Name: "id", In: "path", Type: "string", Required: true, Description: "Идентификатор сессии"}
+
+// operations — все методы API. Новый обработчик добавляется и сюда, иначе он не попадет в спецификацию.
+var operations = []operation{
+	{
+		Method: "POST", Path: "/recommend",
+		Summary:     "Рассчитать рекомендацию",
+		Description: "Критерии задаются идентификаторами из GET /criteria. Язык текстов ответа — из Accept-Language.",
+		Request:     RecommendationRequest{},
+		Responses: map[int]interface{}{
+			200: RecommendationResponse{},
+			400: ValidationError{},
+			500: textError{},
+		},
+	},
+	{
+		Method: "POST", Path: "/compare",
+		Summary:     "Сравнить два результата",
+		Description: "Два результата, сохраненных с ключом API запроса (answer_ids), или два запроса на расчет (a и b).",
+		Request:     CompareRequest{},
+		Responses: map[int]interface{}{
+			200: CompareResponse{},
+			400: ValidationError{},
+			404: textError{},
+			503: textError{},
+		},
+	},
+	{
+		Method: "GET", Path: "/criteria",
+		Public:      true,
+		Summary:     "Активный каталог критериев",
+		Description: "Ответ содержит ETag; запрос с If-None-Match получает 304, пока каталог не изменился.",
+		Params: []parameter{
+			{Name: "If-None-Match", In: "header", Type: "string", Description: "ETag ранее полученного каталога"},
+		},
+		Responses: map[int]interface{}{
+			200: CatalogResponse{},
+			304: nil,
+		},
+	},
+	{
+		Method: "GET", Path: "/profiles",
+		Summary:     "Профили клиента",
+		Description: "Профили ключа API запроса. Без name возвращается список профилей, с name — один профиль.",
+		Params: []parameter{
+			{Name: "name", In: "query", Type: "string", Description: "Название профиля"},
+		},
+		Responses: map[int]interface{}{
+			200: oneOf{[]Profile{}, Profile{}},
+			400: textError{},
+			404: textError{},
+			503: textError{},
+		},
+	},
+	{
+		Method: "POST", Path: "/profiles",
+		Summary:     "Сохранить профиль",
+		Description: "Ввод передается в input или берется из результата answer_id, сохраненного с тем же ключом API.",
+		Request:     ProfileRequest{},
+		Responses: map[int]interface{}{
+			200: Profile{},
+			400: textError{},
+			404: textError{},
+			503: textError{},
+		},
+	},
+	{
+		Method: "DELETE", Path: "/profiles",
+		Summary: "Удалить профиль",
+		Params: []parameter{
+			{Name: "id", In: "query", Type: "integer", Required: true, Description: "Идентификатор профиля"},
+		},
+		Responses: map[int]interface{}{
+			204: nil,
+			400: textError{},
+			404: textError{},
+			503: textError{},
+		},
+	},
+	{
+		Method: "POST", Path: "/wizard/sessions",
+		Summary:     "Начать пошаговый чеклист",
+		Description: "Без handoff_token начинается новый чеклист. С handoff_token — одноразовым кодом из команды бота /web — продолжается чеклист пользователя Telegram.",
+		Request:     WizardSessionRequest{},
+		Optional:    true,
+		Responses: map[int]interface{}{
+			201: WizardView{},
+			400: ValidationError{},
+		},
+	},
+	{
+		Method: "GET", Path: "/wizard/sessions/{id}",
+		Summary: "Текущий шаг чеклиста",
+		Params:  []parameter{sessionIDParam},
+		Responses: map[int]interface{}{
+			200: WizardView{},
+			404: textError{},
+		},
+	},
+	{
+		Method: "DELETE", Path: "/wizard/sessions/{id}",
+		Summary: "Завершить сессию",
+		Params:  []parameter{sessionIDParam},
+		Responses: map[int]interface{}{
+			204: nil,
+			404: textError{},
+		},
+	},
+	{
+		Method: "POST", Path: "/wizard/sessions/{id}/answer",
+		Summary:     "Ответить на вопрос",
+		Description: "action — одна из answers текущего шага, text — если шаг принимает текст (accepts_text).",
+		Params:      []parameter{sessionIDParam},
+		Request:     WizardAnswerRequest{},
+		Responses: map[int]interface{}{
+			200: WizardView{},
+			400: ValidationError{},
+			404: textError{},
+			409: textError{},
+		},
+	},
+	{
+		Method: "POST", Path: "/wizard/sessions/{id}/back",
+		Summary: "Вернуться к предыдущему вопросу",
+		Params:  []parameter{sessionIDParam},
+		Responses: map[int]interface{}{
+			200: WizardView{},
+			404: textError{},
+			409: textError{},
+		},
+	},
+	{
+		Method: "POST", Path: "/wizard/sessions/{id}/finalize",
+		Summary: "Рассчитать результат чеклиста",
+		Params:  []parameter{sessionIDParam},
+		Responses: map[int]interface{}{
+			200: WizardView{},
+			404: textError{},
+			409: textError{},
+		},
+	},
+	{
+		Method: "GET", Path: "/openapi.json",
+		Public:  true,
+		Summary: "Спецификация OpenAPI",
+		Responses: map[int]interface{}{
+			200: map[string]interface{}{},
+		},
+	},
+}
+
+// OpenAPI строит спецификацию OpenAPI 3 по таблице методов и типам пакета: схемы
+// совпадают с тем, что сервер разбирает и отдает. Поля без omitempty всегда присутствуют
+// в ответах; неизвестные поля в запросах сервер отклоняет.
+func OpenAPI() map[string]interface{} {
+	b := &schemaBuilder{schemas: map[string]interface{}{}}
+	paths := map[string]interface{}{}
+
+	for _, op := range operations {
+		item, _ := paths[op.Path].(map[string]interface{})
+		if item == nil {
+			item = map[string]interface{}{}
+			paths[op.Path] = item
+		}
+
+		responses := op.Responses
+		if !op.Public {
+			responses = map[int]interface{}{
+				401: textError{},
+				429: textError{},
+				503: textError{},
+			}
+			for status, body := range op.Responses {
+				responses[status] = body
+			}
+		}
+		spec := map[string]interface{}{
+			"summary":     op.Summary,
+			"operationId": operationID(op),
+			"responses":   b.responses(responses),
+		}
+		if op.Public {
+			spec["security"] = []interface{}{}
+		}
+		if op.Description != "" {
+			spec["description"] = op.Description
+		}
+		params := []interface{}{map[string]interface{}{"$ref": "#/components/parameters/AcceptLanguage"}}
+		for _, p := range op.Params {
+			params = append(params, map[string]interface{}{
+				"name":        p.Name,
+				"in":          p.In,
+				"required":    p.Required,
+				"description": p.Description,
+				"schema":      map[string]interface{}{"type": p.Type},
+			})
+		}
+		spec["parameters"] = params
+		if op.Request != nil {
+			spec["requestBody"] = map[string]interface{}{
+				"required": !op.Optional,
+				"content": map[string]interface{}{
+					"application/json": map[string]interface{}{"schema": b.schema(reflect.TypeOf(op.Request))},
+				},
+			}
+		}
+		item[strings.ToLower(op.Method)] = spec
+	}
+
+	return map[string]interface{}{
+		"openapi": "3.0.3",
+		"info": map[string]interface{}{
+			"title":   "tg-bot-checklist API",
+			"version": Version,
+			"description": "Выбор варианта развертывания СУБД (On-Premise, Private Cloud, Public Cloud) " +
+				"по критериям каталога. Тексты ответов выводятся на языке из Accept-Language (ru или en).",
+		},
+		"servers":  []interface{}{map[string]interface{}{"url": BasePath}},
+		"security": []interface{}{map[string]interface{}{"ApiKey": []interface{}{}}},
+		"paths":    paths,
+		"components": map[string]interface{}{
+			"schemas": b.schemas,
+			"securitySchemes": map[string]interface{}{
+				"ApiKey": map[string]interface{}{
+					"type":        "http",
+					"scheme":      "bearer",
+					"description": "Ключ API, выданный администратором бота командой /apikey",
+				},
+			},
+			"parameters": map[string]interface{}{
+				"AcceptLanguage": map[string]interface{}{
+					"name":        "Accept-Language",
+					"in":          "header",
+					"description": "Язык текстов ответа, по умолчанию ru",
+					"schema":      map[string]interface{}{"type": "string", "example": "en"},
+				},
+			},
+		},
+	}
+}
+
+// operationID — имя метода для генераторов клиентов, например postWizardSessionsIdAnswer.
+func operationID(op operation) string {
+	id := strings.ToLower(op.Method)
+	for _, part := range strings.FieldsFunc(op.Path, func(r rune) bool { return r == '/' || r == '.' || r == '_' }) {
+		part = strings.Trim(part, "{}")
+		id += strings.ToUpper(part[:1]) + part[1:]
+	}
+	return id
+}
+
+type schemaBuilder struct {
+	schemas map[string]interface{}
+}
+
+func (b *schemaBuilder) responses(responses map[int]interface{}) map[string]interface{} {
+	result := map[string]interface{}{}
+	for status, body := range responses {
+		response := map[string]interface{}{"description": http.StatusText(status)}
+		switch body.(type) {
+		case nil:
+		case textError:
+			response["content"] = map[string]interface{}{
+				"text/plain": map[string]interface{}{"schema": map[string]interface{}{"type": "string"}},
+			}
+		case oneOf:
+			var schemas []interface{}
+			for _, v := range body.(oneOf) {
+				schemas = append(schemas, b.schema(reflect.TypeOf(v)))
+			}
+			response["content"] = map[string]interface{}{
+				"application/json": map[string]interface{}{"schema": map[string]interface{}{"oneOf": schemas}},
+			}
+		default:
+			response["content"] = map[string]interface{}{
+				"application/json": map[string]interface{}{"schema": b.schema(reflect.TypeOf(body))},
+			}
+		}
+		result[strconv.Itoa(status)] = response
+	}
+	return result
+}
+
+var timeType = reflect.TypeOf(time.Time{})
+
+// schema возвращает схему типа; именованные типы пакета выносятся в components/schemas.
+func (b *schemaBuilder) schema(t reflect.Type) map[string]interface{} {
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	if t == timeType {
+		return map[string]interface{}{"type": "string", "format": "date-time"}
+	}
+	if t.PkgPath() == reflect.TypeOf(operation{}).PkgPath() && t.Name() != "" {
+		ref := map[string]interface{}{"$ref": "#/components/schemas/" + t.Name()}
+		if _, ok := b.schemas[t.Name()]; !ok {
+			b.schemas[t.Name()] = nil // защита от рекурсии
+			b.schemas[t.Name()] = b.inline(t)
+		}
+		return ref
+	}
+	return b.inline(t)
+}
+
+func (b *schemaBuilder) inline(t reflect.Type) map[string]interface{} {
+	switch t.Kind() {
+	case reflect.Bool:
+		return map[string]interface{}{"type": "boolean"}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32:
+		return map[string]interface{}{"type": "integer", "format": "int32"}
+	case reflect.Int64, reflect.Uint64:
+		return map[string]interface{}{"type": "integer", "format": "int64"}
+	case reflect.Float32, reflect.Float64:
+		return map[string]interface{}{"type": "number"}
+	case reflect.String:
+		return map[string]interface{}{"type": "string"}
+	case reflect.Slice, reflect.Array:
+		return map[string]interface{}{"type": "array", "items": b.schema(t.Elem())}
+	case reflect.Map:
+		schema := map[string]interface{}{"type": "object", "additionalProperties": b.schema(t.Elem())}
+		if t.Key().Kind() != reflect.String {
+			schema["description"] = "Ключи — целые числа"
+		}
+		return schema
+	case reflect.Struct:
+		return b.object(t)
+	default:
+		return map[string]interface{}{}
+	}
+}
+
+// object описывает структуру по тегам json. Поля без omitempty типов срез, словарь
+// и указатель могут быть null.
+func (b *schemaBuilder) object(t reflect.Type) map[string]interface{} {
+	properties := map[string]interface{}{}
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		tag := field.Tag.Get("json")
+		if !field.IsExported() || tag == "-" {
+			continue
+		}
+		name, options, _ := strings.Cut(tag, ",")
+		if name == "" {
+			name = field.Name
+		}
+
+		schema := b.schema(field.Type)
+		switch field.Type.Kind() {
+		case reflect.Slice, reflect.Map, reflect.Ptr:
+			if !strings.Contains(options, "omitempty") {
+				schema = nullable(schema)
+			}
+		}
+		properties[name] = schema
+	}
+	return map[string]interface{}{
+		"type":                 "object",
+		"properties":           properties,
+		"additionalProperties": false,
+	}
+}
+
+func nullable(schema map[string]interface{}) map[string]interface{} {
+	if _, ok := schema["$ref"]; ok {
+		return map[string]interface{}{"allOf": []interface{}{schema}, "nullable": true}
+	}
+	result := map[string]interface{}{"nullable": true}
+	for k, v := range schema {
+		result[k] = v
+	}
+	return result
+}
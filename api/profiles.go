@@ -0,0 +1,34 @@
+package api
+
+import "time"
+
+// Profile — сохраненный под названием ввод чеклиста. UserID заполнен у профилей
+// пользователей Telegram; профили клиента API принадлежат его ключу.
+type Profile struct {
+	ID        int64         `json:"id"`
+	UserID    int64         `json:"user_id,omitempty"`
+	Name      string        `json:"name"`
+	Input     UserInputData `json:"input"`
+	CreatedAt time.Time     `json:"created_at"`
+}
+
+// ProfileRequest — тело POST /profiles; профиль сохраняется для ключа API запроса.
+type ProfileRequest struct {
+	Name     string         `json:"name"`
+	AnswerID int64          `json:"answer_id,omitempty"`
+	Input    *UserInputData `json:"input,omitempty"`
+}
+
+// UserInputData — ответы чеклиста в том виде, в каком они сохраняются в истории и профилях.
+type UserInputData struct {
+	SelectedCriteria   []string           `json:"selected_criteria,omitempty"`
+	CriteriaPriorities map[string]int     `json:"criteria_priorities"`
+	OverriddenScores   map[string]Scores  `json:"overridden_scores"`
+	SpecialValues      map[string]string  `json:"special_values"`
+	Weighting          string             `json:"weighting,omitempty"`
+	CriteriaWeights    map[string]float64 `json:"criteria_weights,omitempty"`
+	AHPJudgments       []AHPJudgment      `json:"ahp_judgments,omitempty"`
+	Cost               *CostInput         `json:"cost,omitempty"`
+	TieBreakAnswer     string             `json:"tie_break_answer,omitempty"`
+	FollowUpAnswers    map[string]string  `json:"follow_up_answers,omitempty"`
+}
@@ -0,0 +1,316 @@
+package api
+
+// Lang — язык интерфейса бота и текстов API.
+type Lang string
+
+// RecommendationRequest — тело POST /api/recommend. Критерии задаются идентификаторами
+// каталога (GET /api/criteria).
+type RecommendationRequest struct {
+	SelectedCriteria   []string            `json:"selected_criteria"`
+	CriteriaPriorities map[string]int      `json:"criteria_priorities"`
+	OverriddenScores   map[string]Scores   `json:"overridden_scores"`
+	SpecialValues      map[string]string   `json:"special_values"`
+	CriteriaWeights    map[string]float64  `json:"criteria_weights,omitempty"`
+	AHPJudgments       []AHPJudgment       `json:"ahp_judgments,omitempty"`
+	Uncertainty        *UncertaintyOptions `json:"uncertainty,omitempty"`
+	Method             string              `json:"method,omitempty"`
+	CompareMethods     bool                `json:"compare_methods,omitempty"`
+	Vendors            *VendorRequest      `json:"vendors,omitempty"`
+	Cost               *CostInput          `json:"cost,omitempty"`
+	TieBreakAnswer     string              `json:"tie_break_answer,omitempty"`
+	FollowUpAnswers    map[string]string   `json:"follow_up_answers,omitempty"`
+
+	// Lang — язык текстов ответа: берется из Accept-Language или из настроек пользователя бота.
+	Lang Lang `json:"-"`
+}
+
+// Scores — баллы критерия по вариантам развертывания, ключ — идентификатор варианта.
+// Баллы дробные, шкала — от 1 до 10.
+type Scores map[string]float64
+
+// DeploymentOption — вариант развертывания из каталога. ShortName используется
+// в компактных строках детализации.
+type DeploymentOption struct {
+	ID        string `json:"id"`
+	Name      string `json:"name"`
+	ShortName string `json:"short_name,omitempty"`
+}
+
+// CriterionDetail содержит баллы по всем вариантам каталога в Scores/Weighted.
+// Weight — нормированный вес критерия (веса всех критериев в сумме дают 1), Weighted = Weight × Scores.
+// Priority = 0 означает, что вес задан напрямую (AHP или criteria_weights).
+// Поля OnPrem*/Private*/Public* сохранены для совместимости со старыми клиентами.
+type CriterionDetail struct {
+	ID              string   `json:"id"`
+	Name            string   `json:"name"`
+	Priority        int      `json:"priority"`
+	Weight          float64  `json:"weight"`
+	Source          string   `json:"source"`
+	SpecialValue    string   `json:"special_value,omitempty"`
+	Adjustments     []string `json:"adjustments,omitempty"`
+	Scores          Scores   `json:"scores"`
+	Weighted        Scores   `json:"weighted"`
+	OnPremScore     float64  `json:"on_prem_score"`
+	PrivateScore    float64  `json:"private_score"`
+	PublicScore     float64  `json:"public_score"`
+	OnPremWeighted  float64  `json:"on_prem_weighted"`
+	PrivateWeighted float64  `json:"private_weighted"`
+	PublicWeighted  float64  `json:"public_weighted"`
+}
+
+// RecommendationResponse — результат расчета: итоги по вариантам, рекомендация и детализация.
+// Recommendation — текст рекомендации по-русски, оставлен для совместимости; выбранный
+// вариант или ничья передаются в Outcome.
+type RecommendationResponse struct {
+	Options        []DeploymentOption `json:"options"`
+	Totals         Scores             `json:"totals"`
+	TotalsPercent  Scores             `json:"totals_percent"`
+	OnPremTotal    float64            `json:"on_prem_total"`
+	PrivateTotal   float64            `json:"private_total"`
+	PublicTotal    float64            `json:"public_total"`
+	Recommendation string             `json:"recommendation"`
+	Outcome        Outcome            `json:"outcome"`
+	Method         string             `json:"method"`
+	Details        []CriterionDetail  `json:"details"`
+	Excluded       []ExcludedOption   `json:"excluded,omitempty"`
+	Sensitivity    *SensitivityReport `json:"sensitivity,omitempty"`
+	Uncertainty    *UncertaintyReport `json:"uncertainty,omitempty"`
+	AHP            *AHPResult         `json:"ahp,omitempty"`
+	Methods        []MethodResult     `json:"methods,omitempty"`
+	MethodsAgree   *bool              `json:"methods_agree,omitempty"`
+	Vendors        *VendorShortlist   `json:"vendors,omitempty"`
+	Cost           *CostEstimate      `json:"cost,omitempty"`
+	Explanation    *Explanation       `json:"explanation,omitempty"`
+	TieBreak       *TieBreakResult    `json:"tie_break,omitempty"`
+	FollowUps      []FollowUpResult   `json:"follow_ups,omitempty"`
+	AIAnalysis     string             `json:"ai_analysis,omitempty"`
+	Language       Lang               `json:"language"`
+}
+
+// Outcome — итог выбора: идентификатор рекомендованного варианта или, при равенстве лидеров,
+// идентификаторы равных вариантов в Tied. Оба поля пусты, если все варианты исключены.
+type Outcome struct {
+	OptionID string   `json:"option_id,omitempty"`
+	Tied     []string `json:"tied,omitempty"`
+}
+
+type ExcludedOption struct {
+	OptionID    string `json:"option_id"`
+	Option      string `json:"option"`
+	RuleID      string `json:"rule_id"`
+	CriterionID string `json:"criterion_id"`
+	Criterion   string `json:"criterion"`
+	Condition   string `json:"condition"`
+	Reason      string `json:"reason"`
+}
+
+// AHPJudgment — ответ на попарное сравнение: во сколько раз критерий A важнее критерия B.
+type AHPJudgment struct {
+	A     string  `json:"a"`
+	B     string  `json:"b"`
+	Value float64 `json:"value"`
+}
+
+// AHPResult — веса критериев по именам (Weights) и по идентификаторам для клиентов API (WeightsByID).
+type AHPResult struct {
+	Weights          map[string]float64 `json:"weights"`
+	WeightsByID      map[string]float64 `json:"weights_by_id"`
+	ConsistencyRatio float64            `json:"consistency_ratio"`
+	Consistent       bool               `json:"consistent"`
+	Revisit          []AHPJudgment      `json:"revisit,omitempty"`
+}
+
+type MethodResult struct {
+	Method         string            `json:"method"`
+	Scores         []OptionScore     `json:"scores"`
+	Ranking        []string          `json:"ranking"`
+	Recommendation string            `json:"recommendation"`
+	Outcome        Outcome           `json:"outcome"`
+	Criteria       []MethodCriterion `json:"criteria"`
+}
+
+type OptionScore struct {
+	Option string  `json:"option"`
+	Score  float64 `json:"score"`
+}
+
+// MethodCriterion объясняет вклад критерия в оценку вариантов конкретным методом:
+// для взвешенной суммы — w·x, для TOPSIS — взвешенное нормированное значение w·x/‖x‖,
+// для взвешенного произведения — w·ln(x).
+type MethodCriterion struct {
+	Name          string        `json:"name"`
+	Weight        float64       `json:"weight"`
+	Contributions []OptionScore `json:"contributions"`
+}
+
+type SensitivityReport struct {
+	Winner        string                  `json:"winner,omitempty"`
+	RunnerUp      string                  `json:"runner_up,omitempty"`
+	Margin        float64                 `json:"margin"`
+	MarginPercent float64                 `json:"margin_percent"`
+	PriorityFlip  *FlipChange             `json:"priority_flip,omitempty"`
+	ScoreFlip     *FlipChange             `json:"score_flip,omitempty"`
+	Contributions []CriterionContribution `json:"contributions"`
+}
+
+// FlipChange — одиночное изменение ввода, после которого рекомендация перестает
+// совпадать с текущим победителем. Для изменения балла Option содержит вариант развертывания.
+type FlipChange struct {
+	CriterionID       string  `json:"criterion_id"`
+	Criterion         string  `json:"criterion"`
+	Kind              string  `json:"kind"`
+	Option            string  `json:"option,omitempty"`
+	From              float64 `json:"from"`
+	To                float64 `json:"to"`
+	NewRecommendation string  `json:"new_recommendation"`
+	NewOutcome        Outcome `json:"new_outcome"`
+}
+
+// CriterionContribution — вклад критерия в отрыв победителя от второго места
+// (разница их взвешенных баллов по этому критерию).
+type CriterionContribution struct {
+	ID   string  `json:"id"`
+	Name string  `json:"name"`
+	Lead float64 `json:"lead"`
+}
+
+// Explanation — детерминированное объяснение результата, построенное по CriterionDetail
+// без обращения к LLM. Supporting — критерии, дающие победителю перевес над вторым местом,
+// Opposing — критерии в пользу второго места.
+type Explanation struct {
+	Winner      string                  `json:"winner,omitempty"`
+	RunnerUp    string                  `json:"runner_up,omitempty"`
+	Supporting  []CriterionContribution `json:"supporting"`
+	Opposing    []CriterionContribution `json:"opposing"`
+	Adjustments []string                `json:"adjustments"`
+	Text        string                  `json:"text"`
+}
+
+// UncertaintyOptions включает режим Монте-Карло. Каждый приоритет и балл на каждой
+// итерации выбирается равномерно из диапазона: явно заданного в PriorityRanges/ScoreRanges
+// или из значения ± PrioritySpread/ScoreSpread. Веса, заданные напрямую (AHP), не меняются.
+// Seed = 0 означает случайное зерно.
+type UncertaintyOptions struct {
+	Iterations     int                    `json:"iterations,omitempty"`
+	Seed           int64                  `json:"seed,omitempty"`
+	PrioritySpread *int                   `json:"priority_spread,omitempty"`
+	ScoreSpread    *float64               `json:"score_spread,omitempty"`
+	PriorityRanges map[string]IntRange    `json:"priority_ranges,omitempty"`
+	ScoreRanges    map[string]ScoreRanges `json:"score_ranges,omitempty"`
+}
+
+type IntRange struct {
+	Min int `json:"min"`
+	Max int `json:"max"`
+}
+
+// FloatRange — диапазон дробных баллов, значение выбирается равномерно.
+type FloatRange struct {
+	Min float64 `json:"min"`
+	Max float64 `json:"max"`
+}
+
+// ScoreRanges задает диапазоны баллов критерия, ключ — идентификатор варианта развертывания.
+type ScoreRanges map[string]FloatRange
+
+type UncertaintyReport struct {
+	Iterations       int                 `json:"iterations"`
+	Seed             int64               `json:"seed"`
+	WinProbabilities []OptionProbability `json:"win_probabilities"`
+	TieProbability   float64             `json:"tie_probability"`
+}
+
+type OptionProbability struct {
+	Option      string  `json:"option"`
+	Probability float64 `json:"probability"`
+}
+
+// CostInput — параметры оценки стоимости. ApplyToScores заменяет баллы экономических
+// критериев баллами, рассчитанными из стоимости (ручное переопределение важнее).
+type CostInput struct {
+	DataGB        int  `json:"data_gb"`
+	Instances     int  `json:"instances"`
+	TeamSize      int  `json:"team_size"`
+	HorizonYears  int  `json:"horizon_years"`
+	ApplyToScores bool `json:"apply_to_scores,omitempty"`
+}
+
+type CostEstimate struct {
+	Currency     string       `json:"currency"`
+	HorizonYears int          `json:"horizon_years"`
+	Options      []OptionCost `json:"options"`
+	Cheapest     string       `json:"cheapest"`
+}
+
+type OptionCost struct {
+	Option  string          `json:"option"`
+	Name    string          `json:"name"`
+	Upfront float64         `json:"upfront"`
+	Annual  float64         `json:"annual"`
+	TCO     map[int]float64 `json:"tco"`
+}
+
+// FollowUpResult — применимый к запросу вопрос и ответ на него; пустой Answer означает,
+// что ответа нет и поправки не применялись. Answers — значения для follow_up_answers,
+// AnswerLabels — те же ответы на языке запроса.
+type FollowUpResult struct {
+	ID           string   `json:"id"`
+	Question     string   `json:"question"`
+	Answers      []string `json:"answers"`
+	AnswerLabels []string `json:"answer_labels"`
+	Answer       string   `json:"answer,omitempty"`
+}
+
+// TieBreakResult фиксирует, как была обработана ничья. Applied = false означает, что
+// ничья осталась (Outcome.Tied ответа). Для ask_user вопрос
+// возвращается в Question, ответ передается в tie_break_answer повторного запроса.
+type TieBreakResult struct {
+	Policy     string            `json:"policy"`
+	Candidates []string          `json:"candidates"`
+	Applied    bool              `json:"applied"`
+	Winner     string            `json:"winner,omitempty"`
+	Reason     string            `json:"reason"`
+	Question   *TieBreakQuestion `json:"question,omitempty"`
+}
+
+type TieBreakQuestion struct {
+	Text    string           `json:"text"`
+	Answers []TieBreakAnswer `json:"answers"`
+}
+
+// TieBreakAnswer — вариант ответа на уточняющий вопрос: выбор критерия означает выбор варианта,
+// у которого по этому критерию наибольшее преимущество.
+type TieBreakAnswer struct {
+	CriterionID string `json:"criterion_id"`
+	Criterion   string `json:"criterion"`
+	Option      string `json:"option"`
+}
+
+// VendorRequest включает второй этап. Option по умолчанию — победитель первого этапа,
+// пустые приоритеты означают равный приоритет 1 для всех критериев поставщиков.
+type VendorRequest struct {
+	Option             string         `json:"option,omitempty"`
+	CriteriaPriorities map[string]int `json:"criteria_priorities,omitempty"`
+}
+
+type VendorShortlist struct {
+	Option    string                  `json:"option"`
+	Shortlist []VendorRanking         `json:"shortlist"`
+	Details   []VendorCriterionDetail `json:"details"`
+}
+
+type VendorRanking struct {
+	Rank  int     `json:"rank"`
+	ID    string  `json:"id"`
+	Name  string  `json:"name"`
+	Total float64 `json:"total"`
+}
+
+type VendorCriterionDetail struct {
+	ID       string  `json:"id"`
+	Name     string  `json:"name"`
+	Priority int     `json:"priority"`
+	Weight   float64 `json:"weight"`
+	Scores   Scores  `json:"scores"`
+	Weighted Scores  `json:"weighted"`
+}
@@ -0,0 +1,47 @@
+package api
+
+// WizardState — именованное состояние чеклиста в боте.
+type WizardState string
+
+// WizardSessionRequest — тело POST /api/wizard/sessions. HandoffToken — одноразовый код,
+// который бот выдает пользователю Telegram по команде /web: с ним чеклист этого пользователя
+// продолжается на сайте; без него начинается новый чеклист.
+type WizardSessionRequest struct {
+	HandoffToken string `json:"handoff_token,omitempty"`
+}
+
+// WizardAnswerRequest — ответ на вопрос: нажатие кнопки (action) или текст.
+type WizardAnswerRequest struct {
+	Action string `json:"action,omitempty"`
+	Text   string `json:"text,omitempty"`
+}
+
+// WizardView — текущий шаг веб-сессии чеклиста.
+type WizardView struct {
+	SessionID   string                  `json:"session_id"`
+	UserID      int64                   `json:"user_id,omitempty"`
+	State       WizardState             `json:"state"`
+	Messages    []WizardMessage         `json:"messages"`
+	Answers     []WizardButton          `json:"answers"`
+	AcceptsText bool                    `json:"accepts_text"`
+	CanGoBack   bool                    `json:"can_go_back"`
+	CanFinalize bool                    `json:"can_finalize"`
+	Finished    bool                    `json:"finished"`
+	Result      *RecommendationResponse `json:"result,omitempty"`
+	AnswerID    int64                   `json:"answer_id,omitempty"`
+	Language    Lang                    `json:"language"`
+}
+
+// WizardMessage — сообщение чеклиста, которое бот отправил бы в Telegram.
+type WizardMessage struct {
+	ID        int              `json:"id"`
+	Text      string           `json:"text"`
+	ParseMode string           `json:"parse_mode,omitempty"`
+	Buttons   [][]WizardButton `json:"buttons,omitempty"`
+}
+
+// WizardButton — кнопка вопроса; Action передается в /answer как ответ.
+type WizardButton struct {
+	Text   string `json:"text"`
+	Action string `json:"action"`
+}
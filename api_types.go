@@ -0,0 +1,75 @@
+package main
+
+import (
+	"net/http"
+
+	"tg-bot-checklist/api"
+)
+
+// Типы запросов и ответов HTTP API определены в пакете api: их же используют пакет client
+// и нагрузочный тест, а спецификация /api/openapi.json строится по ним.
+type (
+	Lang                   = api.Lang
+	RecommendationRequest  = api.RecommendationRequest
+	Scores                 = api.Scores
+	DeploymentOption       = api.DeploymentOption
+	CriterionDetail        = api.CriterionDetail
+	RecommendationResponse = api.RecommendationResponse
+	Outcome                = api.Outcome
+	ExcludedOption         = api.ExcludedOption
+	AHPJudgment            = api.AHPJudgment
+	AHPResult              = api.AHPResult
+	MethodResult           = api.MethodResult
+	OptionScore            = api.OptionScore
+	MethodCriterion        = api.MethodCriterion
+	SensitivityReport      = api.SensitivityReport
+	FlipChange             = api.FlipChange
+	CriterionContribution  = api.CriterionContribution
+	Explanation            = api.Explanation
+	UncertaintyOptions     = api.UncertaintyOptions
+	IntRange               = api.IntRange
+	FloatRange             = api.FloatRange
+	ScoreRanges            = api.ScoreRanges
+	UncertaintyReport      = api.UncertaintyReport
+	OptionProbability      = api.OptionProbability
+	CostInput              = api.CostInput
+	CostEstimate           = api.CostEstimate
+	OptionCost             = api.OptionCost
+	FollowUpResult         = api.FollowUpResult
+	TieBreakResult         = api.TieBreakResult
+	TieBreakQuestion       = api.TieBreakQuestion
+	TieBreakAnswer         = api.TieBreakAnswer
+	VendorRequest          = api.VendorRequest
+	VendorShortlist        = api.VendorShortlist
+	VendorRanking          = api.VendorRanking
+	VendorCriterionDetail  = api.VendorCriterionDetail
+	CompareRequest         = api.CompareRequest
+	CompareResponse        = api.CompareResponse
+	CriterionDiff          = api.CriterionDiff
+	Profile                = api.Profile
+	ProfileRequest         = api.ProfileRequest
+	UserInputData          = api.UserInputData
+	CatalogResponse        = api.CatalogResponse
+	PriorityScale          = api.PriorityScale
+	CatalogCriterion       = api.CatalogCriterion
+	CatalogSpecialOption   = api.CatalogSpecialOption
+	WizardState            = api.WizardState
+	WizardSessionRequest   = api.WizardSessionRequest
+	WizardAnswerRequest    = api.WizardAnswerRequest
+	WizardView             = api.WizardView
+	WizardMessage          = api.WizardMessage
+	WizardButton           = api.WizardButton
+	ValidationError        = api.ValidationError
+	FieldError             = api.FieldError
+)
+
+// openAPISpec — спецификация API; строится по типам пакета api один раз при запуске.
+var openAPISpec = api.OpenAPI()
+
+func openAPIHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != "GET" {
+		http.Error(w, tr(requestLang(r), "Метод не поддерживается"), http.StatusMethodNotAllowed)
+		return
+	}
+	writeJSON(w, http.StatusOK, openAPISpec)
+}
@@ -0,0 +1,138 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"tg-bot-checklist/api"
+)
+
+// Версии HTTP API. Поведение v1 зафиксировано: маршруты, типы запросов и ответов не
+// меняются несовместимо. Несовместимые изменения (произвольное число вариантов
+// развертывания) выходят в v2 с отдельным префиксом и
+// таблицей маршрутов; после этого v1 помечается устаревшей так же, как legacy-маршруты.
+
+// apiRoute — маршрут относительно префикса версии. Маршруты, кроме public, требуют ключ API.
+type apiRoute struct {
+	path    string
+	handler http.HandlerFunc
+	public  bool
+}
+
+var v1Routes = []apiRoute{
+	{"/recommend", recommendHandler, false},
+	{"/profiles", profilesHandler, false},
+	{"/compare", compareHandler, false},
+	{"/criteria", criteriaHandler, true},
+	{"/wizard/sessions", wizardSessionsHandler, false},
+	{"/wizard/sessions/", wizardSessionsHandler, false},
+	{"/openapi.json", openAPIHandler, true},
+}
+
+// apiVersion — набор маршрутов под префиксом. У устаревшей версии заполнены deprecated
+// (с какого момента устарела), sunset (когда будет отключена) и successor (префикс замены).
+type apiVersion struct {
+	prefix     string
+	routes     []apiRoute
+	deprecated time.Time
+	sunset     time.Time
+	successor  string
+}
+
+// legacyAPISunset — дата отключения маршрутов /api/... без версии; задается API_LEGACY_SUNSET.
+var legacyAPISunset = time.Date(2027, time.April, 18, 0, 0, 0, 0, time.UTC)
+
+func apiVersions() []apiVersion {
+	return []apiVersion{
+		{prefix: api.BasePath, routes: v1Routes},
+		// Маршруты без версии — прежние адреса v1, оставлены для совместимости.
+		{
+			prefix:     "/api",
+			routes:     v1Routes,
+			deprecated: time.Date(2026, time.October, 18, 0, 0, 0, 0, time.UTC),
+			sunset:     legacyAPISunset,
+			successor:  api.BasePath,
+		},
+	}
+}
+
+func registerAPIRoutes(mux *http.ServeMux) {
+	for _, version := range apiVersions() {
+		for _, route := range version.routes {
+			handler := route.handler
+			switch {
+			case route.public:
+			case version.deprecated.IsZero():
+				handler = requireAPIKey(handler)
+			default:
+				handler = requireLegacyAPIKey(handler)
+			}
+			if !version.deprecated.IsZero() {
+				handler = deprecatedAPI(version, handler)
+			}
+			mux.HandleFunc(version.prefix+route.path, corsMiddleware(handler))
+		}
+	}
+}
+
+// deprecatedAPI добавляет к ответам устаревшей версии заголовки Deprecation, Sunset и
+// Link на тот же метод в версии-преемнике и логирует обращение. После даты sunset
+// версия отвечает 410 Gone.
+func deprecatedAPI(version apiVersion, next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		successor := version.successor + strings.TrimPrefix(r.URL.Path, version.prefix)
+		w.Header().Set("Deprecation", "@"+strconv.FormatInt(version.deprecated.Unix(), 10))
+		w.Header().Set("Sunset", version.sunset.UTC().Format(http.TimeFormat))
+		w.Header().Set("Link", "<"+successor+`>; rel="successor-version"`)
+
+		logger.LogTelegramAction("Запрос к устаревшей версии API", map[string]interface{}{
+			"Метод":      r.Method,
+			"Путь":       r.URL.Path,
+			"Замена":     successor,
+			"Адрес":      r.RemoteAddr,
+			"User-Agent": r.UserAgent(),
+			"С ключом":   r.Header.Get("Authorization") != "",
+		})
+		if !time.Now().Before(version.sunset) {
+			lang := requestLang(r)
+			http.Error(w, tr(lang, "Версия API отключена, используйте ")+successor, http.StatusGone)
+			return
+		}
+		next(w, r.WithContext(context.WithValue(r.Context(), deprecatedAPIContextKey{}, true)))
+	}
+}
+
+type deprecatedAPIContextKey struct{}
+
+func isDeprecatedAPIRequest(r *http.Request) bool {
+	deprecated, _ := r.Context().Value(deprecatedAPIContextKey{}).(bool)
+	return deprecated
+}
+
+// legacyCriterionNames переводит названия критериев в идентификаторы в запросе к устаревшим
+// маршрутам /api/...: их клиенты появились раньше идентификаторов и отправляют названия.
+// В v1 названия не принимаются, запрос не меняется. Названия в запросе отмечаются
+// заголовком Warning и записью в лог; mapCriteria применяет преобразование к полям запроса.
+func legacyCriterionNames(w http.ResponseWriter, r *http.Request, lang Lang, mapCriteria func(m, vm *keyMapper)) error {
+	if !isDeprecatedAPIRequest(r) {
+		return nil
+	}
+	var used []string
+	m, vm := namesToIDs(defaultCriteria, &used), namesToIDs(vendorCriteria, &used)
+	mapCriteria(m, vm)
+	if len(used) > 0 {
+		w.Header().Add("Warning", `299 - "Criterion names are deprecated, use criterion IDs from `+api.BasePath+`/criteria"`)
+		logger.LogTelegramAction("Названия критериев в запросе к устаревшей версии API", map[string]interface{}{
+			"Путь":     r.URL.Path,
+			"Названия": used,
+			"Адрес":    r.RemoteAddr,
+		})
+	}
+	if err := m.err(lang, "критерии", defaultCriteria); err != nil {
+		return err
+	}
+	return vm.err(lang, "критерии поставщиков", vendorCriteria)
+}
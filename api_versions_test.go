@@ -0,0 +1,296 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strconv"
+	"strings"
+	"testing"
+
+	"tg-bot-checklist/api"
+)
+
+// Контрактные тесты: ответы обработчиков v1 сверяются со спецификацией api.OpenAPI() —
+// код ответа описан у операции, тело разбирается в тип пакета api, указанный в схеме,
+// без неизвестных полей.
+
+func specOperations(t *testing.T) map[string]interface{} {
+	t.Helper()
+	paths, ok := api.OpenAPI()["paths"].(map[string]interface{})
+	if !ok || len(paths) == 0 {
+		t.Fatal("в спецификации нет paths")
+	}
+	return paths
+}
+
+func specOperation(t *testing.T, method, specPath string) map[string]interface{} {
+	t.Helper()
+	item, _ := specOperations(t)[specPath].(map[string]interface{})
+	op, _ := item[strings.ToLower(method)].(map[string]interface{})
+	if op == nil {
+		t.Fatalf("операции %s %s нет в спецификации", method, specPath)
+	}
+	return op
+}
+
+// routeFor возвращает маршрут v1, обслуживающий путь спецификации.
+func routeFor(specPath string) (apiRoute, bool) {
+	for _, route := range v1Routes {
+		if route.path == specPath || strings.HasSuffix(route.path, "/") && strings.HasPrefix(specPath, route.path) {
+			return route, true
+		}
+	}
+	return apiRoute{}, false
+}
+
+func TestV1RoutesMatchOpenAPI(t *testing.T) {
+	paths := specOperations(t)
+
+	for _, route := range v1Routes {
+		found := false
+		for specPath := range paths {
+			if specPath == route.path || strings.HasSuffix(route.path, "/") && strings.HasPrefix(specPath, route.path) {
+				found = true
+			}
+		}
+		if !found {
+			t.Errorf("маршрут %s не описан в спецификации", route.path)
+		}
+	}
+
+	for specPath, item := range paths {
+		route, ok := routeFor(specPath)
+		if !ok {
+			t.Errorf("путь %s из спецификации не обслуживается маршрутами v1", specPath)
+			continue
+		}
+		for method, op := range item.(map[string]interface{}) {
+			security, hasSecurity := op.(map[string]interface{})["security"].([]interface{})
+			public := hasSecurity && len(security) == 0
+			if public != route.public {
+				t.Errorf("%s %s: публичный в спецификации = %t, в маршрутах = %t",
+					strings.ToUpper(method), specPath, public, route.public)
+			}
+		}
+	}
+}
+
+func newTestAPIServer(t *testing.T) *httptest.Server {
+	t.Helper()
+	logger = NewLogger(true)
+	required := apiKeysRequired
+	apiKeysRequired = false
+	t.Cleanup(func() { apiKeysRequired = required })
+
+	mux := http.NewServeMux()
+	registerAPIRoutes(mux)
+	server := httptest.NewServer(mux)
+	t.Cleanup(server.Close)
+	return server
+}
+
+// contractCall выполняет запрос к обработчику v1 и сверяет ответ с операцией specPath:
+// код должен быть wantStatus и описан в спецификации, JSON-тело разбирается в into.
+func contractCall(t *testing.T, server *httptest.Server, method, specPath, path string, body interface{},
+	headers map[string]string, wantStatus int, into interface{}) http.Header {
+	t.Helper()
+
+	var reader io.Reader
+	if body != nil {
+		data, err := json.Marshal(body)
+		if err != nil {
+			t.Fatal(err)
+		}
+		reader = bytes.NewReader(data)
+	}
+	req, err := http.NewRequest(method, server.URL+api.BasePath+path, reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	for k, v := range headers {
+		req.Header.Set(k, v)
+	}
+	resp, err := server.Client().Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	name := method + " " + path
+	if resp.StatusCode != wantStatus {
+		t.Fatalf("%s: код %d, ожидался %d: %s", name, resp.StatusCode, wantStatus, data)
+	}
+	responses, _ := specOperation(t, method, specPath)["responses"].(map[string]interface{})
+	response, _ := responses[strconv.Itoa(resp.StatusCode)].(map[string]interface{})
+	if response == nil {
+		t.Fatalf("%s: код %d не описан в спецификации", name, resp.StatusCode)
+	}
+
+	content, _ := response["content"].(map[string]interface{})
+	if len(content) == 0 {
+		if len(bytes.TrimSpace(data)) > 0 {
+			t.Errorf("%s: код %d описан без тела, получено %q", name, resp.StatusCode, data)
+		}
+		return resp.Header
+	}
+	contentType, _, _ := strings.Cut(resp.Header.Get("Content-Type"), ";")
+	media, _ := content[contentType].(map[string]interface{})
+	if media == nil {
+		t.Fatalf("%s: тип %q для кода %d не описан", name, contentType, resp.StatusCode)
+	}
+	if contentType != "application/json" {
+		return resp.Header
+	}
+
+	if into == nil {
+		t.Fatalf("%s: не задан тип для JSON-ответа", name)
+	}
+	if ref, _ := media["schema"].(map[string]interface{})["$ref"].(string); ref != "" {
+		if want := reflect.TypeOf(into).Elem().Name(); ref != "#/components/schemas/"+want {
+			t.Errorf("%s: схема ответа %s, тип в тесте %s", name, ref, want)
+		}
+	}
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(into); err != nil {
+		t.Errorf("%s: ответ не соответствует типу %T: %v", name, into, err)
+	}
+	return resp.Header
+}
+
+func TestHandlersMatchOpenAPI(t *testing.T) {
+	server := newTestAPIServer(t)
+
+	var spec map[string]interface{}
+	contractCall(t, server, "GET", "/openapi.json", "/openapi.json", nil, nil, http.StatusOK, &spec)
+	if !reflect.DeepEqual(spec["paths"], mustRoundTrip(t, api.OpenAPI()["paths"])) {
+		t.Error("GET /openapi.json отдает не ту спецификацию, что строит api.OpenAPI()")
+	}
+
+	var catalog api.CatalogResponse
+	header := contractCall(t, server, "GET", "/criteria", "/criteria", nil, nil, http.StatusOK, &catalog)
+	if len(catalog.Criteria) == 0 {
+		t.Error("GET /criteria: пустой каталог")
+	}
+	contractCall(t, server, "GET", "/criteria", "/criteria", nil,
+		map[string]string{"If-None-Match": header.Get("ETag")}, http.StatusNotModified, nil)
+
+	request := api.RecommendationRequest{
+		SelectedCriteria:   []string{"latency", "initial_investment"},
+		CriteriaPriorities: map[string]int{"latency": 3, "initial_investment": 4},
+		CompareMethods:     true,
+	}
+	var recommendation api.RecommendationResponse
+	contractCall(t, server, "POST", "/recommend", "/recommend", request, nil, http.StatusOK, &recommendation)
+	if recommendation.Outcome.OptionID == "" && len(recommendation.Outcome.Tied) == 0 {
+		t.Errorf("POST /recommend: пустой outcome: %+v", recommendation.Outcome)
+	}
+	var invalid api.ValidationError
+	contractCall(t, server, "POST", "/recommend", "/recommend",
+		map[string]interface{}{"selected_criteria": []string{"no_such_criterion"}, "unknown": 1},
+		nil, http.StatusBadRequest, &invalid)
+	if len(invalid.Errors) == 0 {
+		t.Error("POST /recommend: ошибка валидации без списка ошибок")
+	}
+
+	var compare api.CompareResponse
+	other := request
+	other.CriteriaPriorities = map[string]int{"latency": 5, "initial_investment": 1}
+	contractCall(t, server, "POST", "/compare", "/compare", api.CompareRequest{A: &request, B: &other},
+		nil, http.StatusOK, &compare)
+
+	// Профили принадлежат ключу API и без него недоступны, даже когда ключ не обязателен.
+	contractCall(t, server, "GET", "/profiles", "/profiles", nil, nil, http.StatusUnauthorized, nil)
+
+	var view api.WizardView
+	contractCall(t, server, "POST", "/wizard/sessions", "/wizard/sessions", nil, nil, http.StatusCreated, &view)
+	t.Cleanup(func() {
+		stateMu.Lock()
+		defer stateMu.Unlock()
+		if session := wizardSessions[view.SessionID]; session != nil {
+			session.close()
+			delete(wizardSessions, view.SessionID)
+		}
+	})
+	contractCall(t, server, "POST", "/wizard/sessions", "/wizard/sessions",
+		api.WizardSessionRequest{HandoffToken: "bogus"}, nil, http.StatusBadRequest, &invalid)
+
+	path := "/wizard/sessions/" + view.SessionID
+	contractCall(t, server, "GET", "/wizard/sessions/{id}", path, nil, nil, http.StatusOK, &view)
+	contractCall(t, server, "POST", "/wizard/sessions/{id}/answer", path+"/answer",
+		api.WizardAnswerRequest{Action: "bogus"}, nil, http.StatusBadRequest, &invalid)
+	contractCall(t, server, "POST", "/wizard/sessions/{id}/back", path+"/back", nil, nil, http.StatusConflict, nil)
+	contractCall(t, server, "POST", "/wizard/sessions/{id}/finalize", path+"/finalize", nil, nil, http.StatusConflict, nil)
+	if len(view.Answers) == 0 {
+		t.Fatal("у первого вопроса нет ответов")
+	}
+	contractCall(t, server, "POST", "/wizard/sessions/{id}/answer", path+"/answer",
+		api.WizardAnswerRequest{Action: view.Answers[0].Action}, nil, http.StatusOK, &view)
+	contractCall(t, server, "DELETE", "/wizard/sessions/{id}", path, nil, nil, http.StatusNoContent, nil)
+	contractCall(t, server, "GET", "/wizard/sessions/{id}", path, nil, nil, http.StatusNotFound, nil)
+}
+
+func TestHandlersRequireAPIKeyAsSpecified(t *testing.T) {
+	server := newTestAPIServer(t)
+	apiKeysRequired = true
+
+	contractCall(t, server, "POST", "/recommend", "/recommend", api.RecommendationRequest{}, nil, http.StatusUnauthorized, nil)
+	contractCall(t, server, "GET", "/criteria", "/criteria", nil, nil, http.StatusOK, &api.CatalogResponse{})
+}
+
+// mustRoundTrip приводит значение к виду, в котором его возвращает разбор JSON.
+func mustRoundTrip(t *testing.T, v interface{}) interface{} {
+	t.Helper()
+	data, err := json.Marshal(v)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var result interface{}
+	if err := json.Unmarshal(data, &result); err != nil {
+		t.Fatal(err)
+	}
+	return result
+}
+
+func TestLegacyRoutesKeyRequirement(t *testing.T) {
+	server := newTestAPIServer(t)
+	apiKeysRequired = true
+	legacy := legacyAPIKeysRequired
+	t.Cleanup(func() { legacyAPIKeysRequired = legacy })
+
+	for _, tt := range []struct {
+		legacyRequired bool
+		want           int
+	}{
+		{false, http.StatusOK},
+		{true, http.StatusUnauthorized},
+	} {
+		legacyAPIKeysRequired = tt.legacyRequired
+		resp, err := server.Client().Post(server.URL+"/api/recommend", "application/json",
+			strings.NewReader(`{"selected_criteria": ["latency"], "criteria_priorities": {"latency": 3}}`))
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp.Body.Close()
+		if resp.StatusCode != tt.want {
+			t.Errorf("API_LEGACY_KEYS_REQUIRED=%t: POST /api/recommend без ключа — %d, ожидался %d",
+				tt.legacyRequired, resp.StatusCode, tt.want)
+		}
+		if resp.Header.Get("Deprecation") == "" {
+			t.Error("ответ устаревшего маршрута без заголовка Deprecation")
+		}
+	}
+
+	contractCall(t, server, "POST", "/recommend", "/recommend", api.RecommendationRequest{}, nil, http.StatusUnauthorized, nil)
+}
@@ -0,0 +1,410 @@
+package main
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+// Ключи API. В базе хранится только SHA-256 ключа; сам ключ показывается администратору
+// один раз при выпуске. Запрос передает ключ в заголовке "Authorization: Bearer <ключ>".
+
+const apiKeyPrefix = "tgc_"
+
+// Лимиты нового ключа по умолчанию; 0 — без ограничения.
+const (
+	defaultAPIKeyRatePerMinute = 60
+	defaultAPIKeyDailyQuota    = 1000
+)
+
+var (
+	// apiKeysRequired — запросы без ключа отклоняются; API_KEYS_REQUIRED=false разрешает
+	// анонимные запросы (ключ, если передан, все равно проверяется).
+	apiKeysRequired = true
+	// legacyAPIKeysRequired — ключ обязателен и на устаревших маршрутах /api/... без версии;
+	// включается API_LEGACY_KEYS_REQUIRED=true, когда клиенты перешли на ключи (см. README).
+	legacyAPIKeysRequired = false
+	// adminChatIDs — chat ID администраторов из ADMIN_CHAT_IDS, им доступна команда /apikey.
+	adminChatIDs = map[int64]bool{}
+)
+
+var errAPIKeyNotFound = errors.New("ключ API не найден")
+
+// apiClient — клиент API, определенный по ключу.
+type apiClient struct {
+	keyID         int64
+	name          string
+	ratePerMinute int
+	dailyQuota    int
+}
+
+type apiKeyInfo struct {
+	apiClient
+	prefix     string
+	usedToday  int
+	createdAt  time.Time
+	revokedAt  *time.Time
+	lastUsedAt *time.Time
+}
+
+func parseAdminChatIDs(value string) (map[int64]bool, error) {
+	ids := map[int64]bool{}
+	for _, field := range strings.FieldsFunc(value, func(r rune) bool { return r == ',' || r == ' ' }) {
+		id, err := strconv.ParseInt(field, 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("некорректный chat ID %q", field)
+		}
+		ids[id] = true
+	}
+	return ids, nil
+}
+
+func hashAPIKey(key string) string {
+	sum := sha256.Sum256([]byte(key))
+	return hex.EncodeToString(sum[:])
+}
+
+// issueAPIKey выпускает ключ и возвращает его открытое значение.
+func issueAPIKey(name string, ratePerMinute, dailyQuota int, createdBy int64) (string, int64, error) {
+	b := make([]byte, 24)
+	if _, err := rand.Read(b); err != nil {
+		return "", 0, fmt.Errorf("ошибка генерации ключа: %w", err)
+	}
+	key := apiKeyPrefix + base64.RawURLEncoding.EncodeToString(b)
+
+	var id int64
+	err := pool.QueryRow(context.Background(),
+		`INSERT INTO api_keys (name, key_hash, prefix, rate_per_minute, daily_quota, created_by)
+		 VALUES ($1, $2, $3, $4, $5, $6) RETURNING id`,
+		name, hashAPIKey(key), key[:len(apiKeyPrefix)+6], ratePerMinute, dailyQuota, createdBy).Scan(&id)
+	if err != nil {
+		return "", 0, fmt.Errorf("ошибка сохранения ключа: %w", err)
+	}
+	return key, id, nil
+}
+
+func revokeAPIKey(id int64) error {
+	tag, err := pool.Exec(context.Background(),
+		`UPDATE api_keys SET revoked_at = CURRENT_TIMESTAMP WHERE id = $1 AND revoked_at IS NULL`, id)
+	if err != nil {
+		return fmt.Errorf("ошибка отзыва ключа: %w", err)
+	}
+	if tag.RowsAffected() == 0 {
+		return errAPIKeyNotFound
+	}
+	return nil
+}
+
+func listAPIKeys() ([]apiKeyInfo, error) {
+	rows, err := pool.Query(context.Background(),
+		`SELECT k.id, k.name, k.prefix, k.rate_per_minute, k.daily_quota, COALESCE(u.requests, 0),
+		        k.created_at, k.revoked_at, k.last_used_at
+		 FROM api_keys k
+		 LEFT JOIN api_key_usage u ON u.key_id = k.id AND u.day = $1
+		 ORDER BY k.id`, quotaDay(time.Now()))
+	if err != nil {
+		return nil, fmt.Errorf("ошибка чтения ключей: %w", err)
+	}
+	defer rows.Close()
+
+	var keys []apiKeyInfo
+	for rows.Next() {
+		var k apiKeyInfo
+		if err := rows.Scan(&k.keyID, &k.name, &k.prefix, &k.ratePerMinute, &k.dailyQuota, &k.usedToday,
+			&k.createdAt, &k.revokedAt, &k.lastUsedAt); err != nil {
+			return nil, err
+		}
+		keys = append(keys, k)
+	}
+	return keys, rows.Err()
+}
+
+// lookupAPIKey находит действующий ключ; отозванный или неизвестный — errAPIKeyNotFound.
+func lookupAPIKey(key string) (*apiClient, error) {
+	client := &apiClient{}
+	err := pool.QueryRow(context.Background(),
+		`UPDATE api_keys SET last_used_at = CURRENT_TIMESTAMP
+		 WHERE key_hash = $1 AND revoked_at IS NULL
+		 RETURNING id, name, rate_per_minute, daily_quota`, hashAPIKey(key)).
+		Scan(&client.keyID, &client.name, &client.ratePerMinute, &client.dailyQuota)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, errAPIKeyNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("ошибка проверки ключа: %w", err)
+	}
+	return client, nil
+}
+
+// quotaDay — начало суток дневной квоты, по UTC.
+func quotaDay(now time.Time) time.Time {
+	y, m, d := now.UTC().Date()
+	return time.Date(y, m, d, 0, 0, 0, 0, time.UTC)
+}
+
+// consumeDailyQuota засчитывает запрос в дневную квоту ключа; false — квота исчерпана.
+func consumeDailyQuota(client *apiClient, now time.Time) (bool, error) {
+	var requests int
+	err := pool.QueryRow(context.Background(),
+		`INSERT INTO api_key_usage (key_id, day, requests) VALUES ($1, $2, 1)
+		 ON CONFLICT (key_id, day) DO UPDATE SET requests = api_key_usage.requests + 1
+		 WHERE $3 = 0 OR api_key_usage.requests < $3
+		 RETURNING requests`, client.keyID, quotaDay(now), client.dailyQuota).Scan(&requests)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return false, nil
+	}
+	if err != nil {
+		return false, fmt.Errorf("ошибка учета квоты: %w", err)
+	}
+	return true, nil
+}
+
+// rateWindow — число запросов ключа в текущей минуте.
+type rateWindow struct {
+	start time.Time
+	count int
+}
+
+var (
+	rateMu      sync.Mutex
+	rateWindows = make(map[int64]*rateWindow)
+)
+
+// allowRate проверяет поминутный лимит ключа; при превышении возвращает время до следующего окна.
+func allowRate(client *apiClient, now time.Time) (bool, time.Duration) {
+	if client.ratePerMinute <= 0 {
+		return true, 0
+	}
+	rateMu.Lock()
+	defer rateMu.Unlock()
+
+	window := rateWindows[client.keyID]
+	if window == nil || now.Sub(window.start) >= time.Minute {
+		window = &rateWindow{start: now}
+		rateWindows[client.keyID] = window
+	}
+	if window.count >= client.ratePerMinute {
+		return false, window.start.Add(time.Minute).Sub(now)
+	}
+	window.count++
+	return true, 0
+}
+
+type apiClientContextKey struct{}
+
+// apiClientFrom возвращает клиента запроса; nil — анонимный запрос.
+func apiClientFrom(r *http.Request) *apiClient {
+	client, _ := r.Context().Value(apiClientContextKey{}).(*apiClient)
+	return client
+}
+
+// apiKeyIDOf — идентификатор ключа для колонки answers.api_key_id.
+func apiKeyIDOf(client *apiClient) *int64 {
+	if client == nil {
+		return nil
+	}
+	return &client.keyID
+}
+
+// requireAPIKey проверяет ключ запроса, поминутный лимит и дневную квоту ключа и передает
+// клиента обработчику через контекст запроса.
+func requireAPIKey(next http.HandlerFunc) http.HandlerFunc {
+	return checkAPIKey(func() bool { return apiKeysRequired }, next)
+}
+
+// requireLegacyAPIKey — requireAPIKey для маршрутов без версии: запросы без ключа
+// пропускаются, пока не включен legacyAPIKeysRequired. Переданный ключ проверяется всегда.
+func requireLegacyAPIKey(next http.HandlerFunc) http.HandlerFunc {
+	return checkAPIKey(func() bool { return apiKeysRequired && legacyAPIKeysRequired }, next)
+}
+
+func checkAPIKey(required func() bool, next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		lang := requestLang(r)
+		key, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+		key = strings.TrimSpace(key)
+		if !ok || key == "" {
+			if !required() {
+				next(w, r)
+				return
+			}
+			w.Header().Set("WWW-Authenticate", "Bearer")
+			http.Error(w, tr(lang, "Требуется ключ API: заголовок Authorization: Bearer <ключ>"), http.StatusUnauthorized)
+			return
+		}
+		if pool == nil {
+			http.Error(w, tr(lang, "База данных недоступна"), http.StatusServiceUnavailable)
+			return
+		}
+
+		client, err := lookupAPIKey(key)
+		if errors.Is(err, errAPIKeyNotFound) {
+			logger.LogTelegramAction("Отклонен неверный ключ API", map[string]interface{}{
+				"Путь":    r.URL.Path,
+				"Адрес":   r.RemoteAddr,
+				"Префикс": key[:min(len(key), len(apiKeyPrefix)+6)],
+			})
+			w.Header().Set("WWW-Authenticate", `Bearer error="invalid_token"`)
+			http.Error(w, tr(lang, "Неверный или отозванный ключ API"), http.StatusUnauthorized)
+			return
+		}
+		if err != nil {
+			logger.Printf("Ошибка проверки ключа API: %v", err)
+			http.Error(w, tr(lang, "База данных недоступна"), http.StatusServiceUnavailable)
+			return
+		}
+
+		now := time.Now()
+		if ok, retry := allowRate(client, now); !ok {
+			w.Header().Set("Retry-After", strconv.Itoa(int(retry.Seconds())+1))
+			http.Error(w, tr(lang, "Превышен лимит запросов ключа API: %d в минуту", client.ratePerMinute), http.StatusTooManyRequests)
+			return
+		}
+		ok, err = consumeDailyQuota(client, now)
+		if err != nil {
+			logger.Printf("Ошибка учета квоты ключа API %d: %v", client.keyID, err)
+			http.Error(w, tr(lang, "База данных недоступна"), http.StatusServiceUnavailable)
+			return
+		}
+		if !ok {
+			nextDay := quotaDay(now).AddDate(0, 0, 1)
+			w.Header().Set("Retry-After", strconv.Itoa(int(nextDay.Sub(now).Seconds())+1))
+			http.Error(w, tr(lang, "Исчерпана дневная квота ключа API: %d запросов", client.dailyQuota), http.StatusTooManyRequests)
+			return
+		}
+
+		next(w, r.WithContext(context.WithValue(r.Context(), apiClientContextKey{}, client)))
+	}
+}
+
+func isAdmin(chatID int64) bool {
+	return adminChatIDs[chatID]
+}
+
+// handleAPIKeyCommand обрабатывает команду администратора:
+//   - /apikey — список ключей;
+//   - /apikey new <название> [запросов в минуту] [запросов в сутки] — выпустить ключ;
+//   - /apikey revoke <id> — отозвать ключ.
+func handleAPIKeyCommand(bot *tgbotapi.BotAPI, chatID int64, text string) {
+	lang := userLang(chatID)
+	args := strings.Fields(text)[1:]
+	reply := func(text string) {
+		sendMessage(bot, tgbotapi.NewMessage(chatID, text))
+	}
+	usage := tr(lang, "Команды:\n/apikey — список ключей\n/apikey new <название> [запросов в минуту] [запросов в сутки] — выпустить ключ (0 — без ограничения)\n/apikey revoke <id> — отозвать ключ")
+
+	if len(args) == 0 || args[0] == "list" {
+		keys, err := listAPIKeys()
+		if err != nil {
+			logger.Printf("Ошибка чтения ключей API: %v", err)
+			reply(tr(lang, "Не удалось загрузить ключи API."))
+			return
+		}
+		if len(keys) == 0 {
+			reply(tr(lang, "Ключей API нет.") + "\n\n" + usage)
+			return
+		}
+		var b strings.Builder
+		b.WriteString(tr(lang, "Ключи API:") + "\n")
+		for _, k := range keys {
+			b.WriteString(tr(lang, "#%d %s (%s…): %s в минуту, сегодня %d из %s, создан %s",
+				k.keyID, k.name, k.prefix, formatLimit(lang, k.ratePerMinute), k.usedToday,
+				formatLimit(lang, k.dailyQuota), k.createdAt.Format("02.01.2006")))
+			if k.revokedAt != nil {
+				b.WriteString(tr(lang, ", отозван %s", k.revokedAt.Format("02.01.2006")))
+			}
+			b.WriteString("\n")
+		}
+		reply(b.String())
+		return
+	}
+
+	switch args[0] {
+	case "new":
+		if len(args) < 2 {
+			reply(usage)
+			return
+		}
+		name, limitArgs := splitAPIKeyArgs(args[1:])
+		limits := []int{defaultAPIKeyRatePerMinute, defaultAPIKeyDailyQuota}
+		for i, arg := range limitArgs {
+			n, err := strconv.Atoi(arg)
+			if err != nil || n < 0 {
+				reply(tr(lang, "Лимит должен быть неотрицательным целым числом: %s", arg))
+				return
+			}
+			limits[i] = n
+		}
+		key, id, err := issueAPIKey(name, limits[0], limits[1], chatID)
+		if err != nil {
+			logger.Printf("Ошибка выпуска ключа API: %v", err)
+			reply(tr(lang, "Не удалось выпустить ключ API."))
+			return
+		}
+		logger.LogTelegramAction("Выпущен ключ API", map[string]interface{}{
+			"KeyID":    id,
+			"Название": name,
+			"ChatID":   chatID,
+		})
+		sendSecretMessage(bot, tgbotapi.NewMessage(chatID, tr(lang, "Ключ #%d «%s»: %s в минуту, %s в сутки.\n\n%s\n\nКлюч показывается один раз, сохраните его.",
+			id, name, formatLimit(lang, limits[0]), formatLimit(lang, limits[1]), key)), key)
+	case "revoke":
+		var id int64
+		var err error
+		if len(args) == 2 {
+			id, err = strconv.ParseInt(strings.TrimPrefix(args[1], "#"), 10, 64)
+		}
+		if len(args) != 2 || err != nil {
+			reply(usage)
+			return
+		}
+		if err := revokeAPIKey(id); errors.Is(err, errAPIKeyNotFound) {
+			reply(tr(lang, "Действующий ключ #%d не найден.", id))
+			return
+		} else if err != nil {
+			logger.Printf("Ошибка отзыва ключа API: %v", err)
+			reply(tr(lang, "Не удалось отозвать ключ API."))
+			return
+		}
+		logger.LogTelegramAction("Отозван ключ API", map[string]interface{}{
+			"KeyID":  id,
+			"ChatID": chatID,
+		})
+		reply(tr(lang, "Ключ #%d отозван.", id))
+	default:
+		reply(usage)
+	}
+}
+
+// splitAPIKeyArgs делит аргументы /apikey new на название ключа и лимиты: до двух последних
+// целых чисел — лимиты, остальные слова — название, которое может состоять из нескольких слов.
+func splitAPIKeyArgs(args []string) (name string, limits []string) {
+	n := len(args)
+	for n > 1 && len(args)-n < 2 {
+		if _, err := strconv.Atoi(args[n-1]); err != nil {
+			break
+		}
+		n--
+	}
+	return strings.Join(args[:n], " "), args[n:]
+}
+
+func formatLimit(lang Lang, n int) string {
+	if n == 0 {
+		return tr(lang, "без ограничения")
+	}
+	return strconv.Itoa(n)
+}
@@ -0,0 +1,54 @@
+package main
+
+import (
+	"bytes"
+	"reflect"
+	"strings"
+	"testing"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+func TestSplitAPIKeyArgs(t *testing.T) {
+	tests := []struct {
+		args   string
+		name   string
+		limits []string
+	}{
+		{"Мобильное приложение", "Мобильное приложение", []string{}},
+		{"Мобильное приложение 120", "Мобильное приложение", []string{"120"}},
+		{"Мобильное приложение 120 5000", "Мобильное приложение", []string{"120", "5000"}},
+		{"Команда 2 120 0", "Команда 2", []string{"120", "0"}},
+		{"Партнер -1", "Партнер", []string{"-1"}},
+		{"2024", "2024", []string{}},
+		{"Сайт v2", "Сайт v2", []string{}},
+	}
+	for _, tt := range tests {
+		name, limits := splitAPIKeyArgs(strings.Fields(tt.args))
+		if name != tt.name || !reflect.DeepEqual(limits, tt.limits) {
+			t.Errorf("splitAPIKeyArgs(%q) = %q, %q, ожидалось %q, %q", tt.args, name, limits, tt.name, tt.limits)
+		}
+	}
+}
+
+func TestSendSecretMessageKeepsSecretOutOfLog(t *testing.T) {
+	var out bytes.Buffer
+	logger = &CustomLogger{debug: true, out: &out}
+	t.Cleanup(func() { logger = NewLogger(true) })
+
+	const chatID, secret = int64(-1), "tgc_0123456789abcdef"
+	session := &wizardSession{chatID: chatID, messages: make(map[int]*WizardMessage)}
+	session.run(func() {
+		sendSecretMessage(nil, tgbotapi.NewMessage(chatID, "Ключ: "+secret), secret)
+	})
+
+	if got := session.messages[session.lastID].Text; got != "Ключ: "+secret {
+		t.Errorf("отправлен текст %q, ожидался ключ целиком", got)
+	}
+	if strings.Contains(out.String(), secret) {
+		t.Errorf("ключ попал в лог:\n%s", out.String())
+	}
+	if !strings.Contains(out.String(), "[скрыто]") {
+		t.Errorf("в логе нет отметки о скрытом ключе:\n%s", out.String())
+	}
+}
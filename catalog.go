@@ -0,0 +1,500 @@
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"math"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+)
+
+func scaleContains(s PriorityScale, prio int) bool {
+	return prio >= s.Min && prio <= s.Max
+}
+
+// SpecialOption — значение специального критерия и баллы, которые оно дает.
+type SpecialOption struct {
+	Value        string                     `json:"value"`
+	Description  string                     `json:"description"`
+	Scores       Scores                     `json:"scores"`
+	Translations map[Lang]SpecialOptionText `json:"translations,omitempty"`
+}
+
+// CriterionText — перевод текстов критерия. Незаполненные поля выводятся по-русски.
+type CriterionText struct {
+	Name          string `json:"name"`
+	Category      string `json:"category,omitempty"`
+	Description   string `json:"description,omitempty"`
+	SpecialPrompt string `json:"special_prompt,omitempty"`
+}
+
+type SpecialOptionText struct {
+	Value       string `json:"value"`
+	Description string `json:"description,omitempty"`
+}
+
+// Catalog — описание вариантов развертывания, критериев и жестких ограничений.
+// По умолчанию используется встроенный каталог, CATALOG_FILE задает JSON-файл с заменой.
+type Catalog struct {
+	PriorityScale  *PriorityScale     `json:"priority_scale,omitempty"`
+	Options        []DeploymentOption `json:"options"`
+	Criteria       []Criterion        `json:"criteria"`
+	VetoRules      []VetoRule         `json:"veto_rules"`
+	Vendors        []Vendor           `json:"vendors,omitempty"`
+	VendorCriteria []Criterion        `json:"vendor_criteria,omitempty"`
+	PriceSheet     *PriceSheet        `json:"price_sheet,omitempty"`
+	FollowUps      []FollowUp         `json:"follow_ups,omitempty"`
+}
+
+func getDefaultDeploymentOptions() []DeploymentOption {
+	return []DeploymentOption{
+		{ID: "on_prem", Name: "On-Premise", ShortName: "OnPrem"},
+		{ID: "private", Name: "Private Cloud", ShortName: "Private"},
+		{ID: "public", Name: "Public Cloud", ShortName: "Public"},
+	}
+}
+
+// loadCatalogFile заменяет встроенный каталог содержимым JSON-файла.
+func loadCatalogFile(path string) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+
+	var catalog Catalog
+	if err := json.Unmarshal(data, &catalog); err != nil {
+		return fmt.Errorf("ошибка парсинга JSON: %w", err)
+	}
+	if err := catalog.validate(); err != nil {
+		return err
+	}
+
+	for i := range catalog.Options {
+		if catalog.Options[i].ShortName == "" {
+			catalog.Options[i].ShortName = catalog.Options[i].Name
+		}
+	}
+
+	if catalog.PriorityScale != nil {
+		priorityScale = *catalog.PriorityScale
+	}
+	deploymentOptions = catalog.Options
+	defaultCriteria = catalog.Criteria
+	vetoRules = catalog.VetoRules
+	vendors = catalog.Vendors
+	vendorCriteria = catalog.VendorCriteria
+	priceSheet = catalog.PriceSheet
+	followUps = catalog.FollowUps
+	return nil
+}
+
+func (c Catalog) validate() error {
+	if scale := c.PriorityScale; scale != nil && (scale.Min < 1 || scale.Max <= scale.Min) {
+		return fmt.Errorf("шкала приоритетов должна быть вида 1 <= min < max, получено [%d, %d]", scale.Min, scale.Max)
+	}
+	if len(c.Options) < 2 {
+		return fmt.Errorf("в каталоге должно быть не менее двух вариантов развертывания")
+	}
+
+	ids := make(map[string]bool, len(c.Options))
+	for _, option := range c.Options {
+		if option.ID == "" || option.Name == "" {
+			return fmt.Errorf("у варианта развертывания должны быть заданы id и name")
+		}
+		if ids[option.ID] {
+			return fmt.Errorf("вариант развертывания %q указан дважды", option.ID)
+		}
+		ids[option.ID] = true
+	}
+
+	checkScores := func(where string, scores Scores) error {
+		for id := range scores {
+			if !ids[id] {
+				return fmt.Errorf("%s: неизвестный вариант развертывания %q", where, id)
+			}
+		}
+		for _, option := range c.Options {
+			if _, ok := scores[option.ID]; !ok {
+				return fmt.Errorf("%s: нет балла для варианта %q", where, option.ID)
+			}
+		}
+		return nil
+	}
+
+	names := make(map[string]bool, len(c.Criteria))
+	criterionIDs := make(map[string]bool, len(c.Criteria)+len(c.VendorCriteria))
+	checkCriterion := func(crit Criterion) error {
+		if crit.ID == "" || criterionIDs[crit.ID] {
+			return fmt.Errorf("%s: идентификатор критерия %q пустой или повторяется", crit.Name, crit.ID)
+		}
+		criterionIDs[crit.ID] = true
+		if !criterionIDPattern.MatchString(crit.ID) {
+			return fmt.Errorf("%s: идентификатор критерия %q должен состоять из латинских строчных букв, цифр и _", crit.Name, crit.ID)
+		}
+		if lang, ok := unsupportedLang(crit.Translations); ok {
+			return fmt.Errorf("%s: неподдерживаемый язык перевода %q", crit.Name, lang)
+		}
+		for _, special := range crit.SpecialOptions {
+			if lang, ok := unsupportedLang(special.Translations); ok {
+				return fmt.Errorf("%s/%s: неподдерживаемый язык перевода %q", crit.Name, special.Value, lang)
+			}
+		}
+		return nil
+	}
+
+	for _, crit := range c.Criteria {
+		if crit.Name == "" || names[crit.Name] {
+			return fmt.Errorf("имя критерия %q пустое или повторяется", crit.Name)
+		}
+		names[crit.Name] = true
+		if err := checkCriterion(crit); err != nil {
+			return err
+		}
+
+		if !crit.IsSpecial {
+			if err := checkScores(crit.Name, crit.BaseScores); err != nil {
+				return err
+			}
+			continue
+		}
+		if len(crit.SpecialOptions) == 0 {
+			return fmt.Errorf("%s: у специального критерия нет значений", crit.Name)
+		}
+		for _, special := range crit.SpecialOptions {
+			if err := checkScores(crit.Name+"/"+special.Value, special.Scores); err != nil {
+				return err
+			}
+		}
+	}
+
+	vendorIDs := make(map[string]bool, len(c.Vendors))
+	for _, vendor := range c.Vendors {
+		if vendor.ID == "" || vendorIDs[vendor.ID] {
+			return fmt.Errorf("идентификатор поставщика %q пустой или повторяется", vendor.ID)
+		}
+		if !ids[vendor.Option] {
+			return fmt.Errorf("поставщик %s: неизвестный вариант развертывания %q", vendor.ID, vendor.Option)
+		}
+		if lang, ok := unsupportedLang(vendor.Translations); ok {
+			return fmt.Errorf("поставщик %s: неподдерживаемый язык перевода %q", vendor.ID, lang)
+		}
+		vendorIDs[vendor.ID] = true
+	}
+	for _, crit := range c.VendorCriteria {
+		if err := checkCriterion(crit); err != nil {
+			return err
+		}
+		for _, vendor := range c.Vendors {
+			if _, ok := crit.BaseScores[vendor.ID]; !ok {
+				return fmt.Errorf("%s: нет балла для поставщика %q", crit.Name, vendor.ID)
+			}
+		}
+	}
+
+	if c.PriceSheet != nil {
+		if err := c.PriceSheet.validate(c.Options); err != nil {
+			return fmt.Errorf("прайс-лист: %w", err)
+		}
+	}
+
+	if err := validateFollowUps(c.FollowUps, names, ids); err != nil {
+		return err
+	}
+
+	scale := priorityScale
+	if c.PriorityScale != nil {
+		scale = *c.PriorityScale
+	}
+	for _, rule := range c.VetoRules {
+		if rule.MinPriority != 0 && !scaleContains(scale, rule.MinPriority) {
+			return fmt.Errorf("правило %s: min_priority %d вне шкалы приоритетов [%d, %d]",
+				rule.ID, rule.MinPriority, scale.Min, scale.Max)
+		}
+		if lang, ok := unsupportedLang(rule.Translations); ok {
+			return fmt.Errorf("правило %s: неподдерживаемый язык перевода %q", rule.ID, lang)
+		}
+		if !names[rule.Criterion] {
+			return fmt.Errorf("правило %s: неизвестный критерий %q", rule.ID, rule.Criterion)
+		}
+		for _, id := range rule.Exclude {
+			if !ids[id] {
+				return fmt.Errorf("правило %s: неизвестный вариант развертывания %q", rule.ID, id)
+			}
+		}
+	}
+
+	return nil
+}
+
+// unsupportedLang находит в переводах язык, который не поддерживается интерфейсом.
+func unsupportedLang[T any](translations map[Lang]T) (Lang, bool) {
+	for lang := range translations {
+		if parsed, ok := parseLang(string(lang)); !ok || parsed != lang {
+			return lang, true
+		}
+	}
+	return "", false
+}
+
+// text возвращает тексты критерия на языке lang.
+func (c Criterion) text(lang Lang) CriterionText {
+	t := c.Translations[lang]
+	return CriterionText{
+		Name:          firstNonEmpty(t.Name, c.Name),
+		Category:      firstNonEmpty(t.Category, c.Category),
+		Description:   firstNonEmpty(t.Description, c.Description),
+		SpecialPrompt: firstNonEmpty(t.SpecialPrompt, c.SpecialPrompt),
+	}
+}
+
+// hasSpecialValue проверяет, что значение входит в значения специального критерия (без учета регистра).
+func (c Criterion) hasSpecialValue(value string) bool {
+	for _, option := range c.SpecialOptions {
+		if strings.EqualFold(option.Value, value) {
+			return true
+		}
+	}
+	return false
+}
+
+func (c Criterion) specialValues() []string {
+	values := make([]string, 0, len(c.SpecialOptions))
+	for _, option := range c.SpecialOptions {
+		values = append(values, option.Value)
+	}
+	return values
+}
+
+func (o SpecialOption) text(lang Lang) SpecialOptionText {
+	t := o.Translations[lang]
+	return SpecialOptionText{
+		Value:       firstNonEmpty(t.Value, o.Value),
+		Description: firstNonEmpty(t.Description, o.Description),
+	}
+}
+
+// criterionLabel возвращает название критерия (первого или второго этапа) на языке lang.
+func criterionLabel(lang Lang, name string) string {
+	for _, list := range [][]Criterion{defaultCriteria, vendorCriteria} {
+		for _, crit := range list {
+			if crit.Name == name {
+				return crit.text(lang).Name
+			}
+		}
+	}
+	return name
+}
+
+// specialValueLabel возвращает значение специального критерия на языке lang.
+func specialValueLabel(lang Lang, criterion, value string) string {
+	for _, option := range findCriterionByName(criterion).SpecialOptions {
+		if strings.EqualFold(option.Value, value) {
+			return option.text(lang).Value
+		}
+	}
+	return value
+}
+
+func firstNonEmpty(values ...string) string {
+	for _, v := range values {
+		if v != "" {
+			return v
+		}
+	}
+	return ""
+}
+
+func findDeploymentOption(id string) (DeploymentOption, bool) {
+	for _, option := range deploymentOptions {
+		if option.ID == id {
+			return option, true
+		}
+	}
+	return DeploymentOption{}, false
+}
+
+func deploymentOptionIDs() []string {
+	ids := make([]string, 0, len(deploymentOptions))
+	for _, option := range deploymentOptions {
+		ids = append(ids, option.ID)
+	}
+	return ids
+}
+
+func cloneScores(s Scores) Scores {
+	c := make(Scores, len(s))
+	for id, v := range s {
+		c[id] = v
+	}
+	return c
+}
+
+// mergeScores возвращает копию баллов, в которой заданные в override варианты заменены.
+// Варианты, отсутствующие в override (например, добавленные в каталог позже), сохраняют прежний балл.
+func mergeScores(s, override Scores) Scores {
+	c := cloneScores(s)
+	for id, v := range override {
+		c[id] = v
+	}
+	return c
+}
+
+// scoreVector раскладывает баллы в срез в порядке deploymentOptions.
+func scoreVector(s Scores) []float64 {
+	values := make([]float64, len(deploymentOptions))
+	for i, option := range deploymentOptions {
+		values[i] = s[option.ID]
+	}
+	return values
+}
+
+func scoresFromVector(values []float64) Scores {
+	s := make(Scores, len(values))
+	for i, v := range values {
+		s[deploymentOptions[i].ID] = v
+	}
+	return s
+}
+
+// formatScores выводит баллы в виде "OnPrem=8, Private=5, Public=4".
+func formatScores(s Scores) string {
+	parts := make([]string, 0, len(deploymentOptions))
+	for _, option := range deploymentOptions {
+		parts = append(parts, fmt.Sprintf("%s=%s", option.ShortName, formatNumber(s[option.ID])))
+	}
+	return strings.Join(parts, ", ")
+}
+
+// formatNumber выводит число с точностью до сотых без лишних нулей: 7, 7.5, 7.25.
+func formatNumber(v float64) string {
+	return strconv.FormatFloat(math.Round(v*100)/100, 'f', -1, 64)
+}
+
+func deploymentOptionNames() string {
+	names := make([]string, 0, len(deploymentOptions))
+	for _, option := range deploymentOptions {
+		names = append(names, option.Name)
+	}
+	return strings.Join(names, ", ")
+}
+
+// priorityScaleNote дополняет промпт LLM, если шкала приоритетов отличается от 1..5.
+func priorityScaleNote() string {
+	if priorityScale == (PriorityScale{Min: 1, Max: 5}) {
+		return ""
+	}
+	return fmt.Sprintf("\nВНИМАНИЕ: приоритеты в текущей конфигурации задаются по шкале от %d (низкий) до %d (высокий).\n",
+		priorityScale.Min, priorityScale.Max)
+}
+
+// catalogOptionsNote дополняет промпт LLM, если каталог отличается от трех стандартных вариантов.
+func catalogOptionsNote() string {
+	defaults := getDefaultDeploymentOptions()
+	same := len(defaults) == len(deploymentOptions)
+	for i := 0; same && i < len(defaults); i++ {
+		same = defaults[i].Name == deploymentOptions[i].Name
+	}
+	if same {
+		return ""
+	}
+
+	return fmt.Sprintf("\nВНИМАНИЕ: в текущей конфигурации доступны варианты развертывания: %s. Рекомендуй строго один из них.\n",
+		deploymentOptionNames())
+}
+
+// hideCatalogScores скрывает баллы критериев в /api/criteria (переменная CATALOG_HIDE_SCORES).
+var hideCatalogScores bool
+
+// catalogVersion — хэш активного каталога: меняется при любом изменении критериев,
+// вариантов, баллов, правил или переводов.
+func catalogVersion() string {
+	data, err := json.Marshal(Catalog{
+		PriorityScale:  &priorityScale,
+		Options:        deploymentOptions,
+		Criteria:       defaultCriteria,
+		VetoRules:      vetoRules,
+		Vendors:        vendors,
+		VendorCriteria: vendorCriteria,
+		PriceSheet:     priceSheet,
+		FollowUps:      followUps,
+	})
+	if err != nil {
+		logger.Printf("Ошибка сериализации каталога: %v", err)
+	}
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:8])
+}
+
+func catalogCriteria(lang Lang, list []Criterion) []CatalogCriterion {
+	result := make([]CatalogCriterion, 0, len(list))
+	for _, crit := range list {
+		text := crit.text(lang)
+		item := CatalogCriterion{
+			ID:            crit.ID,
+			Name:          text.Name,
+			Category:      text.Category,
+			Description:   text.Description,
+			IsSpecial:     crit.IsSpecial,
+			SpecialPrompt: text.SpecialPrompt,
+		}
+		if !hideCatalogScores && !crit.IsSpecial {
+			item.BaseScores = crit.BaseScores
+		}
+		for _, option := range crit.SpecialOptions {
+			optionText := option.text(lang)
+			special := CatalogSpecialOption{Value: option.Value, Label: optionText.Value, Description: optionText.Description}
+			if !hideCatalogScores {
+				special.Scores = option.Scores
+			}
+			item.SpecialOptions = append(item.SpecialOptions, special)
+		}
+		result = append(result, item)
+	}
+	return result
+}
+
+// etagMatches проверяет заголовок If-None-Match (список тегов или "*").
+func etagMatches(header, etag string) bool {
+	for _, tag := range strings.Split(header, ",") {
+		tag = strings.TrimPrefix(strings.TrimSpace(tag), "W/")
+		if tag == "*" || tag == etag {
+			return true
+		}
+	}
+	return false
+}
+
+func criteriaHandler(w http.ResponseWriter, r *http.Request) {
+	lang := requestLang(r)
+	w.Header().Set("Content-Language", string(lang))
+
+	if r.Method != "GET" {
+		http.Error(w, tr(lang, "Метод не поддерживается"), http.StatusMethodNotAllowed)
+		return
+	}
+
+	// Ответ зависит от версии каталога, языка и того, скрыты ли баллы.
+	version := catalogVersion()
+	etag := fmt.Sprintf("%q", fmt.Sprintf("%s-%s-%t", version, lang, hideCatalogScores))
+	w.Header().Set("ETag", etag)
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Add("Vary", "Accept-Language")
+
+	if etagMatches(r.Header.Get("If-None-Match"), etag) {
+		w.WriteHeader(http.StatusNotModified)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, CatalogResponse{
+		Version:        version,
+		Language:       lang,
+		PriorityScale:  priorityScale,
+		Options:        deploymentOptions,
+		Criteria:       catalogCriteria(lang, defaultCriteria),
+		VendorCriteria: catalogCriteria(lang, vendorCriteria),
+	})
+}
@@ -0,0 +1,47 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestCatalogValidateVetoRulePriorityScale(t *testing.T) {
+	base := func(scale *PriorityScale, minPriority int) Catalog {
+		rules := getDefaultVetoRules()
+		rules[0].MinPriority = minPriority
+		return Catalog{
+			PriorityScale:  scale,
+			Options:        deploymentOptions,
+			Criteria:       defaultCriteria,
+			VetoRules:      rules,
+			Vendors:        vendors,
+			VendorCriteria: vendorCriteria,
+			PriceSheet:     priceSheet,
+			FollowUps:      followUps,
+		}
+	}
+
+	tests := []struct {
+		name        string
+		scale       *PriorityScale
+		minPriority int
+		wantErr     bool
+	}{
+		{"встроенное правило", nil, priorityScale.Max, false},
+		{"выше шкалы по умолчанию", nil, priorityScale.Max + 1, true},
+		{"в пределах шкалы каталога", &PriorityScale{Min: 1, Max: 10}, 9, false},
+		{"выше шкалы каталога", &PriorityScale{Min: 1, Max: 3}, 5, true},
+		{"ниже шкалы каталога", &PriorityScale{Min: 2, Max: 7}, 1, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := base(tt.scale, tt.minPriority).validate()
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("validate() = %v, ожидалась ошибка: %t", err, tt.wantErr)
+			}
+			if err != nil && !strings.Contains(err.Error(), "min_priority") {
+				t.Errorf("ошибка не про min_priority: %v", err)
+			}
+		})
+	}
+}
@@ -0,0 +1,227 @@
+// Package client — Go-клиент HTTP API сервиса выбора варианта развертывания СУБД.
+// Запросы и ответы — типы пакета api, те же, что использует сервер.
+package client
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"tg-bot-checklist/api"
+)
+
+// Client вызывает методы API версии api.BasePath по адресу BaseURL, например "http://localhost:8080".
+// Language передается в Accept-Language; пустое значение — язык сервера по умолчанию.
+// APIKey передается в заголовке Authorization; без ключа доступны только каталог и спецификация.
+type Client struct {
+	BaseURL    string
+	Language   string
+	APIKey     string
+	HTTPClient *http.Client
+}
+
+// New создает клиент со стандартным http.Client.
+func New(baseURL string) *Client {
+	return &Client{BaseURL: strings.TrimRight(baseURL, "/"), HTTPClient: http.DefaultClient}
+}
+
+// Error — ответ API с кодом ошибки. Для 400 с ошибками полей заполнено Validation,
+// иначе Message содержит текст ответа.
+type Error struct {
+	StatusCode int
+	Message    string
+	Validation *api.ValidationError
+}
+
+func (e *Error) Error() string {
+	if e.Validation != nil {
+		parts := make([]string, 0, len(e.Validation.Errors))
+		for _, fe := range e.Validation.Errors {
+			if fe.Path == "" {
+				parts = append(parts, fe.Message)
+			} else {
+				parts = append(parts, fe.Path+": "+fe.Message)
+			}
+		}
+		return fmt.Sprintf("%d %s: %s", e.StatusCode, e.Validation.Error, strings.Join(parts, "; "))
+	}
+	return fmt.Sprintf("%d %s", e.StatusCode, e.Message)
+}
+
+// Recommend рассчитывает рекомендацию (POST /api/v1/recommend).
+func (c *Client) Recommend(ctx context.Context, req api.RecommendationRequest) (*api.RecommendationResponse, error) {
+	var resp api.RecommendationResponse
+	if err := c.do(ctx, "POST", "/recommend", req, &resp); err != nil {
+		return nil, err
+	}
+	return &resp, nil
+}
+
+// Compare сравнивает два результата (POST /api/v1/compare).
+func (c *Client) Compare(ctx context.Context, req api.CompareRequest) (*api.CompareResponse, error) {
+	var resp api.CompareResponse
+	if err := c.do(ctx, "POST", "/compare", req, &resp); err != nil {
+		return nil, err
+	}
+	return &resp, nil
+}
+
+// Criteria возвращает активный каталог критериев (GET /api/v1/criteria).
+func (c *Client) Criteria(ctx context.Context) (*api.CatalogResponse, error) {
+	var resp api.CatalogResponse
+	if err := c.do(ctx, "GET", "/criteria", nil, &resp); err != nil {
+		return nil, err
+	}
+	return &resp, nil
+}
+
+// Profiles возвращает профили ключа APIKey (GET /api/v1/profiles).
+func (c *Client) Profiles(ctx context.Context) ([]api.Profile, error) {
+	var resp []api.Profile
+	if err := c.do(ctx, "GET", "/profiles", nil, &resp); err != nil {
+		return nil, err
+	}
+	return resp, nil
+}
+
+// Profile возвращает профиль ключа APIKey по названию (GET /api/v1/profiles?name=...).
+func (c *Client) Profile(ctx context.Context, name string) (*api.Profile, error) {
+	var resp api.Profile
+	query := url.Values{"name": {name}}
+	if err := c.do(ctx, "GET", "/profiles?"+query.Encode(), nil, &resp); err != nil {
+		return nil, err
+	}
+	return &resp, nil
+}
+
+// SaveProfile сохраняет профиль (POST /api/v1/profiles).
+func (c *Client) SaveProfile(ctx context.Context, req api.ProfileRequest) (*api.Profile, error) {
+	var resp api.Profile
+	if err := c.do(ctx, "POST", "/profiles", req, &resp); err != nil {
+		return nil, err
+	}
+	return &resp, nil
+}
+
+// DeleteProfile удаляет профиль (DELETE /api/v1/profiles).
+func (c *Client) DeleteProfile(ctx context.Context, profileID int64) error {
+	query := url.Values{"id": {strconv.FormatInt(profileID, 10)}}
+	return c.do(ctx, "DELETE", "/profiles?"+query.Encode(), nil, nil)
+}
+
+// StartWizard начинает пошаговый чеклист (POST /api/v1/wizard/sessions). Пустой handoffToken —
+// новый чеклист, иначе продолжается чеклист пользователя Telegram, получившего код командой /web.
+func (c *Client) StartWizard(ctx context.Context, handoffToken string) (*api.WizardView, error) {
+	return c.wizard(ctx, "POST", "/wizard/sessions", api.WizardSessionRequest{HandoffToken: handoffToken})
+}
+
+// Wizard возвращает текущий шаг сессии.
+func (c *Client) Wizard(ctx context.Context, sessionID string) (*api.WizardView, error) {
+	return c.wizard(ctx, "GET", wizardPath(sessionID, ""), nil)
+}
+
+// AnswerWizard отвечает на текущий вопрос кнопкой (Action) или текстом (Text).
+func (c *Client) AnswerWizard(ctx context.Context, sessionID string, answer api.WizardAnswerRequest) (*api.WizardView, error) {
+	return c.wizard(ctx, "POST", wizardPath(sessionID, "answer"), answer)
+}
+
+// WizardBack возвращает сессию к предыдущему вопросу.
+func (c *Client) WizardBack(ctx context.Context, sessionID string) (*api.WizardView, error) {
+	return c.wizard(ctx, "POST", wizardPath(sessionID, "back"), nil)
+}
+
+// FinalizeWizard рассчитывает результат чеклиста; результат — в Result шага.
+func (c *Client) FinalizeWizard(ctx context.Context, sessionID string) (*api.WizardView, error) {
+	return c.wizard(ctx, "POST", wizardPath(sessionID, "finalize"), nil)
+}
+
+// DeleteWizard завершает сессию.
+func (c *Client) DeleteWizard(ctx context.Context, sessionID string) error {
+	return c.do(ctx, "DELETE", wizardPath(sessionID, ""), nil, nil)
+}
+
+// OpenAPI возвращает спецификацию API (GET /api/v1/openapi.json).
+func (c *Client) OpenAPI(ctx context.Context) (map[string]interface{}, error) {
+	var resp map[string]interface{}
+	if err := c.do(ctx, "GET", "/openapi.json", nil, &resp); err != nil {
+		return nil, err
+	}
+	return resp, nil
+}
+
+func wizardPath(sessionID, action string) string {
+	path := "/wizard/sessions/" + url.PathEscape(sessionID)
+	if action != "" {
+		path += "/" + action
+	}
+	return path
+}
+
+func (c *Client) wizard(ctx context.Context, method, path string, body interface{}) (*api.WizardView, error) {
+	var resp api.WizardView
+	if err := c.do(ctx, method, path, body, &resp); err != nil {
+		return nil, err
+	}
+	return &resp, nil
+}
+
+// do отправляет запрос с телом body в JSON и разбирает ответ 2xx в out; out = nil — ответ без тела.
+func (c *Client) do(ctx context.Context, method, path string, body, out interface{}) error {
+	var reader io.Reader
+	if body != nil {
+		data, err := json.Marshal(body)
+		if err != nil {
+			return err
+		}
+		reader = bytes.NewReader(data)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, c.BaseURL+api.BasePath+path, reader)
+	if err != nil {
+		return err
+	}
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	if c.Language != "" {
+		req.Header.Set("Accept-Language", c.Language)
+	}
+	if c.APIKey != "" {
+		req.Header.Set("Authorization", "Bearer "+c.APIKey)
+	}
+
+	httpClient := c.HTTPClient
+	if httpClient == nil {
+		httpClient = http.DefaultClient
+	}
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return err
+	}
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		apiErr := &Error{StatusCode: resp.StatusCode, Message: strings.TrimSpace(string(data))}
+		if strings.HasPrefix(resp.Header.Get("Content-Type"), "application/json") {
+			var validation api.ValidationError
+			if json.Unmarshal(data, &validation) == nil && validation.Error != "" {
+				apiErr.Validation = &validation
+			}
+		}
+		return apiErr
+	}
+	if out == nil || len(data) == 0 {
+		return nil
+	}
+	return json.Unmarshal(data, out)
+}
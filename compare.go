@@ -0,0 +1,275 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+const compareListSize = 10
+
+// scoresDelta считает b - a по каждому варианту каталога.
+func scoresDelta(a, b Scores) Scores {
+	delta := make(Scores, len(deploymentOptions))
+	for _, option := range deploymentOptions {
+		delta[option.ID] = b[option.ID] - a[option.ID]
+	}
+	return delta
+}
+
+func compareRecommendations(a, b *RecommendationResponse) *CompareResponse {
+	detailsA := make(map[string]CriterionDetail, len(a.Details))
+	for _, detail := range a.Details {
+		detailsA[detail.Name] = detail
+	}
+	detailsB := make(map[string]CriterionDetail, len(b.Details))
+	for _, detail := range b.Details {
+		detailsB[detail.Name] = detail
+	}
+
+	var diffs []CriterionDiff
+	for _, crit := range defaultCriteria {
+		detailA, inA := detailsA[crit.Name]
+		detailB, inB := detailsB[crit.Name]
+		if !inA && !inB {
+			continue
+		}
+
+		diff := CriterionDiff{
+			ID:            crit.ID,
+			Name:          crit.Name,
+			InA:           inA,
+			InB:           inB,
+			PriorityA:     detailA.Priority,
+			PriorityB:     detailB.Priority,
+			WeightedDelta: scoresDelta(detailA.Weighted, detailB.Weighted),
+		}
+		if diffChanged(diff) {
+			diffs = append(diffs, diff)
+		}
+	}
+
+	return &CompareResponse{
+		A:                     a,
+		B:                     b,
+		Criteria:              diffs,
+		TotalsDelta:           scoresDelta(a.Totals, b.Totals),
+		RecommendationChanged: !sameOutcome(a.Outcome, b.Outcome),
+	}
+}
+
+func diffChanged(d CriterionDiff) bool {
+	if d.InA != d.InB || d.PriorityA != d.PriorityB {
+		return true
+	}
+	for _, delta := range d.WeightedDelta {
+		if delta != 0 {
+			return true
+		}
+	}
+	return false
+}
+
+func formatDelta(delta float64) string {
+	if delta > 0 {
+		return "+" + formatNumber(delta)
+	}
+	return formatNumber(delta)
+}
+
+func formatCompareMessage(lang Lang, cmp *CompareResponse, labelA, labelB string) string {
+	var text strings.Builder
+	text.WriteString(tr(lang, "Сравнение: A — %s, B — %s", labelA, labelB) + "\n\n")
+
+	text.WriteString(tr(lang, "Итоговые баллы (A → B):") + "\n")
+	for _, option := range deploymentOptions {
+		text.WriteString(fmt.Sprintf("%s: %s → %s (%s)\n",
+			option.Name, formatNumber(cmp.A.Totals[option.ID]), formatNumber(cmp.B.Totals[option.ID]), formatDelta(cmp.TotalsDelta[option.ID])))
+	}
+	text.WriteString("\n")
+
+	if cmp.RecommendationChanged {
+		text.WriteString(tr(lang, "Рекомендация изменилась: %s → %s",
+			recommendationLabel(lang, cmp.A.Outcome), recommendationLabel(lang, cmp.B.Outcome)) + "\n\n")
+	} else {
+		text.WriteString(tr(lang, "Рекомендация не изменилась: %s", recommendationLabel(lang, cmp.A.Outcome)) + "\n\n")
+	}
+
+	if len(cmp.Criteria) == 0 {
+		text.WriteString(tr(lang, "Различий по критериям нет."))
+		return text.String()
+	}
+
+	text.WriteString(tr(lang, "Различия по критериям:") + "\n")
+	for _, diff := range cmp.Criteria {
+		label := criterionLabel(lang, diff.Name)
+		switch {
+		case !diff.InA:
+			text.WriteString(tr(lang, "• %s: только в B (приоритет %d)", label, diff.PriorityB))
+		case !diff.InB:
+			text.WriteString(tr(lang, "• %s: только в A (приоритет %d)", label, diff.PriorityA))
+		case diff.PriorityA != diff.PriorityB:
+			text.WriteString(tr(lang, "• %s: приоритет %d → %d", label, diff.PriorityA, diff.PriorityB))
+		default:
+			text.WriteString(tr(lang, "• %s: изменены баллы", label))
+		}
+		var deltas []string
+		for _, option := range deploymentOptions {
+			deltas = append(deltas, fmt.Sprintf("%s %s", option.ShortName, formatDelta(diff.WeightedDelta[option.ID])))
+		}
+		text.WriteString("; " + strings.Join(deltas, ", ") + "\n")
+	}
+
+	return text.String()
+}
+
+func showCompareSelection(bot *tgbotapi.BotAPI, chatID int64) {
+	lang := userLang(chatID)
+	records, total, err := loadUserAnswers(chatID, compareListSize, 0)
+	if err != nil {
+		logger.Printf("Ошибка загрузки истории для сравнения chatID %d: %v", chatID, err)
+		sendMessage(bot, tgbotapi.NewMessage(chatID, tr(lang, "Не удалось загрузить историю.")))
+		return
+	}
+
+	if total < 2 {
+		sendMessage(bot, tgbotapi.NewMessage(chatID, tr(lang, "Для сравнения нужно хотя бы два пройденных чеклиста.")))
+		return
+	}
+
+	userStates[chatID].CompareAnswerID = 0
+
+	var keyboardRows [][]tgbotapi.InlineKeyboardButton
+	for _, record := range records {
+		buttonText := fmt.Sprintf("%s — %s", record.CreatedAt.Format("02.01.2006 15:04"), record.recommendationLabel(lang))
+		keyboardRows = append(keyboardRows, tgbotapi.NewInlineKeyboardRow(
+			tgbotapi.NewInlineKeyboardButtonData(buttonText, fmt.Sprintf("cmp_%d", record.ID)),
+		))
+	}
+
+	msg := tgbotapi.NewMessage(chatID, tr(lang, "Выберите два чеклиста для сравнения: сначала A, затем B."))
+	msg.ReplyMarkup = tgbotapi.NewInlineKeyboardMarkup(keyboardRows...)
+	sendMessage(bot, msg)
+}
+
+func handleCompareCallback(bot *tgbotapi.BotAPI, query *tgbotapi.CallbackQuery, chatID int64) {
+	answerID, err := strconv.ParseInt(strings.TrimPrefix(query.Data, "cmp_"), 10, 64)
+	if err != nil {
+		logger.Printf("Некорректный callback сравнения: %s", query.Data)
+		return
+	}
+
+	state := userStates[chatID]
+	if state == nil {
+		state = newUserState()
+		userStates[chatID] = state
+	}
+	lang := userLang(chatID)
+
+	if state.CompareAnswerID == 0 || state.CompareAnswerID == answerID {
+		state.CompareAnswerID = answerID
+		sendMessage(bot, tgbotapi.NewMessage(chatID, tr(lang, "Вариант A выбран. Теперь выберите вариант B.")))
+		return
+	}
+
+	recordA, errA := loadUserAnswer(chatID, state.CompareAnswerID)
+	recordB, errB := loadUserAnswer(chatID, answerID)
+	state.CompareAnswerID = 0
+	if errA != nil || errB != nil {
+		logger.Printf("Ошибка загрузки записей для сравнения chatID %d: %v, %v", chatID, errA, errB)
+		sendMessage(bot, tgbotapi.NewMessage(chatID, tr(lang, "Запись не найдена.")))
+		return
+	}
+
+	reqA, reqB := inputToRequest(recordA.UserInput), inputToRequest(recordB.UserInput)
+	reqA.Lang, reqB.Lang = lang, lang
+	cmp := compareRecommendations(evaluateRecommendation(reqA), evaluateRecommendation(reqB))
+
+	logger.LogTelegramAction("Сравнение чеклистов", map[string]interface{}{
+		"ChatID":                chatID,
+		"A":                     recordA.ID,
+		"B":                     recordB.ID,
+		"RecommendationChanged": cmp.RecommendationChanged,
+	})
+
+	sendMessage(bot, tgbotapi.NewMessage(chatID, formatCompareMessage(lang, cmp,
+		recordA.CreatedAt.Format("02.01.2006 15:04"),
+		recordB.CreatedAt.Format("02.01.2006 15:04"))))
+}
+
+func compareHandler(w http.ResponseWriter, r *http.Request) {
+	lang := requestLang(r)
+	w.Header().Set("Content-Language", string(lang))
+
+	if r.Method != "POST" {
+		http.Error(w, tr(lang, "Метод не поддерживается"), http.StatusMethodNotAllowed)
+		return
+	}
+
+	var req CompareRequest
+	if errs := decodeJSONBody(lang, r, &req); len(errs) > 0 {
+		writeValidationErrors(w, lang, errs)
+		return
+	}
+
+	var reqA, reqB RecommendationRequest
+	switch {
+	case len(req.AnswerIDs) > 0:
+		if len(req.AnswerIDs) != 2 {
+			http.Error(w, tr(lang, "Необходимо указать ровно два answer_ids"), http.StatusBadRequest)
+			return
+		}
+		// Сохраненные результаты сравниваются только свои: записанные с ключом запроса.
+		client := apiClientFrom(r)
+		if client == nil {
+			w.Header().Set("WWW-Authenticate", "Bearer")
+			http.Error(w, tr(lang, "Требуется ключ API: заголовок Authorization: Bearer <ключ>"), http.StatusUnauthorized)
+			return
+		}
+		if pool == nil {
+			http.Error(w, tr(lang, "База данных недоступна"), http.StatusServiceUnavailable)
+			return
+		}
+		recordA, err := loadAnswer(apiKeyOwner(client), req.AnswerIDs[0])
+		if err != nil {
+			http.Error(w, tr(lang, "Запись %d не найдена", req.AnswerIDs[0]), http.StatusNotFound)
+			return
+		}
+		recordB, err := loadAnswer(apiKeyOwner(client), req.AnswerIDs[1])
+		if err != nil {
+			http.Error(w, tr(lang, "Запись %d не найдена", req.AnswerIDs[1]), http.StatusNotFound)
+			return
+		}
+		reqA = inputToRequest(recordA.UserInput)
+		reqB = inputToRequest(recordB.UserInput)
+	case req.A != nil && req.B != nil:
+		reqA = *req.A
+		reqB = *req.B
+		if err := legacyCriterionNames(w, r, lang, func(m, vm *keyMapper) {
+			mapRequestCriteria(&reqA, m, vm)
+			mapRequestCriteria(&reqB, m, vm)
+		}); err != nil {
+			writeValidationErrors(w, lang, []FieldError{{Message: err.Error()}})
+			return
+		}
+		errs := append(validateRecommendationRequest(lang, "a", &reqA), validateRecommendationRequest(lang, "b", &reqB)...)
+		if len(errs) > 0 {
+			writeValidationErrors(w, lang, errs)
+			return
+		}
+	default:
+		http.Error(w, tr(lang, "Необходимо указать answer_ids или оба запроса a и b"), http.StatusBadRequest)
+		return
+	}
+
+	if len(reqA.SelectedCriteria) == 0 || len(reqB.SelectedCriteria) == 0 {
+		http.Error(w, tr(lang, "Необходимо выбрать хотя бы один критерий"), http.StatusBadRequest)
+		return
+	}
+
+	reqA.Lang, reqB.Lang = lang, lang
+	writeJSON(w, http.StatusOK, compareRecommendations(evaluateRecommendation(reqA), evaluateRecommendation(reqB)))
+}
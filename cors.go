@@ -0,0 +1,208 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// corsPolicy — правила CORS для HTTP API. Задаются переменными окружения CORS_*;
+// по умолчанию запросы с других доменов запрещены.
+type corsPolicy struct {
+	// origins — разрешенные Origin: точные ("https://app.example.com") и шаблоны
+	// поддоменов ("https://*.example.com"); "*" — любой Origin.
+	origins          []string
+	anyOrigin        bool
+	methods          []string
+	headers          []string
+	allowCredentials bool
+	maxAge           int
+}
+
+// corsExposedHeaders — заголовки ответа, которые API отдает клиентам в браузере.
+const corsExposedHeaders = "ETag, Deprecation, Sunset, Link, Retry-After, Warning"
+
+var cors = corsPolicy{
+	methods: []string{"GET", "POST", "DELETE"},
+	headers: []string{"Content-Type", "Authorization", "If-None-Match", "Accept-Language"},
+	maxAge:  600,
+}
+
+// loadCORSPolicy читает CORS_ALLOWED_ORIGINS, CORS_ALLOWED_METHODS, CORS_ALLOWED_HEADERS,
+// CORS_ALLOW_CREDENTIALS и CORS_MAX_AGE. Ошибка в настройке — ошибка запуска, а не
+// молча открытый или закрытый API.
+func loadCORSPolicy() (corsPolicy, error) {
+	policy := cors
+	if value := os.Getenv("CORS_ALLOWED_ORIGINS"); value != "" {
+		for _, origin := range splitList(value) {
+			if origin == "*" {
+				policy.anyOrigin = true
+				continue
+			}
+			if err := validateCORSOrigin(origin); err != nil {
+				return policy, fmt.Errorf("CORS_ALLOWED_ORIGINS: %w", err)
+			}
+			policy.origins = append(policy.origins, strings.ToLower(origin))
+		}
+	}
+	if value := os.Getenv("CORS_ALLOWED_METHODS"); value != "" {
+		policy.methods = nil
+		for _, method := range splitList(value) {
+			method = strings.ToUpper(method)
+			switch method {
+			case "GET", "POST", "DELETE", "PUT", "PATCH", "HEAD":
+				policy.methods = append(policy.methods, method)
+			default:
+				return policy, fmt.Errorf("CORS_ALLOWED_METHODS: неизвестный метод %q", method)
+			}
+		}
+	}
+	if value := os.Getenv("CORS_ALLOWED_HEADERS"); value != "" {
+		policy.headers = splitList(value)
+	}
+	if value := os.Getenv("CORS_ALLOW_CREDENTIALS"); value != "" {
+		allow, err := strconv.ParseBool(value)
+		if err != nil {
+			return policy, fmt.Errorf("CORS_ALLOW_CREDENTIALS: %w", err)
+		}
+		policy.allowCredentials = allow
+	}
+	if value := os.Getenv("CORS_MAX_AGE"); value != "" {
+		maxAge, err := strconv.Atoi(value)
+		if err != nil || maxAge < 0 {
+			return policy, fmt.Errorf("CORS_MAX_AGE: ожидается число секунд, получено %q", value)
+		}
+		policy.maxAge = maxAge
+	}
+	if policy.anyOrigin && policy.allowCredentials {
+		return policy, fmt.Errorf("CORS_ALLOW_CREDENTIALS=true нельзя сочетать с CORS_ALLOWED_ORIGINS=*: укажите домены явно")
+	}
+	return policy, nil
+}
+
+func splitList(value string) []string {
+	var items []string
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
+
+// validateCORSOrigin проверяет, что origin — схема и хост без пути, например https://app.example.com.
+func validateCORSOrigin(origin string) error {
+	u, err := url.Parse(strings.Replace(origin, "://*.", "://wildcard.", 1))
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" ||
+		u.Path != "" || u.RawQuery != "" || u.Fragment != "" || u.User != nil {
+		return fmt.Errorf("некорректный origin %q, ожидается схема и хост, например https://app.example.com", origin)
+	}
+	return nil
+}
+
+// allowsOrigin — Origin запроса разрешен политикой.
+func (p corsPolicy) allowsOrigin(origin string) bool {
+	if p.anyOrigin {
+		return true
+	}
+	origin = strings.ToLower(origin)
+	for _, allowed := range p.origins {
+		if allowed == origin {
+			return true
+		}
+		if scheme, domain, ok := strings.Cut(allowed, "://*."); ok &&
+			strings.HasPrefix(origin, scheme+"://") && strings.HasSuffix(origin, "."+domain) {
+			return true
+		}
+	}
+	return false
+}
+
+// sameOrigin — Origin указывает на сам сервер (запрос со страницы, которую он отдал).
+func sameOrigin(r *http.Request, origin string) bool {
+	u, err := url.Parse(origin)
+	return err == nil && strings.EqualFold(u.Host, r.Host)
+}
+
+func containsFold(list []string, value string) bool {
+	for _, item := range list {
+		if strings.EqualFold(item, value) {
+			return true
+		}
+	}
+	return false
+}
+
+// corsMiddleware применяет политику cors. Запросы без Origin (не из браузера) и с Origin
+// самого сервера проходят без заголовков CORS; запрос или preflight с неразрешенного
+// Origin отклоняется с 403 и записью в лог.
+func corsMiddleware(next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		origin := r.Header.Get("Origin")
+		preflight := r.Method == "OPTIONS" && r.Header.Get("Access-Control-Request-Method") != ""
+		if !cors.anyOrigin || cors.allowCredentials {
+			w.Header().Add("Vary", "Origin")
+		}
+		if preflight {
+			w.Header().Add("Vary", "Access-Control-Request-Method, Access-Control-Request-Headers")
+		}
+
+		if origin != "" && !sameOrigin(r, origin) {
+			if !cors.allowsOrigin(origin) {
+				rejectCORS(w, r, origin, "Origin не разрешен")
+				return
+			}
+			if cors.anyOrigin && !cors.allowCredentials {
+				w.Header().Set("Access-Control-Allow-Origin", "*")
+			} else {
+				w.Header().Set("Access-Control-Allow-Origin", origin)
+			}
+			if cors.allowCredentials {
+				w.Header().Set("Access-Control-Allow-Credentials", "true")
+			}
+			w.Header().Set("Access-Control-Expose-Headers", corsExposedHeaders)
+		}
+
+		if preflight {
+			method := r.Header.Get("Access-Control-Request-Method")
+			if !containsFold(cors.methods, method) {
+				rejectCORS(w, r, origin, "метод "+method+" не разрешен")
+				return
+			}
+			for _, header := range splitList(r.Header.Get("Access-Control-Request-Headers")) {
+				if !containsFold(cors.headers, header) {
+					rejectCORS(w, r, origin, "заголовок "+header+" не разрешен")
+					return
+				}
+			}
+			w.Header().Set("Access-Control-Allow-Methods", strings.Join(cors.methods, ", ")+", OPTIONS")
+			w.Header().Set("Access-Control-Allow-Headers", strings.Join(cors.headers, ", "))
+			w.Header().Set("Access-Control-Max-Age", strconv.Itoa(cors.maxAge))
+			w.WriteHeader(http.StatusNoContent)
+			return
+		}
+		if r.Method == "OPTIONS" {
+			w.WriteHeader(http.StatusNoContent)
+			return
+		}
+
+		next(w, r)
+	}
+}
+
+func rejectCORS(w http.ResponseWriter, r *http.Request, origin, reason string) {
+	logger.LogTelegramAction("Отклонен запрос CORS", map[string]interface{}{
+		"Origin":  origin,
+		"Метод":   r.Method,
+		"Путь":    r.URL.Path,
+		"Причина": reason,
+		"Адрес":   r.RemoteAddr,
+	})
+	w.Header().Del("Access-Control-Allow-Origin")
+	w.Header().Del("Access-Control-Allow-Credentials")
+	w.Header().Del("Access-Control-Expose-Headers")
+	http.Error(w, tr(requestLang(r), "Запрос с этого источника запрещен политикой CORS"), http.StatusForbidden)
+}
@@ -0,0 +1,392 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"os"
+	"strconv"
+	"strings"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+const maxCostHorizonYears = 10
+
+var costReportYears = []int{1, 3, 5}
+
+// OptionPrices — цены одного варианта развертывания. StaffShare — доля годовой стоимости
+// инженера, которая уходит на сопровождение СУБД при данном варианте.
+type OptionPrices struct {
+	UpfrontPerInstance float64 `json:"upfront_per_instance"`
+	MonthlyPerInstance float64 `json:"monthly_per_instance"`
+	MonthlyPerGB       float64 `json:"monthly_per_gb"`
+	StaffShare         float64 `json:"staff_share"`
+}
+
+// PriceSheet — прайс-лист для оценки TCO. UpfrontCriterion и RunningCriterion — критерии
+// каталога, баллы которых можно вычислить из стоимости.
+type PriceSheet struct {
+	Currency         string                  `json:"currency"`
+	AnnualStaffCost  float64                 `json:"annual_staff_cost"`
+	UpfrontCriterion string                  `json:"upfront_criterion"`
+	RunningCriterion string                  `json:"running_criterion"`
+	Options          map[string]OptionPrices `json:"options"`
+}
+
+var priceSheet = getDefaultPriceSheet()
+
+func getDefaultPriceSheet() *PriceSheet {
+	return &PriceSheet{
+		Currency:         "₽",
+		AnnualStaffCost:  3600000,
+		UpfrontCriterion: "Начальные инвестиции",
+		RunningCriterion: "Постоянные затраты",
+		Options: map[string]OptionPrices{
+			"on_prem": {UpfrontPerInstance: 1500000, MonthlyPerInstance: 25000, MonthlyPerGB: 5, StaffShare: 1},
+			"private": {UpfrontPerInstance: 400000, MonthlyPerInstance: 45000, MonthlyPerGB: 8, StaffShare: 0.8},
+			"public":  {UpfrontPerInstance: 0, MonthlyPerInstance: 60000, MonthlyPerGB: 12, StaffShare: 0.4},
+		},
+	}
+}
+
+// loadPriceSheetFile заменяет прайс-лист содержимым JSON-файла.
+func loadPriceSheetFile(path string) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+
+	var sheet PriceSheet
+	if err := json.Unmarshal(data, &sheet); err != nil {
+		return fmt.Errorf("ошибка парсинга JSON: %w", err)
+	}
+	if err := sheet.validate(deploymentOptions); err != nil {
+		return err
+	}
+
+	priceSheet = &sheet
+	return nil
+}
+
+func (p *PriceSheet) validate(options []DeploymentOption) error {
+	known := make(map[string]bool, len(options))
+	for _, option := range options {
+		known[option.ID] = true
+		if _, ok := p.Options[option.ID]; !ok {
+			return fmt.Errorf("нет цен для варианта %q", option.ID)
+		}
+	}
+	for id := range p.Options {
+		if !known[id] {
+			return fmt.Errorf("неизвестный вариант развертывания %q", id)
+		}
+	}
+	return nil
+}
+
+func validateCostInput(errs *fieldErrors, path string, input *CostInput) {
+	if priceSheet == nil {
+		errs.add(path, "прайс-лист не настроен")
+		return
+	}
+	if input.DataGB < 0 {
+		errs.add(fieldPath(path, "data_gb"), "data_gb не может быть отрицательным")
+	}
+	if input.Instances < 1 {
+		errs.add(fieldPath(path, "instances"), "instances должно быть не меньше 1")
+	}
+	if input.TeamSize < 0 {
+		errs.add(fieldPath(path, "team_size"), "team_size не может быть отрицательным")
+	}
+	if input.HorizonYears < 1 || input.HorizonYears > maxCostHorizonYears {
+		errs.add(fieldPath(path, "horizon_years"), "horizon_years должно быть от 1 до %d", maxCostHorizonYears)
+	}
+}
+
+// estimateCost считает TCO = разовые затраты + годовые затраты × число лет
+// для 1, 3, 5 лет и горизонта пользователя.
+func estimateCost(input CostInput) *CostEstimate {
+	estimate := &CostEstimate{Currency: priceSheet.Currency, HorizonYears: input.HorizonYears}
+
+	years := append([]int(nil), costReportYears...)
+	if !containsInt(years, input.HorizonYears) {
+		years = append(years, input.HorizonYears)
+	}
+
+	for _, option := range deploymentOptions {
+		prices := priceSheet.Options[option.ID]
+		cost := OptionCost{
+			Option:  option.ID,
+			Name:    option.Name,
+			Upfront: prices.UpfrontPerInstance * float64(input.Instances),
+			Annual: 12*(prices.MonthlyPerInstance*float64(input.Instances)+prices.MonthlyPerGB*float64(input.DataGB)) +
+				priceSheet.AnnualStaffCost*prices.StaffShare*float64(input.TeamSize),
+			TCO: make(map[int]float64, len(years)),
+		}
+		for _, y := range years {
+			cost.TCO[y] = cost.Upfront + cost.Annual*float64(y)
+		}
+		estimate.Options = append(estimate.Options, cost)
+	}
+
+	cheapest := estimate.Options[0]
+	for _, cost := range estimate.Options[1:] {
+		if cost.TCO[input.HorizonYears] < cheapest.TCO[input.HorizonYears] {
+			cheapest = cost
+		}
+	}
+	estimate.Cheapest = cheapest.Name
+
+	return estimate
+}
+
+// costScores переводит стоимость в баллы 1–10 с точностью до десятых: самый дешевый
+// вариант получает 10, самый дорогой — 1, остальные — пропорционально. При равной стоимости баллов нет.
+func costScores(costs []float64) Scores {
+	minCost, maxCost := costs[0], costs[0]
+	for _, c := range costs {
+		minCost = math.Min(minCost, c)
+		maxCost = math.Max(maxCost, c)
+	}
+	if maxCost == minCost {
+		return nil
+	}
+
+	values := make([]float64, len(costs))
+	for i, c := range costs {
+		score := minScore + (maxScore-minScore)*(maxCost-c)/(maxCost-minCost)
+		values[i] = math.Round(score*10) / 10
+	}
+	return scoresFromVector(values)
+}
+
+// economicScores возвращает баллы экономических критериев, рассчитанные из оценки стоимости.
+func economicScores(estimate *CostEstimate) map[string]Scores {
+	upfront := make([]float64, len(estimate.Options))
+	annual := make([]float64, len(estimate.Options))
+	for i, cost := range estimate.Options {
+		upfront[i] = cost.Upfront
+		annual[i] = cost.Annual
+	}
+
+	result := make(map[string]Scores)
+	if scores := costScores(upfront); scores != nil && priceSheet.UpfrontCriterion != "" {
+		result[priceSheet.UpfrontCriterion] = scores
+	}
+	if scores := costScores(annual); scores != nil && priceSheet.RunningCriterion != "" {
+		result[priceSheet.RunningCriterion] = scores
+	}
+	return result
+}
+
+func containsInt(arr []int, val int) bool {
+	for _, v := range arr {
+		if v == val {
+			return true
+		}
+	}
+	return false
+}
+
+func formatMoney(amount float64) string {
+	digits := strconv.FormatInt(int64(math.Round(amount)), 10)
+
+	var text strings.Builder
+	for i, r := range digits {
+		if i > 0 && (len(digits)-i)%3 == 0 {
+			text.WriteString(" ")
+		}
+		text.WriteRune(r)
+	}
+	return text.String()
+}
+
+func formatCostMessage(lang Lang, estimate *CostEstimate) string {
+	var text strings.Builder
+	text.WriteString(tr(lang, "Оценка стоимости владения (TCO):") + "\n")
+
+	years := append([]int(nil), costReportYears...)
+	if !containsInt(years, estimate.HorizonYears) {
+		years = append(years, estimate.HorizonYears)
+	}
+
+	for _, cost := range estimate.Options {
+		text.WriteString("\n" + tr(lang, "%s: разово %s %s, в год %s %s",
+			cost.Name, formatMoney(cost.Upfront), estimate.Currency, formatMoney(cost.Annual), estimate.Currency) + "\n")
+		for _, y := range years {
+			text.WriteString(fmt.Sprintf("  %s: %s %s\n", formatYears(lang, y), formatMoney(cost.TCO[y]), estimate.Currency))
+		}
+	}
+
+	text.WriteString("\n" + tr(lang, "Дешевле всего на горизонте %s: %s", formatYears(lang, estimate.HorizonYears), estimate.Cheapest))
+	return text.String()
+}
+
+var (
+	costDataSizes = []int{50, 500, 2000, 10000}
+	costInstances = []int{1, 2, 3, 5}
+	costTeamSizes = []int{0, 1, 2, 5}
+	costHorizons  = []int{1, 3, 5}
+)
+
+func formatDataSize(lang Lang, gb int) string {
+	if gb >= 1000 {
+		return tr(lang, "%d ТБ", gb/1000)
+	}
+	return tr(lang, "%d ГБ", gb)
+}
+
+func formatYears(lang Lang, years int) string {
+	return tr(lang, "%d г.", years)
+}
+
+// askCostEstimate предлагает необязательный шаг оценки стоимости перед переопределением баллов.
+func askCostEstimate(bot *tgbotapi.BotAPI, chatID int64) {
+	state := userStates[chatID]
+	if priceSheet == nil {
+		transition(bot, chatID, stateOverrideAsk)
+		return
+	}
+
+	state.Cost = nil
+	state.CostMessageID = 0
+
+	lang := userLang(chatID)
+	msg := tgbotapi.NewMessage(chatID, tr(lang, "Оценить стоимость владения (TCO) для каждого варианта развертывания?"))
+	msg.ReplyMarkup = tgbotapi.NewInlineKeyboardMarkup(
+		tgbotapi.NewInlineKeyboardRow(
+			tgbotapi.NewInlineKeyboardButtonData(tr(lang, "Да"), "cost_yes"),
+			tgbotapi.NewInlineKeyboardButtonData(tr(lang, "Нет"), "cost_no"),
+		),
+	)
+	logger.LogTelegramAction("Запрос оценки стоимости", nil)
+	sendMessage(bot, msg)
+}
+
+func askCostParameter(bot *tgbotapi.BotAPI, chatID int64) {
+	state := userStates[chatID]
+	lang := userLang(chatID)
+
+	var text, prefix string
+	var values []int
+	label := strconv.Itoa
+
+	switch {
+	case state.Cost.DataGB == 0:
+		text, prefix, values = tr(lang, "Какой объём данных планируется хранить?"), "cost_data_", costDataSizes
+		label = func(v int) string { return formatDataSize(lang, v) }
+	case state.Cost.Instances == 0:
+		text, prefix, values = tr(lang, "Сколько экземпляров СУБД (с репликами) потребуется?"), "cost_inst_", costInstances
+	case state.Cost.TeamSize < 0:
+		text, prefix, values = tr(lang, "Сколько инженеров будет сопровождать СУБД?"), "cost_team_", costTeamSizes
+	case state.Cost.HorizonYears == 0:
+		text, prefix, values = tr(lang, "На какой срок считать стоимость (лет)?"), "cost_hor_", costHorizons
+		label = func(v int) string { return formatYears(lang, v) }
+	default:
+		finishCostEstimate(bot, chatID)
+		return
+	}
+
+	var row []tgbotapi.InlineKeyboardButton
+	for i, v := range values {
+		row = append(row, tgbotapi.NewInlineKeyboardButtonData(label(v), fmt.Sprintf("%s%d", prefix, i)))
+	}
+	keyboard := tgbotapi.NewInlineKeyboardMarkup(row)
+
+	if state.CostMessageID != 0 {
+		editMsg := tgbotapi.NewEditMessageTextAndMarkup(chatID, state.CostMessageID, text, keyboard)
+		editMessageText(bot, editMsg)
+		return
+	}
+
+	msg := tgbotapi.NewMessage(chatID, text)
+	msg.ReplyMarkup = keyboard
+	sendTrackedMessage(bot, msg, &state.CostMessageID)
+}
+
+// finishCostEstimate показывает оценку и, если выбраны экономические критерии,
+// предлагает заменить их баллы рассчитанными из стоимости.
+func finishCostEstimate(bot *tgbotapi.BotAPI, chatID int64) {
+	state := userStates[chatID]
+	estimate := estimateCost(*state.Cost)
+
+	logger.LogTelegramAction("Оценка стоимости", map[string]interface{}{
+		"ChatID":    chatID,
+		"Параметры": state.Cost,
+		"Дешевле":   estimate.Cheapest,
+	})
+
+	lang := userLang(chatID)
+	sendMessage(bot, tgbotapi.NewMessage(chatID, formatCostMessage(lang, estimate)))
+
+	if contains(state.SelectedCriteria, priceSheet.UpfrontCriterion) || contains(state.SelectedCriteria, priceSheet.RunningCriterion) {
+		msg := tgbotapi.NewMessage(chatID, tr(lang, "Использовать рассчитанную стоимость для баллов экономических критериев?"))
+		msg.ReplyMarkup = tgbotapi.NewInlineKeyboardMarkup(
+			tgbotapi.NewInlineKeyboardRow(
+				tgbotapi.NewInlineKeyboardButtonData(tr(lang, "Да"), "cost_apply_yes"),
+				tgbotapi.NewInlineKeyboardButtonData(tr(lang, "Нет"), "cost_apply_no"),
+			),
+		)
+		sendMessage(bot, msg)
+		return
+	}
+
+	transition(bot, chatID, stateOverrideAsk)
+}
+
+func handleCostCallback(bot *tgbotapi.BotAPI, chatID int64, callbackData string) {
+	state := userStates[chatID]
+
+	switch callbackData {
+	case "cost_yes":
+		state.Cost = &CostInput{TeamSize: -1}
+		askCostParameter(bot, chatID)
+		return
+	case "cost_no":
+		state.Cost = nil
+		transition(bot, chatID, stateOverrideAsk)
+		return
+	case "cost_apply_yes", "cost_apply_no":
+		if state.Cost != nil {
+			state.Cost.ApplyToScores = callbackData == "cost_apply_yes"
+		}
+		transition(bot, chatID, stateOverrideAsk)
+		return
+	}
+
+	if state.Cost == nil {
+		return
+	}
+
+	idx := strings.LastIndex(callbackData, "_")
+	index, err := strconv.Atoi(callbackData[idx+1:])
+	if err != nil {
+		return
+	}
+
+	pick := func(values []int) (int, bool) {
+		if index < 0 || index >= len(values) {
+			return 0, false
+		}
+		return values[index], true
+	}
+
+	var ok bool
+	switch callbackData[:idx+1] {
+	case "cost_data_":
+		state.Cost.DataGB, ok = pick(costDataSizes)
+	case "cost_inst_":
+		state.Cost.Instances, ok = pick(costInstances)
+	case "cost_team_":
+		state.Cost.TeamSize, ok = pick(costTeamSizes)
+	case "cost_hor_":
+		state.Cost.HorizonYears, ok = pick(costHorizons)
+	}
+	if !ok {
+		logger.Printf("Некорректный callback оценки стоимости: %s", callbackData)
+		return
+	}
+
+	askCostParameter(bot, chatID)
+}
@@ -0,0 +1,211 @@
+package main
+
+import (
+	"errors"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// criterionIDPattern — формат идентификатора критерия: стабильный ASCII-ключ для API.
+// Имена критериев используются только для отображения.
+var criterionIDPattern = regexp.MustCompile(`^[a-z][a-z0-9_]*$`)
+
+// criterionID возвращает идентификатор критерия первого или второго этапа по его имени.
+func criterionID(name string) string {
+	for _, list := range [][]Criterion{defaultCriteria, vendorCriteria} {
+		for _, crit := range list {
+			if crit.Name == name {
+				return crit.ID
+			}
+		}
+	}
+	return ""
+}
+
+func criterionIDs(list []Criterion) []string {
+	ids := make([]string, 0, len(list))
+	for _, crit := range list {
+		ids = append(ids, crit.ID)
+	}
+	return ids
+}
+
+// keyMapper переводит ключи критериев между идентификаторами API и внутренними именами
+// (по именам критерии хранятся в состоянии бота, истории и профилях) и копит
+// нераспознанные и повторяющиеся ключи.
+type keyMapper struct {
+	convert   func(key string) (string, bool)
+	unknown   []string
+	duplicate []string
+}
+
+// lookupCriterion находит критерий списка по идентификатору. Названия критериев в API
+// не принимаются: они переводятся и могут меняться в каталоге.
+func lookupCriterion(list []Criterion, key string) (Criterion, bool) {
+	for _, crit := range list {
+		if crit.ID == key {
+			return crit, true
+		}
+	}
+	return Criterion{}, false
+}
+
+func idToName(list []Criterion) *keyMapper {
+	return &keyMapper{convert: func(key string) (string, bool) {
+		crit, ok := lookupCriterion(list, key)
+		return crit.Name, ok
+	}}
+}
+
+// nameToID — обратное преобразование для ответов API; неизвестные имена остаются как есть.
+func nameToID() *keyMapper {
+	return &keyMapper{convert: func(key string) (string, bool) {
+		if id := criterionID(key); id != "" {
+			return id, true
+		}
+		return key, true
+	}}
+}
+
+func (m *keyMapper) key(key string) string {
+	converted, ok := m.convert(key)
+	if !ok {
+		m.unknown = append(m.unknown, key)
+		return key
+	}
+	return converted
+}
+
+func (m *keyMapper) list(keys []string) []string {
+	if keys == nil {
+		return nil
+	}
+	result := make([]string, len(keys))
+	seen := make(map[string]bool, len(keys))
+	for i, key := range keys {
+		result[i] = m.key(key)
+		if seen[result[i]] {
+			m.duplicate = append(m.duplicate, key)
+		}
+		seen[result[i]] = true
+	}
+	return result
+}
+
+func mapKeys[V any](m *keyMapper, values map[string]V) map[string]V {
+	if values == nil {
+		return nil
+	}
+	result := make(map[string]V, len(values))
+	for key, value := range values {
+		converted := m.key(key)
+		if _, ok := result[converted]; ok {
+			m.duplicate = append(m.duplicate, key)
+		}
+		result[converted] = value
+	}
+	return result
+}
+
+// err описывает ошибки преобразования; допустимые значения — идентификаторы из списка.
+func (m *keyMapper) err(lang Lang, what string, list []Criterion) error {
+	var problems []string
+	if len(m.unknown) > 0 {
+		sort.Strings(m.unknown)
+		problems = append(problems, tr(lang, "неизвестные %s: %s, допустимые значения: %s",
+			tr(lang, what), quoteAll(m.unknown), strings.Join(criterionIDs(list), ", ")))
+	}
+	if len(m.duplicate) > 0 {
+		sort.Strings(m.duplicate)
+		problems = append(problems, tr(lang, "%s указаны повторно: %s",
+			tr(lang, what), quoteAll(m.duplicate)))
+	}
+	if len(problems) == 0 {
+		return nil
+	}
+	return errors.New(strings.Join(problems, "; "))
+}
+
+func quoteAll(values []string) string {
+	quoted := make([]string, len(values))
+	for i, v := range values {
+		quoted[i] = `"` + v + `"`
+	}
+	return strings.Join(quoted, ", ")
+}
+
+// namesToIDs переводит названия критериев в идентификаторы, остальные ключи оставляет как есть
+// и записывает встреченные названия в used.
+func namesToIDs(list []Criterion, used *[]string) *keyMapper {
+	return &keyMapper{convert: func(key string) (string, bool) {
+		for _, crit := range list {
+			if crit.Name == key && crit.ID != key {
+				*used = append(*used, key)
+				return crit.ID, true
+			}
+		}
+		return key, true
+	}}
+}
+
+// resolveRequestIDs переводит критерии запроса API из идентификаторов во внутренние имена.
+func resolveRequestIDs(lang Lang, req *RecommendationRequest) error {
+	m, vm := idToName(defaultCriteria), idToName(vendorCriteria)
+	mapRequestCriteria(req, m, vm)
+	if err := m.err(lang, "критерии", defaultCriteria); err != nil {
+		return err
+	}
+	return vm.err(lang, "критерии поставщиков", vendorCriteria)
+}
+
+// mapRequestCriteria переводит ключи критериев запроса: m — критерии первого этапа,
+// vm — критерии поставщиков.
+func mapRequestCriteria(req *RecommendationRequest, m, vm *keyMapper) {
+	req.SelectedCriteria = m.list(req.SelectedCriteria)
+	req.CriteriaPriorities = mapKeys(m, req.CriteriaPriorities)
+	req.OverriddenScores = mapKeys(m, req.OverriddenScores)
+	req.SpecialValues = mapKeys(m, req.SpecialValues)
+	req.CriteriaWeights = mapKeys(m, req.CriteriaWeights)
+	for i, j := range req.AHPJudgments {
+		req.AHPJudgments[i].A, req.AHPJudgments[i].B = m.key(j.A), m.key(j.B)
+	}
+	if req.TieBreakAnswer != "" {
+		req.TieBreakAnswer = m.key(req.TieBreakAnswer)
+	}
+	if req.Uncertainty != nil {
+		req.Uncertainty.PriorityRanges = mapKeys(m, req.Uncertainty.PriorityRanges)
+		req.Uncertainty.ScoreRanges = mapKeys(m, req.Uncertainty.ScoreRanges)
+	}
+	if req.Vendors != nil {
+		req.Vendors.CriteriaPriorities = mapKeys(vm, req.Vendors.CriteriaPriorities)
+	}
+}
+
+// resolveInputIDs переводит критерии сохраняемого через API ввода во внутренние имена.
+func resolveInputIDs(lang Lang, d *UserInputData) error {
+	m := idToName(defaultCriteria)
+	mapInputCriteria(d, m)
+	return m.err(lang, "критерии", defaultCriteria)
+}
+
+// inputWithCriterionIDs возвращает копию сохраненного ввода с идентификаторами критериев для ответа API.
+func inputWithCriterionIDs(d UserInputData) UserInputData {
+	d.AHPJudgments = append([]AHPJudgment(nil), d.AHPJudgments...)
+	mapInputCriteria(&d, nameToID())
+	return d
+}
+
+func mapInputCriteria(d *UserInputData, m *keyMapper) {
+	d.SelectedCriteria = m.list(d.SelectedCriteria)
+	d.CriteriaPriorities = mapKeys(m, d.CriteriaPriorities)
+	d.OverriddenScores = mapKeys(m, d.OverriddenScores)
+	d.SpecialValues = mapKeys(m, d.SpecialValues)
+	d.CriteriaWeights = mapKeys(m, d.CriteriaWeights)
+	for i, j := range d.AHPJudgments {
+		d.AHPJudgments[i].A, d.AHPJudgments[i].B = m.key(j.A), m.key(j.B)
+	}
+	if d.TieBreakAnswer != "" {
+		d.TieBreakAnswer = m.key(d.TieBreakAnswer)
+	}
+}
@@ -0,0 +1,144 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+const maxExplanationFactors = 3
+
+func explainRecommendation(response *RecommendationResponse) *Explanation {
+	lang := response.Language
+	explanation := &Explanation{
+		Supporting:  []CriterionContribution{},
+		Opposing:    []CriterionContribution{},
+		Adjustments: []string{},
+	}
+
+	totals := responseTotals(response)
+	excluded := excludedMask(response.Excluded)
+	order := rankOptions(totals, excluded)
+
+	var lines []string
+
+	winner := order[0]
+	for i, option := range deploymentOptions {
+		if option.ID == response.Outcome.OptionID {
+			winner = i
+		}
+	}
+
+	runnerUp := -1
+	for _, i := range order {
+		if i != winner && !excluded[i] {
+			runnerUp = i
+			break
+		}
+	}
+
+	switch {
+	case excluded[winner]:
+		lines = append(lines, tr(lang, "Все варианты исключены жёсткими ограничениями, сравнивать баллы не из чего."))
+	case response.Outcome.OptionID == "":
+		if leaders := response.Outcome.Tied; len(leaders) > 0 {
+			lines = append(lines, tr(lang, "Однозначной рекомендации нет: варианты (%s) равны по оценке.", strings.Join(optionNamesByID(leaders), ", ")))
+		} else {
+			lines = append(lines, tr(lang, "Однозначной рекомендации нет."))
+		}
+	case runnerUp < 0:
+		explanation.Winner = deploymentOptions[winner].Name
+		lines = append(lines, tr(lang, "Рекомендуется %s: остальные варианты исключены жёсткими ограничениями.",
+			explanation.Winner))
+	case response.Method != methodWeightedSum:
+		explanation.Winner = deploymentOptions[winner].Name
+		explanation.RunnerUp = deploymentOptions[runnerUp].Name
+		lines = append(lines, tr(lang, "Рекомендуется %s (метод «%s»), ближайшая альтернатива — %s.",
+			explanation.Winner, tr(lang, decisionMethodNames[response.Method]), explanation.RunnerUp))
+	default:
+		explanation.Winner = deploymentOptions[winner].Name
+		explanation.RunnerUp = deploymentOptions[runnerUp].Name
+		lines = append(lines, tr(lang, "Рекомендуется %s: %s баллов против %s у %s.",
+			explanation.Winner, formatNumber(totals[winner]), formatNumber(totals[runnerUp]), explanation.RunnerUp))
+	}
+
+	if runnerUp >= 0 && !excluded[winner] {
+		for _, detail := range response.Details {
+			weighted := detailWeighted(detail)
+			contribution := CriterionContribution{ID: detail.ID, Name: detail.Name, Lead: weighted[winner] - weighted[runnerUp]}
+			switch {
+			case contribution.Lead > 0:
+				explanation.Supporting = append(explanation.Supporting, contribution)
+			case contribution.Lead < 0:
+				contribution.Lead = -contribution.Lead
+				explanation.Opposing = append(explanation.Opposing, contribution)
+			}
+		}
+		byLead := func(list []CriterionContribution) {
+			sort.SliceStable(list, func(i, j int) bool { return list[i].Lead > list[j].Lead })
+		}
+		byLead(explanation.Supporting)
+		byLead(explanation.Opposing)
+
+		if len(explanation.Supporting) > 0 {
+			lines = append(lines, tr(lang, "Больше всего в пользу %s: %s.",
+				deploymentOptions[winner].Name, formatContributions(lang, explanation.Supporting)))
+		}
+		if len(explanation.Opposing) > 0 {
+			lines = append(lines, tr(lang, "В пользу %s: %s.",
+				deploymentOptions[runnerUp].Name, formatContributions(lang, explanation.Opposing)))
+		} else {
+			lines = append(lines, tr(lang, "Ни один критерий не даёт перевеса %s.", deploymentOptions[runnerUp].Name))
+		}
+	}
+
+	for _, detail := range response.Details {
+		crit := findCriterionByName(detail.Name)
+		label := criterionLabel(lang, detail.Name)
+		switch {
+		case detail.Source == sourceOverridden:
+			explanation.Adjustments = append(explanation.Adjustments, tr(lang, "Баллы «%s» переопределены вручную: %s вместо %s.",
+				label, formatScores(detail.Scores), formatScores(catalogScores(crit, detail))))
+		case detail.Source == sourceCost:
+			explanation.Adjustments = append(explanation.Adjustments, tr(lang, "Баллы «%s» рассчитаны из оценки стоимости: %s.",
+				label, formatScores(detail.Scores)))
+		case detail.SpecialValue != "":
+			explanation.Adjustments = append(explanation.Adjustments, tr(lang, "Значение «%s» критерия «%s» даёт баллы %s.",
+				specialValueLabel(lang, detail.Name, detail.SpecialValue), label, formatScores(detail.Scores)))
+		}
+		if len(detail.Adjustments) > 0 && detail.Source != sourceOverridden {
+			explanation.Adjustments = append(explanation.Adjustments, tr(lang, "Уточнения по «%s» (%s) дают баллы %s.",
+				label, strings.Join(detail.Adjustments, "; "), formatScores(detail.Scores)))
+		}
+	}
+	if response.TieBreak != nil && response.TieBreak.Policy != tieBreakNone {
+		explanation.Adjustments = append(explanation.Adjustments, formatTieBreak(lang, response.TieBreak))
+	}
+	for _, exclusion := range response.Excluded {
+		explanation.Adjustments = append(explanation.Adjustments, tr(lang, "%s исключён правилом «%s»: %s",
+			exclusion.Option, criterionLabel(lang, exclusion.Criterion), exclusion.Reason))
+	}
+	lines = append(lines, explanation.Adjustments...)
+
+	explanation.Text = tr(lang, "Почему такой результат:") + "\n" + strings.Join(lines, "\n")
+	return explanation
+}
+
+// catalogScores возвращает баллы критерия до ручного переопределения.
+func catalogScores(crit Criterion, detail CriterionDetail) Scores {
+	if detail.SpecialValue != "" {
+		return getScoresForSpecialCriterion(crit.Name, detail.SpecialValue)
+	}
+	return crit.BaseScores
+}
+
+func formatContributions(lang Lang, contributions []CriterionContribution) string {
+	var parts []string
+	for i, contribution := range contributions {
+		if i == maxExplanationFactors {
+			break
+		}
+		parts = append(parts, fmt.Sprintf("«%s» (+%s)", criterionLabel(lang, contribution.Name), formatNumber(contribution.Lead)))
+	}
+	return strings.Join(parts, ", ")
+}
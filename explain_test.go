@@ -0,0 +1,138 @@
+package main
+
+import (
+	"math"
+	"reflect"
+	"testing"
+)
+
+func TestExplainRecommendationContributions(t *testing.T) {
+	logger = NewLogger(true)
+
+	tests := []struct {
+		name        string
+		req         RecommendationRequest
+		winner      string
+		runnerUp    string
+		supporting  []CriterionContribution
+		opposing    []CriterionContribution
+		adjustments []string
+	}{
+		{
+			// Итоги: On-Premise 5.5, Private Cloud 4.5, Public Cloud 6.
+			name: "перевес одного критерия",
+			req: RecommendationRequest{
+				SelectedCriteria:   []string{"Юрисдикция данных", "Начальные инвестиции"},
+				CriteriaPriorities: map[string]int{"Юрисдикция данных": 4, "Начальные инвестиции": 4},
+			},
+			winner:      "Public Cloud",
+			runnerUp:    "On-Premise",
+			supporting:  []CriterionContribution{{ID: "initial_investment", Name: "Начальные инвестиции", Lead: 2.5}},
+			opposing:    []CriterionContribution{{ID: "data_jurisdiction", Name: "Юрисдикция данных", Lead: 2}},
+			adjustments: []string{},
+		},
+		{
+			// Public Cloud исключен правилом юрисдикции, сравниваются On-Premise и Private Cloud.
+			name: "второе место после исключения",
+			req: RecommendationRequest{
+				SelectedCriteria:   []string{"Юрисдикция данных", "Начальные инвестиции"},
+				CriteriaPriorities: map[string]int{"Юрисдикция данных": 5, "Начальные инвестиции": 5},
+			},
+			winner:     "On-Premise",
+			runnerUp:   "Private Cloud",
+			supporting: []CriterionContribution{{ID: "data_jurisdiction", Name: "Юрисдикция данных", Lead: 1.5}},
+			opposing:   []CriterionContribution{{ID: "initial_investment", Name: "Начальные инвестиции", Lead: 0.5}},
+			adjustments: []string{
+				"Public Cloud исключён правилом «Юрисдикция данных»: Данные должны оставаться в РФ на собственном оборудовании.",
+			},
+		},
+		{
+			// Веса 1/2, 1/3, 1/6; вклады сортируются по убыванию перевеса.
+			name: "все критерии за победителя",
+			req: RecommendationRequest{
+				SelectedCriteria: []string{"Отраслевые стандарты", "Латентность", "Физическая безопасность"},
+				CriteriaPriorities: map[string]int{
+					"Отраслевые стандарты":    3,
+					"Латентность":             2,
+					"Физическая безопасность": 1,
+				},
+			},
+			winner:   "On-Premise",
+			runnerUp: "Private Cloud",
+			supporting: []CriterionContribution{
+				{ID: "latency", Name: "Латентность", Lead: 2.0 / 3},
+				{ID: "industry_standards", Name: "Отраслевые стандарты", Lead: 0.5},
+				{ID: "physical_security", Name: "Физическая безопасность", Lead: 1.0 / 6},
+			},
+			opposing:    []CriterionContribution{},
+			adjustments: []string{},
+		},
+		{
+			name: "переопределенные баллы",
+			req: RecommendationRequest{
+				SelectedCriteria:   []string{"Латентность"},
+				CriteriaPriorities: map[string]int{"Латентность": 3},
+				OverriddenScores:   map[string]Scores{"Латентность": {"public": 10}},
+			},
+			winner:     "Public Cloud",
+			runnerUp:   "On-Premise",
+			supporting: []CriterionContribution{{ID: "latency", Name: "Латентность", Lead: 2}},
+			opposing:   []CriterionContribution{},
+			adjustments: []string{
+				"Баллы «Латентность» переопределены вручную: OnPrem=8, Private=6, Public=10 вместо OnPrem=8, Private=6, Public=5.",
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.req.Lang = langRU
+			explanation := evaluateRecommendation(tt.req).Explanation
+
+			if explanation.Winner != tt.winner || explanation.RunnerUp != tt.runnerUp {
+				t.Errorf("победитель/второе место = %s/%s, ожидалось %s/%s",
+					explanation.Winner, explanation.RunnerUp, tt.winner, tt.runnerUp)
+			}
+			assertContributions(t, "Supporting", explanation.Supporting, tt.supporting)
+			assertContributions(t, "Opposing", explanation.Opposing, tt.opposing)
+			if !reflect.DeepEqual(explanation.Adjustments, tt.adjustments) {
+				t.Errorf("Adjustments = %q, ожидалось %q", explanation.Adjustments, tt.adjustments)
+			}
+		})
+	}
+}
+
+func assertContributions(t *testing.T, field string, got, want []CriterionContribution) {
+	t.Helper()
+	if len(got) != len(want) {
+		t.Fatalf("%s = %+v, ожидалось %+v", field, got, want)
+	}
+	for i := range want {
+		if got[i].ID != want[i].ID || got[i].Name != want[i].Name || math.Abs(got[i].Lead-want[i].Lead) > 1e-9 {
+			t.Errorf("%s[%d] = %+v, ожидалось %+v", field, i, got[i], want[i])
+		}
+	}
+}
+
+func TestExplainRecommendationTie(t *testing.T) {
+	logger = NewLogger(true)
+	response := evaluateRecommendation(RecommendationRequest{
+		SelectedCriteria:   []string{"Латентность"},
+		CriteriaPriorities: map[string]int{"Латентность": 3},
+		OverriddenScores:   map[string]Scores{"Латентность": {"on_prem": 7, "private": 7, "public": 5}},
+		Lang:               langEN,
+	})
+
+	if want := (Outcome{Tied: []string{"on_prem", "private"}}); !sameOutcome(response.Outcome, want) {
+		t.Fatalf("Outcome = %+v, ожидалось %+v", response.Outcome, want)
+	}
+	if want := "Требуется дополнительная оценка (On-Premise/Private Cloud)"; response.Recommendation != want {
+		t.Errorf("Recommendation = %q, ожидалось %q", response.Recommendation, want)
+	}
+	if got, want := recommendationLabel(langEN, response.Outcome), "Further assessment required (On-Premise/Private Cloud)"; got != want {
+		t.Errorf("recommendationLabel = %q, ожидалось %q", got, want)
+	}
+	if explanation := response.Explanation; explanation.Winner != "" || len(explanation.Supporting) != 0 {
+		t.Errorf("при ничьей объяснение называет победителя: %+v", explanation)
+	}
+}
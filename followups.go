@@ -0,0 +1,384 @@
+package main
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+// FollowUpCondition — условие показа уточняющего вопроса. Заданные поля проверяются вместе:
+// критерий выбран, его приоритет не ниже MinPriority (при весах AHP приоритета нет и условие
+// не проверяется), специальное значение совпадает, на вопрос FollowUp дан ответ Answer.
+type FollowUpCondition struct {
+	Criterion    string `json:"criterion,omitempty"`
+	MinPriority  int    `json:"min_priority,omitempty"`
+	SpecialValue string `json:"special_value,omitempty"`
+	FollowUp     string `json:"follow_up,omitempty"`
+	Answer       string `json:"answer,omitempty"`
+}
+
+// FollowUpAnswer — вариант ответа. Adjustments — поправки к баллам критериев
+// (ключ — имя критерия), применяются только к выбранным критериям.
+type FollowUpAnswer struct {
+	Value       string            `json:"value"`
+	Adjustments map[string]Scores `json:"adjustments,omitempty"`
+}
+
+// FollowUp — уточняющий вопрос каталога. Вопрос может зависеть от ответа на вопрос,
+// стоящий в каталоге раньше.
+type FollowUp struct {
+	ID           string                `json:"id"`
+	When         FollowUpCondition     `json:"when"`
+	Question     string                `json:"question"`
+	Answers      []FollowUpAnswer      `json:"answers"`
+	Translations map[Lang]FollowUpText `json:"translations,omitempty"`
+}
+
+// FollowUpText — перевод вопроса. Answers — переводы ответов, ключ — значение ответа.
+type FollowUpText struct {
+	Question string            `json:"question"`
+	Answers  map[string]string `json:"answers,omitempty"`
+}
+
+var followUps = getDefaultFollowUps()
+
+func getDefaultFollowUps() []FollowUp {
+	return []FollowUp{
+		{
+			ID:       "industry_standard",
+			When:     FollowUpCondition{Criterion: "Отраслевые стандарты"},
+			Question: "Какой стандарт применяется?",
+			Answers: []FollowUpAnswer{
+				{Value: "PCI DSS", Adjustments: map[string]Scores{
+					"Отраслевые стандарты": {"on_prem": -1, "public": 1},
+				}},
+				{Value: "152-ФЗ", Adjustments: map[string]Scores{
+					"Отраслевые стандарты": {"on_prem": 1, "public": -1},
+				}},
+				{Value: "ГОСТ", Adjustments: map[string]Scores{
+					"Отраслевые стандарты": {"on_prem": 1, "public": -2},
+				}},
+				{Value: "Другой"},
+			},
+			Translations: map[Lang]FollowUpText{
+				langEN: {
+					Question: "Which standard applies?",
+					Answers:  map[string]string{"152-ФЗ": "152-FZ", "ГОСТ": "GOST", "Другой": "Other"},
+				},
+			},
+		},
+		{
+			ID:       "pd_protection_level",
+			When:     FollowUpCondition{FollowUp: "industry_standard", Answer: "152-ФЗ"},
+			Question: "Какой уровень защищенности персональных данных требуется?",
+			Answers: []FollowUpAnswer{
+				{Value: "УЗ-1", Adjustments: map[string]Scores{
+					"Отраслевые стандарты": {"on_prem": 1, "public": -2},
+				}},
+				{Value: "УЗ-2 или УЗ-3"},
+				{Value: "УЗ-4", Adjustments: map[string]Scores{
+					"Отраслевые стандарты": {"public": 1},
+				}},
+			},
+			Translations: map[Lang]FollowUpText{
+				langEN: {
+					Question: "What level of personal data protection is required?",
+					Answers:  map[string]string{"УЗ-1": "UZ-1", "УЗ-2 или УЗ-3": "UZ-2 or UZ-3", "УЗ-4": "UZ-4"},
+				},
+			},
+		},
+		{
+			ID:       "client_geography",
+			When:     FollowUpCondition{Criterion: "Латентность", MinPriority: 4},
+			Question: "Где находятся клиенты приложения?",
+			Answers: []FollowUpAnswer{
+				{Value: "В одном регионе"},
+				{Value: "В нескольких регионах", Adjustments: map[string]Scores{
+					"Латентность": {"on_prem": -2, "private": -1, "public": 2},
+				}},
+			},
+			Translations: map[Lang]FollowUpText{
+				langEN: {
+					Question: "Where are the application's clients located?",
+					Answers:  map[string]string{"В одном регионе": "In one region", "В нескольких регионах": "In several regions"},
+				},
+			},
+		},
+	}
+}
+
+func findFollowUp(id string) (FollowUp, bool) {
+	for _, followUp := range followUps {
+		if followUp.ID == id {
+			return followUp, true
+		}
+	}
+	return FollowUp{}, false
+}
+
+func (f FollowUp) answer(value string) (FollowUpAnswer, bool) {
+	for _, answer := range f.Answers {
+		if answer.Value == value {
+			return answer, true
+		}
+	}
+	return FollowUpAnswer{}, false
+}
+
+func (f FollowUp) question(lang Lang) string {
+	return firstNonEmpty(f.Translations[lang].Question, f.Question)
+}
+
+func (f FollowUp) answerLabel(lang Lang, value string) string {
+	return firstNonEmpty(f.Translations[lang].Answers[value], value)
+}
+
+func (f FollowUp) answerLabels(lang Lang) []string {
+	labels := make([]string, 0, len(f.Answers))
+	for _, answer := range f.Answers {
+		labels = append(labels, f.answerLabel(lang, answer.Value))
+	}
+	return labels
+}
+
+func (f FollowUp) answerValues() []string {
+	values := make([]string, 0, len(f.Answers))
+	for _, answer := range f.Answers {
+		values = append(values, answer.Value)
+	}
+	return values
+}
+
+// applicableFollowUps возвращает вопросы, условия которых выполнены для запроса, в порядке каталога.
+func applicableFollowUps(req RecommendationRequest) []FollowUp {
+	var result []FollowUp
+	shown := make(map[string]bool)
+
+	for _, followUp := range followUps {
+		when := followUp.When
+		if when.Criterion != "" {
+			if !contains(req.SelectedCriteria, when.Criterion) {
+				continue
+			}
+			if prio, ok := req.CriteriaPriorities[when.Criterion]; when.MinPriority > 0 && ok && prio < when.MinPriority {
+				continue
+			}
+			if when.SpecialValue != "" && !strings.EqualFold(req.SpecialValues[when.Criterion], when.SpecialValue) {
+				continue
+			}
+		}
+		if when.FollowUp != "" && (!shown[when.FollowUp] || req.FollowUpAnswers[when.FollowUp] != when.Answer) {
+			continue
+		}
+
+		shown[followUp.ID] = true
+		result = append(result, followUp)
+	}
+	return result
+}
+
+// followUpChoice — ответ на уточняющий вопрос вместе с самим вопросом.
+type followUpChoice struct {
+	followUp FollowUp
+	answer   FollowUpAnswer
+}
+
+// evaluateFollowUps возвращает применимые вопросы с ответами и поправки к баллам по критериям.
+// Ответы на неприменимые вопросы игнорируются, тексты вопросов выводятся на языке запроса.
+func evaluateFollowUps(req RecommendationRequest) ([]FollowUpResult, map[string][]followUpChoice) {
+	var results []FollowUpResult
+	adjustments := make(map[string][]followUpChoice)
+
+	for _, followUp := range applicableFollowUps(req) {
+		result := FollowUpResult{
+			ID:           followUp.ID,
+			Question:     followUp.question(req.Lang),
+			Answers:      followUp.answerValues(),
+			AnswerLabels: followUp.answerLabels(req.Lang),
+		}
+		if answer, ok := followUp.answer(req.FollowUpAnswers[followUp.ID]); ok {
+			result.Answer = answer.Value
+			for name := range answer.Adjustments {
+				adjustments[name] = append(adjustments[name], followUpChoice{followUp, answer})
+			}
+		}
+		results = append(results, result)
+	}
+	return results, adjustments
+}
+
+// adjustScores прибавляет поправки ответов к баллам критерия, удерживая их в пределах шкалы.
+func adjustScores(scores Scores, criterion string, choices []followUpChoice) Scores {
+	adjusted := cloneScores(scores)
+	for _, choice := range choices {
+		for id, delta := range choice.answer.Adjustments[criterion] {
+			adjusted[id] = math.Max(minScore, math.Min(maxScore, adjusted[id]+delta))
+		}
+	}
+	return adjusted
+}
+
+func validateFollowUps(list []FollowUp, criteria map[string]bool, options map[string]bool) error {
+	seen := make(map[string]FollowUp)
+	for _, followUp := range list {
+		if followUp.ID == "" || followUp.Question == "" || len(followUp.Answers) == 0 {
+			return fmt.Errorf("у уточняющего вопроса должны быть заданы id, question и answers")
+		}
+		if _, ok := seen[followUp.ID]; ok {
+			return fmt.Errorf("уточняющий вопрос %q указан дважды", followUp.ID)
+		}
+		if lang, ok := unsupportedLang(followUp.Translations); ok {
+			return fmt.Errorf("вопрос %s: неподдерживаемый язык перевода %q", followUp.ID, lang)
+		}
+
+		when := followUp.When
+		if when.Criterion == "" && when.FollowUp == "" {
+			return fmt.Errorf("вопрос %s: в условии нужен criterion или follow_up", followUp.ID)
+		}
+		if when.Criterion != "" && !criteria[when.Criterion] {
+			return fmt.Errorf("вопрос %s: неизвестный критерий %q", followUp.ID, when.Criterion)
+		}
+		if when.FollowUp != "" {
+			parent, ok := seen[when.FollowUp]
+			if !ok {
+				return fmt.Errorf("вопрос %s: вопрос %q должен быть описан раньше", followUp.ID, when.FollowUp)
+			}
+			if _, ok := parent.answer(when.Answer); !ok {
+				return fmt.Errorf("вопрос %s: у вопроса %q нет ответа %q", followUp.ID, when.FollowUp, when.Answer)
+			}
+		}
+
+		for _, answer := range followUp.Answers {
+			for name, scores := range answer.Adjustments {
+				if !criteria[name] {
+					return fmt.Errorf("вопрос %s/%s: неизвестный критерий %q", followUp.ID, answer.Value, name)
+				}
+				for id := range scores {
+					if !options[id] {
+						return fmt.Errorf("вопрос %s/%s: неизвестный вариант развертывания %q", followUp.ID, answer.Value, id)
+					}
+				}
+			}
+		}
+		seen[followUp.ID] = followUp
+	}
+	return nil
+}
+
+func formatFollowUpAdjustment(lang Lang, criterion string, choice followUpChoice) string {
+	var parts []string
+	for _, option := range deploymentOptions {
+		if delta, ok := choice.answer.Adjustments[criterion][option.ID]; ok {
+			parts = append(parts, fmt.Sprintf("%s %+g", option.ShortName, delta))
+		}
+	}
+	return tr(lang, "ответ «%s»: %s", choice.followUp.answerLabel(lang, choice.answer.Value), strings.Join(parts, ", "))
+}
+
+// followUpRequest собирает из состояния данные, от которых зависят уточняющие вопросы.
+func followUpRequest(state *UserState) RecommendationRequest {
+	return RecommendationRequest{
+		SelectedCriteria:   state.SelectedCriteria,
+		CriteriaPriorities: state.CriteriaPriorities,
+		SpecialValues:      state.SpecialValues,
+		FollowUpAnswers:    state.FollowUpAnswers,
+	}
+}
+
+// pendingFollowUp возвращает индекс в каталоге первого применимого вопроса без ответа или -1.
+func pendingFollowUp(state *UserState) int {
+	for _, followUp := range applicableFollowUps(followUpRequest(state)) {
+		if _, ok := state.FollowUpAnswers[followUp.ID]; ok {
+			continue
+		}
+		for i := range followUps {
+			if followUps[i].ID == followUp.ID {
+				return i
+			}
+		}
+	}
+	return -1
+}
+
+// pruneFollowUpAnswers удаляет ответы на вопросы, ставшие неприменимыми после изменения ответов.
+func pruneFollowUpAnswers(state *UserState) {
+	for {
+		applicable := make(map[string]bool)
+		for _, followUp := range applicableFollowUps(followUpRequest(state)) {
+			applicable[followUp.ID] = true
+		}
+		removed := false
+		for id := range state.FollowUpAnswers {
+			if !applicable[id] {
+				delete(state.FollowUpAnswers, id)
+				removed = true
+			}
+		}
+		if !removed {
+			return
+		}
+	}
+}
+
+// askFollowUps задает следующий применимый уточняющий вопрос, а когда вопросов не осталось,
+// переходит к оценке стоимости.
+func askFollowUps(bot *tgbotapi.BotAPI, chatID int64) {
+	state := userStates[chatID]
+	if state.FollowUpAnswers == nil {
+		state.FollowUpAnswers = make(map[string]string)
+	}
+
+	index := pendingFollowUp(state)
+	if index < 0 {
+		transition(bot, chatID, stateCost)
+		return
+	}
+
+	followUp := followUps[index]
+	lang := userLang(chatID)
+
+	var rows [][]tgbotapi.InlineKeyboardButton
+	for i, label := range followUp.answerLabels(lang) {
+		rows = append(rows, tgbotapi.NewInlineKeyboardRow(
+			tgbotapi.NewInlineKeyboardButtonData(label, fmt.Sprintf("fup_%d_%d", index, i)),
+		))
+	}
+
+	msg := tgbotapi.NewMessage(chatID, followUp.question(lang))
+	msg.ReplyMarkup = tgbotapi.NewInlineKeyboardMarkup(rows...)
+	sendMessage(bot, msg)
+
+	logger.LogTelegramAction("Уточняющий вопрос", map[string]interface{}{
+		"ChatID": chatID,
+		"Вопрос": followUp.ID,
+	})
+}
+
+func handleFollowUpCallback(bot *tgbotapi.BotAPI, chatID int64, callbackData string) {
+	state := userStates[chatID]
+
+	parts := strings.Split(callbackData, "_")
+	if len(parts) != 3 {
+		return
+	}
+	index, indexErr := strconv.Atoi(parts[1])
+	answerIndex, answerErr := strconv.Atoi(parts[2])
+	if indexErr != nil || answerErr != nil || index < 0 || index >= len(followUps) ||
+		answerIndex < 0 || answerIndex >= len(followUps[index].Answers) {
+		logger.Printf("Некорректный ответ на уточняющий вопрос: %s", callbackData)
+		return
+	}
+
+	followUp := followUps[index]
+	state.FollowUpAnswers[followUp.ID] = followUp.Answers[answerIndex].Value
+
+	logger.LogTelegramAction("Ответ на уточняющий вопрос", map[string]interface{}{
+		"Вопрос": followUp.ID,
+		"Ответ":  followUp.Answers[answerIndex].Value,
+	})
+
+	askFollowUps(bot, chatID)
+}
@@ -0,0 +1,291 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+const historyPageSize = 5
+
+// AnswerRecord — сохраненный результат чеклиста. Outcome пуст у записей, сохраненных до
+// появления колонки outcome: для них показывается текст AlgorithmResult.
+type AnswerRecord struct {
+	ID              int64
+	UserInput       UserInputData
+	AlgorithmResult string
+	Outcome         *Outcome
+	GPTAnswer       string
+	CreatedAt       time.Time
+}
+
+// recommendationLabel выводит рекомендацию записи на языке lang.
+func (record AnswerRecord) recommendationLabel(lang Lang) string {
+	if record.Outcome == nil {
+		return record.AlgorithmResult
+	}
+	return recommendationLabel(lang, *record.Outcome)
+}
+
+// recommendedOption возвращает идентификатор рекомендованного варианта или "" при ничьей.
+func (record AnswerRecord) recommendedOption() string {
+	if record.Outcome == nil {
+		if option, ok := findDeploymentOptionByName(record.AlgorithmResult); ok {
+			return option.ID
+		}
+		return ""
+	}
+	return record.Outcome.OptionID
+}
+
+func loadUserAnswers(userID int64, limit, offset int) ([]AnswerRecord, int, error) {
+	var total int
+	err := pool.QueryRow(context.Background(),
+		`SELECT COUNT(*) FROM answers WHERE user_id = $1`, userID).Scan(&total)
+	if err != nil {
+		return nil, 0, fmt.Errorf("ошибка подсчета записей истории: %w", err)
+	}
+
+	rows, err := pool.Query(context.Background(),
+		`SELECT id, user_input, COALESCE(algorithm_result, ''), outcome, COALESCE(gpt_answer, ''), created_at
+		 FROM answers WHERE user_id = $1
+		 ORDER BY created_at DESC, id DESC
+		 LIMIT $2 OFFSET $3`, userID, limit, offset)
+	if err != nil {
+		return nil, 0, fmt.Errorf("ошибка чтения истории: %w", err)
+	}
+	defer rows.Close()
+
+	var records []AnswerRecord
+	for rows.Next() {
+		record, err := scanAnswerRecord(rows)
+		if err != nil {
+			return nil, 0, err
+		}
+		records = append(records, record)
+	}
+
+	return records, total, rows.Err()
+}
+
+// recordOwner — владелец ответов и профилей: пользователь Telegram (колонка user_id) или
+// клиент API (api_key_id). Клиент API видит только записи, сделанные с его ключом.
+type recordOwner struct {
+	column string
+	id     int64
+}
+
+func telegramOwner(chatID int64) recordOwner {
+	return recordOwner{column: "user_id", id: chatID}
+}
+
+func apiKeyOwner(client *apiClient) recordOwner {
+	return recordOwner{column: "api_key_id", id: client.keyID}
+}
+
+func loadUserAnswer(userID, answerID int64) (*AnswerRecord, error) {
+	return loadAnswer(telegramOwner(userID), answerID)
+}
+
+func loadAnswer(owner recordOwner, answerID int64) (*AnswerRecord, error) {
+	row := pool.QueryRow(context.Background(),
+		`SELECT id, user_input, COALESCE(algorithm_result, ''), outcome, COALESCE(gpt_answer, ''), created_at
+		 FROM answers WHERE `+owner.column+` = $1 AND id = $2`, owner.id, answerID)
+
+	record, err := scanAnswerRecord(row)
+	if err != nil {
+		return nil, err
+	}
+	return &record, nil
+}
+
+type answerScanner interface {
+	Scan(dest ...interface{}) error
+}
+
+func scanAnswerRecord(row answerScanner) (AnswerRecord, error) {
+	var record AnswerRecord
+	var userInputJSON, outcomeJSON []byte
+
+	err := row.Scan(&record.ID, &userInputJSON, &record.AlgorithmResult, &outcomeJSON, &record.GPTAnswer, &record.CreatedAt)
+	if err != nil {
+		return record, fmt.Errorf("ошибка чтения записи ответа: %w", err)
+	}
+
+	if len(userInputJSON) > 0 {
+		if err := json.Unmarshal(userInputJSON, &record.UserInput); err != nil {
+			logger.Printf("Ошибка разбора user_input записи %d: %v", record.ID, err)
+		}
+	}
+	if len(outcomeJSON) > 0 {
+		record.Outcome = &Outcome{}
+		if err := json.Unmarshal(outcomeJSON, record.Outcome); err != nil {
+			logger.Printf("Ошибка разбора outcome записи %d: %v", record.ID, err)
+			record.Outcome = nil
+		}
+	}
+
+	return record, nil
+}
+
+// aiVerdict возвращает первую строку ответа AI, в которой по формату промпта указан тип СУБД.
+func aiVerdict(lang Lang, gptAnswer string) string {
+	for _, line := range strings.Split(gptAnswer, "\n") {
+		line = strings.Trim(strings.TrimSpace(line), "*")
+		if line != "" {
+			return line
+		}
+	}
+	return tr(lang, "нет ответа")
+}
+
+func showHistory(bot *tgbotapi.BotAPI, chatID int64, page int, messageID int) {
+	lang := userLang(chatID)
+	records, total, err := loadUserAnswers(chatID, historyPageSize, page*historyPageSize)
+	if err != nil {
+		logger.Printf("Ошибка загрузки истории для chatID %d: %v", chatID, err)
+		sendMessage(bot, tgbotapi.NewMessage(chatID, tr(lang, "Не удалось загрузить историю.")))
+		return
+	}
+
+	if total == 0 {
+		sendMessage(bot, tgbotapi.NewMessage(chatID, tr(lang, "У вас пока нет пройденных чеклистов. Чтобы начать, введите /start")))
+		return
+	}
+
+	pages := (total + historyPageSize - 1) / historyPageSize
+
+	var text strings.Builder
+	text.WriteString(tr(lang, "История чеклистов (страница %d из %d):", page+1, pages) + "\n\n")
+
+	var keyboardRows [][]tgbotapi.InlineKeyboardButton
+	for i, record := range records {
+		number := page*historyPageSize + i + 1
+		text.WriteString(fmt.Sprintf("%d. %s\n", number, record.CreatedAt.Format("02.01.2006 15:04")))
+		text.WriteString("   " + tr(lang, "Рекомендация: %s", record.recommendationLabel(lang)) + "\n")
+		text.WriteString(fmt.Sprintf("   AI: %s\n\n", aiVerdict(lang, record.GPTAnswer)))
+
+		keyboardRows = append(keyboardRows, tgbotapi.NewInlineKeyboardRow(
+			tgbotapi.NewInlineKeyboardButtonData(tr(lang, "%d. Подробнее", number),
+				fmt.Sprintf("hist_view_%d", record.ID)),
+			tgbotapi.NewInlineKeyboardButtonData(tr(lang, "%d. Пройти заново", number),
+				fmt.Sprintf("hist_rerun_%d", record.ID)),
+		))
+	}
+
+	var navRow []tgbotapi.InlineKeyboardButton
+	if page > 0 {
+		navRow = append(navRow, tgbotapi.NewInlineKeyboardButtonData(tr(lang, "◀️ Назад"), fmt.Sprintf("hist_page_%d", page-1)))
+	}
+	if page+1 < pages {
+		navRow = append(navRow, tgbotapi.NewInlineKeyboardButtonData(tr(lang, "Вперёд ▶️"), fmt.Sprintf("hist_page_%d", page+1)))
+	}
+	if len(navRow) > 0 {
+		keyboardRows = append(keyboardRows, navRow)
+	}
+
+	keyboard := tgbotapi.NewInlineKeyboardMarkup(keyboardRows...)
+
+	if messageID != 0 {
+		editMsg := tgbotapi.NewEditMessageTextAndMarkup(chatID, messageID, text.String(), keyboard)
+		editMessageText(bot, editMsg)
+		return
+	}
+
+	msg := tgbotapi.NewMessage(chatID, text.String())
+	msg.ReplyMarkup = keyboard
+	sendMessage(bot, msg)
+}
+
+func handleHistoryCallback(bot *tgbotapi.BotAPI, query *tgbotapi.CallbackQuery, chatID int64) {
+	parts := strings.Split(query.Data, "_")
+	if len(parts) != 3 {
+		return
+	}
+
+	value, err := strconv.ParseInt(parts[2], 10, 64)
+	if err != nil {
+		logger.Printf("Некорректный callback истории: %s", query.Data)
+		return
+	}
+
+	switch parts[1] {
+	case "page":
+		messageID := 0
+		if query.Message != nil {
+			messageID = query.Message.MessageID
+		}
+		showHistory(bot, chatID, int(value), messageID)
+	case "view":
+		showHistoryEntry(bot, chatID, value)
+	case "rerun":
+		rerunFromHistory(bot, chatID, value)
+	}
+}
+
+func showHistoryEntry(bot *tgbotapi.BotAPI, chatID int64, answerID int64) {
+	lang := userLang(chatID)
+	record, err := loadUserAnswer(chatID, answerID)
+	if err != nil {
+		logger.Printf("Ошибка загрузки записи %d для chatID %d: %v", answerID, chatID, err)
+		sendMessage(bot, tgbotapi.NewMessage(chatID, tr(lang, "Запись не найдена.")))
+		return
+	}
+
+	req := inputToRequest(record.UserInput)
+	req.Lang = lang
+	response := evaluateRecommendation(req)
+
+	logger.LogTelegramAction("Просмотр записи истории", map[string]interface{}{
+		"ChatID":   chatID,
+		"AnswerID": answerID,
+	})
+
+	header := tr(lang, "Чеклист от %s\nСохранённая рекомендация: %s",
+		record.CreatedAt.Format("02.01.2006 15:04"), record.recommendationLabel(lang)) + "\n\n"
+	sendMessage(bot, tgbotapi.NewMessage(chatID, header+formatResultMessage(response)))
+	sendMessage(bot, tgbotapi.NewMessage(chatID, formatDetailsMessage(lang, response.Details)))
+
+	if record.GPTAnswer != "" {
+		sendMessage(bot, tgbotapi.NewMessage(chatID, tr(lang, "Рекомендация AI:")+"\n"+record.GPTAnswer))
+	}
+
+	msg := tgbotapi.NewMessage(chatID, tr(lang, "Что сделать с этим прохождением?"))
+	msg.ReplyMarkup = tgbotapi.NewInlineKeyboardMarkup(
+		tgbotapi.NewInlineKeyboardRow(
+			tgbotapi.NewInlineKeyboardButtonData(tr(lang, "🔁 Пройти заново"), fmt.Sprintf("hist_rerun_%d", record.ID)),
+			tgbotapi.NewInlineKeyboardButtonData(tr(lang, "💾 Сохранить как профиль"), fmt.Sprintf("prof_save_%d", record.ID)),
+		),
+		tgbotapi.NewInlineKeyboardRow(
+			tgbotapi.NewInlineKeyboardButtonData(tr(lang, "🎲 Показать уверенность"), fmt.Sprintf("conf_%d", record.ID)),
+			tgbotapi.NewInlineKeyboardButtonData(tr(lang, "🏷 Подобрать поставщика"), fmt.Sprintf("vend_%d", record.ID)),
+		),
+	)
+	sendMessage(bot, msg)
+}
+
+func rerunFromHistory(bot *tgbotapi.BotAPI, chatID int64, answerID int64) {
+	record, err := loadUserAnswer(chatID, answerID)
+	if err != nil {
+		logger.Printf("Ошибка загрузки записи %d для chatID %d: %v", answerID, chatID, err)
+		sendMessage(bot, tgbotapi.NewMessage(chatID, tr(userLang(chatID), "Запись не найдена.")))
+		return
+	}
+
+	state := newStateFromInput(record.UserInput)
+	userStates[chatID] = state
+
+	logger.LogTelegramAction("Чеклист начат из истории", map[string]interface{}{
+		"ChatID":   chatID,
+		"AnswerID": answerID,
+		"Критерии": state.SelectedCriteria,
+	})
+
+	sendMessage(bot, tgbotapi.NewMessage(chatID, tr(userLang(chatID), "Ответы предыдущего прохождения подставлены.")))
+	transition(bot, chatID, stateReview)
+}
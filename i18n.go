@@ -0,0 +1,185 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/jackc/pgx/v5"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+const (
+	langRU Lang = "ru"
+	langEN Lang = "en"
+
+	defaultLang = langRU
+)
+
+var supportedLangs = []Lang{langRU, langEN}
+
+var langNames = map[Lang]string{
+	langRU: "🇷🇺 Русский",
+	langEN: "🇬🇧 English",
+}
+
+// translations — каталоги сообщений по языкам. Ключ сообщения — русский текст (или формат
+// fmt) из кода, поэтому русскому языку каталог не нужен, а сообщение без перевода
+// выводится по-русски.
+var translations = map[Lang]map[string]string{
+	langEN: messagesEN,
+}
+
+// userLangs — язык, выбранный пользователем бота командой /lang или взятый из настроек Telegram.
+var userLangs = make(map[int64]Lang)
+
+// tr переводит сообщение msgid на язык lang и подставляет в него аргументы.
+func tr(lang Lang, msgid string, args ...interface{}) string {
+	text := msgid
+	if translated, ok := translations[lang][msgid]; ok {
+		text = translated
+	}
+	if len(args) == 0 {
+		return text
+	}
+	return fmt.Sprintf(text, args...)
+}
+
+// parseLang разбирает код языка вида "en", "en-US" или "ru_RU".
+func parseLang(code string) (Lang, bool) {
+	code = strings.ToLower(strings.TrimSpace(code))
+	if i := strings.IndexAny(code, "-_"); i >= 0 {
+		code = code[:i]
+	}
+	for _, lang := range supportedLangs {
+		if string(lang) == code {
+			return lang, true
+		}
+	}
+	return "", false
+}
+
+// acceptLanguage выбирает поддерживаемый язык из заголовка Accept-Language с учетом весов q.
+func acceptLanguage(header string) Lang {
+	type candidate struct {
+		lang Lang
+		q    float64
+	}
+	var candidates []candidate
+
+	for _, part := range strings.Split(header, ",") {
+		fields := strings.Split(part, ";")
+		q := 1.0
+		for _, param := range fields[1:] {
+			if value, ok := strings.CutPrefix(strings.TrimSpace(param), "q="); ok {
+				if parsed, err := strconv.ParseFloat(value, 64); err == nil {
+					q = parsed
+				}
+			}
+		}
+		if lang, ok := parseLang(fields[0]); ok && q > 0 {
+			candidates = append(candidates, candidate{lang, q})
+		}
+	}
+
+	sort.SliceStable(candidates, func(i, j int) bool { return candidates[i].q > candidates[j].q })
+	if len(candidates) == 0 {
+		return defaultLang
+	}
+	return candidates[0].lang
+}
+
+func requestLang(r *http.Request) Lang {
+	return acceptLanguage(r.Header.Get("Accept-Language"))
+}
+
+// userLang возвращает язык пользователя бота: сохраненный выбор или язык по умолчанию.
+func userLang(chatID int64) Lang {
+	if lang, ok := userLangs[chatID]; ok {
+		return lang
+	}
+	if lang, ok := loadUserLang(chatID); ok {
+		userLangs[chatID] = lang
+		return lang
+	}
+	return defaultLang
+}
+
+// rememberTelegramLang запоминает язык из настроек Telegram, если пользователь еще не выбрал язык.
+func rememberTelegramLang(chatID int64, from *tgbotapi.User) {
+	if _, ok := userLangs[chatID]; ok || from == nil {
+		return
+	}
+	if lang, ok := loadUserLang(chatID); ok {
+		userLangs[chatID] = lang
+		return
+	}
+	if lang, ok := parseLang(from.LanguageCode); ok {
+		userLangs[chatID] = lang
+	} else {
+		userLangs[chatID] = defaultLang
+	}
+}
+
+func loadUserLang(chatID int64) (Lang, bool) {
+	if pool == nil {
+		return "", false
+	}
+
+	var code string
+	err := pool.QueryRow(context.Background(),
+		`SELECT lang FROM user_settings WHERE user_id = $1`, chatID).Scan(&code)
+	if err != nil {
+		if !errors.Is(err, pgx.ErrNoRows) {
+			logger.Printf("Ошибка загрузки языка для chatID %d: %v", chatID, err)
+		}
+		return "", false
+	}
+	return parseLang(code)
+}
+
+func saveUserLang(chatID int64, lang Lang) {
+	userLangs[chatID] = lang
+	if pool == nil {
+		return
+	}
+
+	_, err := pool.Exec(context.Background(),
+		`INSERT INTO user_settings (user_id, lang) VALUES ($1, $2)
+		 ON CONFLICT (user_id) DO UPDATE SET lang = EXCLUDED.lang`, chatID, string(lang))
+	if err != nil {
+		logger.Printf("Ошибка сохранения языка для chatID %d: %v", chatID, err)
+	}
+}
+
+func showLangSelection(bot *tgbotapi.BotAPI, chatID int64) {
+	var row []tgbotapi.InlineKeyboardButton
+	for _, lang := range supportedLangs {
+		row = append(row, tgbotapi.NewInlineKeyboardButtonData(langNames[lang], "lang_"+string(lang)))
+	}
+
+	msg := tgbotapi.NewMessage(chatID, tr(userLang(chatID), "Выберите язык:"))
+	msg.ReplyMarkup = tgbotapi.NewInlineKeyboardMarkup(row)
+	sendMessage(bot, msg)
+}
+
+func handleLangCallback(bot *tgbotapi.BotAPI, query *tgbotapi.CallbackQuery, chatID int64) {
+	lang, ok := parseLang(strings.TrimPrefix(query.Data, "lang_"))
+	if !ok {
+		logger.Printf("Некорректный callback языка: %s", query.Data)
+		return
+	}
+
+	saveUserLang(chatID, lang)
+	logger.LogTelegramAction("Выбран язык", map[string]interface{}{
+		"ChatID": chatID,
+		"Язык":   lang,
+	})
+
+	sendMessage(bot, tgbotapi.NewMessage(chatID, tr(lang, "Язык переключен на русский.")))
+}
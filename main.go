@@ -8,8 +8,10 @@ import (
 	"fmt"
 	"io"
 	"log"
+	"math"
 	"net/http"
 	"os"
+	"slices"
 	"strconv"
 	"strings"
 	"time"
@@ -23,28 +25,55 @@ import (
 	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
 )
 
-type Scores struct {
-	OnPrem  int `json:"on_prem"`
-	Private int `json:"private"`
-	Public  int `json:"public"`
-}
-
+// Criterion — критерий каталога. Name — русское название, по которому критерий указывается
+// в запросах и сохраненных ответах; ID — стабильный идентификатор, Translations — переводы
+// текстов критерия по языкам.
 type Criterion struct {
-	Name        string `json:"name"`
-	Category    string `json:"category"`
-	BaseScores  Scores `json:"base_scores"`
-	Description string `json:"description"`
-	IsSpecial   bool   `json:"is_special"`
+	ID             string                 `json:"id"`
+	Name           string                 `json:"name"`
+	Category       string                 `json:"category"`
+	BaseScores     Scores                 `json:"base_scores"`
+	Description    string                 `json:"description"`
+	IsSpecial      bool                   `json:"is_special"`
+	SpecialPrompt  string                 `json:"special_prompt,omitempty"`
+	SpecialOptions []SpecialOption        `json:"special_options,omitempty"`
+	Translations   map[Lang]CriterionText `json:"translations,omitempty"`
 }
 
-type UserInputData struct {
-	CriteriaPriorities map[string]int    `json:"criteria_priorities"`
-	OverriddenScores   map[string]Scores `json:"overridden_scores"`
-	SpecialValues      map[string]string `json:"special_values"`
+// inputToRequest восстанавливает запрос на расчет из сохраненного ввода. В старых записях
+// нет списка выбранных критериев, поэтому он берется из приоритетов в порядке каталога.
+func inputToRequest(d UserInputData) RecommendationRequest {
+	selected := d.SelectedCriteria
+	if len(selected) == 0 {
+		for _, crit := range defaultCriteria {
+			if _, ok := d.CriteriaPriorities[crit.Name]; ok {
+				selected = append(selected, crit.Name)
+			}
+		}
+	}
+
+	if len(selected) == 0 {
+		for _, crit := range defaultCriteria {
+			if _, ok := d.CriteriaWeights[crit.Name]; ok {
+				selected = append(selected, crit.Name)
+			}
+		}
+	}
+
+	return RecommendationRequest{
+		SelectedCriteria:   selected,
+		CriteriaPriorities: d.CriteriaPriorities,
+		OverriddenScores:   d.OverriddenScores,
+		SpecialValues:      d.SpecialValues,
+		CriteriaWeights:    d.CriteriaWeights,
+		Cost:               d.Cost,
+		TieBreakAnswer:     d.TieBreakAnswer,
+		FollowUpAnswers:    d.FollowUpAnswers,
+	}
 }
 
 type UserState struct {
-	Step               int
+	State              WizardState
 	SelectedCriteria   []string
 	CriteriaPriorities map[string]int
 	OverriddenScores   map[string]Scores
@@ -57,97 +86,275 @@ type UserState struct {
 	TempOverride       Scores
 	OverrideMessageID  int
 	CurrentOverride    string
+	PendingAnswerID    int64
+	CompareAnswerID    int64
+	Weighting          string
+	CriteriaWeights    map[string]float64
+	AHPJudgments       []AHPJudgment
+	AHPMessageID       int
+	VendorOption       string
+	VendorPriorities   map[string]int
+	Cost               *CostInput
+	CostMessageID      int
+	TieBreakQuestion   *TieBreakQuestion
+	TieBreakAnswer     string
+	FollowUpAnswers    map[string]string
+	// History — состояния, в которых чеклист ждал ответа, по порядку; используется для шага назад.
+	History []WizardState
 }
 
 var (
-	botToken        = os.Getenv("BOT_TOKEN")
-	host            = "rc1d-7vowbk5nhczg7plw.mdb.yandexcloud.net"
-	port            = 6432
-	user            = "mvpshe"
-	password        = os.Getenv("DB_PASSWORD")
-	dbname          = "db"
-	ca              = "/etc/ssl/certs/root.crt"
-	userStates      = make(map[int64]*UserState)
-	defaultCriteria = getDefaultCriteria()
-	logger          *CustomLogger
-	pool            *pgxpool.Pool
+	botToken          = os.Getenv("BOT_TOKEN")
+	host              = "rc1d-7vowbk5nhczg7plw.mdb.yandexcloud.net"
+	port              = 6432
+	user              = "mvpshe"
+	password          = os.Getenv("DB_PASSWORD")
+	dbname            = "db"
+	ca                = "/etc/ssl/certs/root.crt"
+	userStates        = make(map[int64]*UserState)
+	priorityScale     = PriorityScale{Min: 1, Max: 5}
+	deploymentOptions = getDefaultDeploymentOptions()
+	defaultCriteria   = getDefaultCriteria()
+	vetoRules         = getDefaultVetoRules()
+	logger            *CustomLogger
+	pool              *pgxpool.Pool
 )
 
 func getDefaultCriteria() []Criterion {
 	return []Criterion{
 		{
+			ID:          "data_jurisdiction",
 			Name:        "Юрисдикция данных",
 			Category:    "Регуляторные и безопасность",
-			BaseScores:  Scores{OnPrem: 8, Private: 5, Public: 4},
+			BaseScores:  Scores{"on_prem": 8, "private": 5, "public": 4},
 			Description: "Насколько важна локализация данных и соответствие местным законам.",
+			Translations: map[Lang]CriterionText{
+				langEN: {
+					Name:        "Data jurisdiction",
+					Category:    "Regulatory and security",
+					Description: "How important data localization and compliance with local laws are.",
+				},
+			},
 		},
 		{
+			ID:          "industry_standards",
 			Name:        "Отраслевые стандарты",
 			Category:    "Регуляторные и безопасность",
-			BaseScores:  Scores{OnPrem: 9, Private: 8, Public: 5},
+			BaseScores:  Scores{"on_prem": 9, "private": 8, "public": 5},
 			Description: "Требования к сертификации и соответствию отраслевым нормам.",
+			Translations: map[Lang]CriterionText{
+				langEN: {
+					Name:        "Industry standards",
+					Category:    "Regulatory and security",
+					Description: "Certification and industry compliance requirements.",
+				},
+			},
 		},
 		{
+			ID:          "physical_security",
 			Name:        "Физическая безопасность",
 			Category:    "Регуляторные и безопасность",
-			BaseScores:  Scores{OnPrem: 5, Private: 4, Public: 3},
+			BaseScores:  Scores{"on_prem": 5, "private": 4, "public": 3},
 			Description: "Насколько важно физическое расположение серверов и меры их защиты.",
+			Translations: map[Lang]CriterionText{
+				langEN: {
+					Name:        "Physical security",
+					Category:    "Regulatory and security",
+					Description: "How important the physical location of servers and their protection are.",
+				},
+			},
 		},
 		{
-			Name:        "Объём данных",
-			Category:    "Технические",
-			BaseScores:  Scores{OnPrem: 0, Private: 0, Public: 0},
-			Description: "Объём хранимых данных (зависит от масштаба).",
-			IsSpecial:   true,
+			ID:            "data_volume",
+			Name:          "Объём данных",
+			Category:      "Технические",
+			Description:   "Объём хранимых данных (зависит от масштаба).",
+			IsSpecial:     true,
+			SpecialPrompt: "Укажите объем данных:",
+			SpecialOptions: []SpecialOption{
+				{
+					Value:       "Малый",
+					Description: "до 100 ГБ данных (несколько таблиц, тысячи-миллионы записей)",
+					Scores:      Scores{"on_prem": 8, "private": 7, "public": 9},
+					Translations: map[Lang]SpecialOptionText{
+						langEN: {Value: "Small", Description: "up to 100 GB of data (a few tables, thousands to millions of rows)"},
+					},
+				},
+				{
+					Value:       "Средний",
+					Description: "от 100 ГБ до 1 ТБ (множество таблиц, миллионы-миллиарды записей)",
+					Scores:      Scores{"on_prem": 6, "private": 8, "public": 9},
+					Translations: map[Lang]SpecialOptionText{
+						langEN: {Value: "Medium", Description: "100 GB to 1 TB (many tables, millions to billions of rows)"},
+					},
+				},
+				{
+					Value:       "Большой",
+					Description: "более 1 ТБ (сложная структура, миллиарды записей и выше)",
+					Scores:      Scores{"on_prem": 4, "private": 8, "public": 9},
+					Translations: map[Lang]SpecialOptionText{
+						langEN: {Value: "Large", Description: "over 1 TB (complex schema, billions of rows and more)"},
+					},
+				},
+			},
+			Translations: map[Lang]CriterionText{
+				langEN: {
+					Name:          "Data volume",
+					Category:      "Technical",
+					Description:   "Volume of stored data (depends on scale).",
+					SpecialPrompt: "Specify the data volume:",
+				},
+			},
 		},
 		{
+			ID:          "latency",
 			Name:        "Латентность",
 			Category:    "Технические",
-			BaseScores:  Scores{OnPrem: 8, Private: 6, Public: 5},
+			BaseScores:  Scores{"on_prem": 8, "private": 6, "public": 5},
 			Description: "Требования к задержкам при доступе к данным.",
+			Translations: map[Lang]CriterionText{
+				langEN: {
+					Name:        "Latency",
+					Category:    "Technical",
+					Description: "Requirements for data access latency.",
+				},
+			},
 		},
 		{
+			ID:          "load_variability",
 			Name:        "Вариативность нагрузки",
 			Category:    "Технические",
-			BaseScores:  Scores{OnPrem: 9, Private: 8, Public: 8},
+			BaseScores:  Scores{"on_prem": 9, "private": 8, "public": 8},
 			Description: "Насколько часто и сильно меняется нагрузка на БД.",
+			Translations: map[Lang]CriterionText{
+				langEN: {
+					Name:        "Load variability",
+					Category:    "Technical",
+					Description: "How often and how much the database load changes.",
+				},
+			},
 		},
 		{
+			ID:          "initial_investment",
 			Name:        "Начальные инвестиции",
 			Category:    "Экономические",
-			BaseScores:  Scores{OnPrem: 3, Private: 4, Public: 8},
+			BaseScores:  Scores{"on_prem": 3, "private": 4, "public": 8},
 			Description: "Начальные затраты на развёртывание.",
+			Translations: map[Lang]CriterionText{
+				langEN: {
+					Name:        "Initial investment",
+					Category:    "Economic",
+					Description: "Upfront deployment costs.",
+				},
+			},
 		},
 		{
+			ID:          "running_costs",
 			Name:        "Постоянные затраты",
 			Category:    "Экономические",
-			BaseScores:  Scores{OnPrem: 7, Private: 8, Public: 9},
+			BaseScores:  Scores{"on_prem": 7, "private": 8, "public": 9},
 			Description: "Регулярные расходы на поддержку, лицензии и т.д.",
+			Translations: map[Lang]CriterionText{
+				langEN: {
+					Name:        "Running costs",
+					Category:    "Economic",
+					Description: "Recurring costs of support, licenses, etc.",
+				},
+			},
 		},
 		{
-			Name:        "Срок использования",
-			Category:    "Экономические",
-			BaseScores:  Scores{OnPrem: 0, Private: 0, Public: 0},
-			Description: "Как долго планируется использовать систему (зависит от срока).",
-			IsSpecial:   true,
+			ID:            "usage_term",
+			Name:          "Срок использования",
+			Category:      "Экономические",
+			Description:   "Как долго планируется использовать систему (зависит от срока).",
+			IsSpecial:     true,
+			SpecialPrompt: "Укажите планируемый срок использования:",
+			SpecialOptions: []SpecialOption{
+				{
+					Value:       "Краткосрочный",
+					Description: "до 1-2 лет (временные проекты, эксперименты)",
+					Scores:      Scores{"on_prem": 4, "private": 6, "public": 9},
+					Translations: map[Lang]SpecialOptionText{
+						langEN: {Value: "Short-term", Description: "up to 1-2 years (temporary projects, experiments)"},
+					},
+				},
+				{
+					Value:       "Долгосрочный",
+					Description: "от 3 лет и более (постоянные, долгосрочные системы)",
+					Scores:      Scores{"on_prem": 9, "private": 7, "public": 6},
+					Translations: map[Lang]SpecialOptionText{
+						langEN: {Value: "Long-term", Description: "3 years or more (permanent, long-lived systems)"},
+					},
+				},
+			},
+			Translations: map[Lang]CriterionText{
+				langEN: {
+					Name:          "Usage term",
+					Category:      "Economic",
+					Description:   "How long the system is planned to be used (depends on the term).",
+					SpecialPrompt: "Specify the planned usage term:",
+				},
+			},
 		},
 		{
+			ID:          "staff_expertise",
 			Name:        "Квалификация персонала",
 			Category:    "Организационные",
-			BaseScores:  Scores{OnPrem: 7, Private: 8, Public: 9},
+			BaseScores:  Scores{"on_prem": 7, "private": 8, "public": 9},
 			Description: "Есть ли в команде экспертиза по управлению и настройке БД.",
+			Translations: map[Lang]CriterionText{
+				langEN: {
+					Name:        "Staff expertise",
+					Category:    "Organizational",
+					Description: "Whether the team has expertise in administering and tuning databases.",
+				},
+			},
 		},
 		{
+			ID:          "time_to_launch",
 			Name:        "Время до запуска",
 			Category:    "Организационные",
-			BaseScores:  Scores{OnPrem: 8, Private: 9, Public: 9},
+			BaseScores:  Scores{"on_prem": 8, "private": 9, "public": 9},
 			Description: "Насколько быстро нужно развернуть систему.",
+			Translations: map[Lang]CriterionText{
+				langEN: {
+					Name:        "Time to launch",
+					Category:    "Organizational",
+					Description: "How quickly the system needs to be deployed.",
+				},
+			},
 		},
 		{
+			ID:          "scalability",
 			Name:        "Масштабируемость",
 			Category:    "Организационные",
-			BaseScores:  Scores{OnPrem: 7, Private: 9, Public: 9},
+			BaseScores:  Scores{"on_prem": 7, "private": 9, "public": 9},
 			Description: "Требования к быстрому масштабированию под нагрузку.",
+			Translations: map[Lang]CriterionText{
+				langEN: {
+					Name:        "Scalability",
+					Category:    "Organizational",
+					Description: "Requirements for scaling quickly with the load.",
+				},
+			},
+		},
+	}
+}
+
+// getDefaultVetoRules задает жесткие ограничения каталога: варианты, которые исключаются
+// из рассмотрения независимо от набранных баллов.
+func getDefaultVetoRules() []VetoRule {
+	return []VetoRule{
+		{
+			ID:          "jurisdiction_on_own_hardware",
+			Criterion:   "Юрисдикция данных",
+			MinPriority: priorityScale.Max,
+			MinWeight:   0.25,
+			Exclude:     []string{"public"},
+			Reason:      "Данные должны оставаться в РФ на собственном оборудовании.",
+			Translations: map[Lang]VetoRuleText{
+				langEN: {Reason: "Data must stay in Russia on own hardware."},
+			},
 		},
 	}
 }
@@ -201,7 +408,8 @@ func initDB() {
 		gpt_answer TEXT,
 		equal BOOLEAN DEFAULT FALSE,
 		created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP
-	);`
+	);
+	ALTER TABLE answers ADD COLUMN IF NOT EXISTS outcome JSONB;`
 
 	_, err = pool.Exec(context.Background(), createTableSQL)
 	if err != nil {
@@ -210,36 +418,84 @@ func initDB() {
 	} else {
 		logger.Printf("Таблица 'answers' успешно проверена/создана.")
 	}
-}
 
-type RecommendationRequest struct {
-	SelectedCriteria   []string          `json:"selected_criteria"`
-	CriteriaPriorities map[string]int    `json:"criteria_priorities"`
-	OverriddenScores   map[string]Scores `json:"overridden_scores"`
-	SpecialValues      map[string]string `json:"special_values"`
-}
+	createProfilesSQL := `
+	CREATE TABLE IF NOT EXISTS profiles (
+		id SERIAL PRIMARY KEY,
+		user_id BIGINT NOT NULL,
+		name TEXT NOT NULL,
+		user_input JSONB NOT NULL,
+		created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+		UNIQUE (user_id, name)
+	);`
 
-type CriterionDetail struct {
-	Name            string `json:"name"`
-	Priority        int    `json:"priority"`
-	Source          string `json:"source"`
-	OnPremScore     int    `json:"on_prem_score"`
-	PrivateScore    int    `json:"private_score"`
-	PublicScore     int    `json:"public_score"`
-	OnPremWeighted  int    `json:"on_prem_weighted"`
-	PrivateWeighted int    `json:"private_weighted"`
-	PublicWeighted  int    `json:"public_weighted"`
-}
+	_, err = pool.Exec(context.Background(), createProfilesSQL)
+	if err != nil {
+		logger.Printf("Ошибка создания таблицы 'profiles': %v", err)
+		log.Fatalf("Не удалось создать таблицу 'profiles': %v", err)
+	} else {
+		logger.Printf("Таблица 'profiles' успешно проверена/создана.")
+	}
+
+	createUserSettingsSQL := `
+	CREATE TABLE IF NOT EXISTS user_settings (
+		user_id BIGINT PRIMARY KEY,
+		lang TEXT NOT NULL
+	);`
+
+	_, err = pool.Exec(context.Background(), createUserSettingsSQL)
+	if err != nil {
+		logger.Printf("Ошибка создания таблицы 'user_settings': %v", err)
+		log.Fatalf("Не удалось создать таблицу 'user_settings': %v", err)
+	} else {
+		logger.Printf("Таблица 'user_settings' успешно проверена/создана.")
+	}
 
-type RecommendationResponse struct {
-	OnPremTotal    int               `json:"on_prem_total"`
-	PrivateTotal   int               `json:"private_total"`
-	PublicTotal    int               `json:"public_total"`
-	Recommendation string            `json:"recommendation"`
-	Details        []CriterionDetail `json:"details"`
-	AIAnalysis     string            `json:"ai_analysis,omitempty"`
+	createAPIKeysSQL := `
+	CREATE TABLE IF NOT EXISTS api_keys (
+		id SERIAL PRIMARY KEY,
+		name TEXT NOT NULL,
+		key_hash TEXT NOT NULL UNIQUE,
+		prefix TEXT NOT NULL,
+		rate_per_minute INTEGER NOT NULL,
+		daily_quota INTEGER NOT NULL,
+		created_by BIGINT NOT NULL,
+		created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+		last_used_at TIMESTAMP WITH TIME ZONE,
+		revoked_at TIMESTAMP WITH TIME ZONE
+	);
+	CREATE TABLE IF NOT EXISTS api_key_usage (
+		key_id INTEGER NOT NULL REFERENCES api_keys(id),
+		day DATE NOT NULL,
+		requests INTEGER NOT NULL,
+		PRIMARY KEY (key_id, day)
+	);
+	ALTER TABLE answers ADD COLUMN IF NOT EXISTS api_key_id INTEGER REFERENCES api_keys(id);
+	ALTER TABLE answers ALTER COLUMN user_id DROP NOT NULL;
+	ALTER TABLE profiles ADD COLUMN IF NOT EXISTS api_key_id INTEGER REFERENCES api_keys(id);
+	ALTER TABLE profiles ALTER COLUMN user_id DROP NOT NULL;
+	CREATE UNIQUE INDEX IF NOT EXISTS profiles_api_key_id_name_key ON profiles (api_key_id, name);`
+
+	_, err = pool.Exec(context.Background(), createAPIKeysSQL)
+	if err != nil {
+		logger.Printf("Ошибка создания таблиц ключей API: %v", err)
+		log.Fatalf("Не удалось создать таблицы ключей API: %v", err)
+	} else {
+		logger.Printf("Таблицы 'api_keys' и 'api_key_usage' успешно проверены/созданы.")
+	}
 }
 
+// Источники баллов критерия в CriterionDetail.Source; для специальных критериев
+// источник — "специальный (<значение>)".
+const (
+	sourceBase       = "базовый"
+	sourceOverridden = "переопределенный"
+	sourceCost       = "расчет TCO"
+)
+
+// priorityButtonsPerRow ограничивает ширину клавиатуры приоритетов для длинных шкал.
+const priorityButtonsPerRow = 5
+
 func main() {
 	logger = NewLogger(true)
 
@@ -250,6 +506,72 @@ func main() {
 		log.Fatal("Переменная окружения DB_PASSWORD не установлена.")
 	}
 
+	if path := os.Getenv("CATALOG_FILE"); path != "" {
+		if err := loadCatalogFile(path); err != nil {
+			log.Fatalf("Не удалось загрузить каталог %s: %v", path, err)
+		}
+		logger.Printf("Каталог загружен из %s: вариантов %d, критериев %d", path, len(deploymentOptions), len(defaultCriteria))
+	}
+	if policy := os.Getenv("TIE_BREAK_POLICY"); policy != "" {
+		if !isKnownTieBreakPolicy(policy) {
+			log.Fatalf("Неизвестное правило разрешения ничьей %q, допустимые значения: %s",
+				policy, strings.Join(tieBreakPolicies, ", "))
+		}
+		tieBreakPolicy = policy
+		logger.Printf("Правило разрешения ничьей: %s", policy)
+	}
+	if path := os.Getenv("PRICE_SHEET_FILE"); path != "" {
+		if err := loadPriceSheetFile(path); err != nil {
+			log.Fatalf("Не удалось загрузить прайс-лист %s: %v", path, err)
+		}
+		logger.Printf("Прайс-лист загружен из %s", path)
+	}
+	if value := os.Getenv("CATALOG_HIDE_SCORES"); value != "" {
+		hide, err := strconv.ParseBool(value)
+		if err != nil {
+			log.Fatalf("Некорректное значение CATALOG_HIDE_SCORES %q: %v", value, err)
+		}
+		hideCatalogScores = hide
+	}
+	if value := os.Getenv("API_LEGACY_SUNSET"); value != "" {
+		sunset, err := time.Parse("2006-01-02", value)
+		if err != nil {
+			log.Fatalf("Некорректное значение API_LEGACY_SUNSET %q, ожидается дата ГГГГ-ММ-ДД: %v", value, err)
+		}
+		legacyAPISunset = sunset
+	}
+	if value := os.Getenv("API_KEYS_REQUIRED"); value != "" {
+		required, err := strconv.ParseBool(value)
+		if err != nil {
+			log.Fatalf("Некорректное значение API_KEYS_REQUIRED %q: %v", value, err)
+		}
+		apiKeysRequired = required
+	}
+	if value := os.Getenv("API_LEGACY_KEYS_REQUIRED"); value != "" {
+		required, err := strconv.ParseBool(value)
+		if err != nil {
+			log.Fatalf("Некорректное значение API_LEGACY_KEYS_REQUIRED %q: %v", value, err)
+		}
+		legacyAPIKeysRequired = required
+	}
+	policy, err := loadCORSPolicy()
+	if err != nil {
+		log.Fatalf("Некорректная настройка CORS: %v", err)
+	}
+	cors = policy
+	if cors.anyOrigin {
+		logger.Printf("CORS: разрешены запросы с любого источника")
+	} else if len(cors.origins) > 0 {
+		logger.Printf("CORS: разрешенные источники: %s", strings.Join(cors.origins, ", "))
+	}
+	if value := os.Getenv("ADMIN_CHAT_IDS"); value != "" {
+		ids, err := parseAdminChatIDs(value)
+		if err != nil {
+			log.Fatalf("Некорректное значение ADMIN_CHAT_IDS: %v", err)
+		}
+		adminChatIDs = ids
+	}
+
 	initDB()
 	defer pool.Close()
 
@@ -270,73 +592,108 @@ func main() {
 	updates := bot.GetUpdatesChan(u)
 
 	for update := range updates {
-		if update.Message != nil {
-			chatID := update.Message.Chat.ID
-			text := update.Message.Text
-
-			logger.LogTelegramAction("Получено сообщение", map[string]interface{}{
-				"ID чата": chatID,
-				"Текст":   text,
-				"От":      update.Message.From.UserName,
-			})
+		stateMu.Lock()
+		handleUpdate(bot, update)
+		flushOutbox()
+		finishResults()
+		stateMu.Unlock()
+	}
+}
 
-			if userStates[chatID] == nil {
-				userStates[chatID] = &UserState{
-					Step:               0,
-					SelectedCriteria:   []string{},
-					CriteriaPriorities: make(map[string]int),
-					OverriddenScores:   make(map[string]Scores),
-					SpecialValues:      make(map[string]string),
-				}
-			}
+// handleUpdate обрабатывает сообщение или нажатие кнопки Telegram. Вызывается под stateMu;
+// запросы к Telegram копятся в outbox и выполняются flushOutbox уже без блокировки.
+func handleUpdate(bot *tgbotapi.BotAPI, update tgbotapi.Update) {
+	if update.Message != nil {
+		chatID := update.Message.Chat.ID
+		text := update.Message.Text
+
+		logger.LogTelegramAction("Получено сообщение", map[string]interface{}{
+			"ID чата": chatID,
+			"Текст":   text,
+			"От":      update.Message.From.UserName,
+		})
 
-			if text == "/start" {
-				msg := tgbotapi.NewMessage(chatID, "Привет! Я бот для выбора типа СУБД (On-Premise, Private, Public). Давайте начнём чеклист.")
-				userStates[chatID] = &UserState{
-					Step:               1,
-					SelectedCriteria:   []string{},
-					CriteriaPriorities: make(map[string]int),
-					OverriddenScores:   make(map[string]Scores),
-					SpecialValues:      make(map[string]string),
-				}
-				sendMessage(bot, msg)
-				showCriteriaButtons(bot, chatID)
-			} else if text == "/reset" {
-				userStates[chatID] = &UserState{
-					Step:               1,
-					SelectedCriteria:   []string{},
-					CriteriaPriorities: make(map[string]int),
-					OverriddenScores:   make(map[string]Scores),
-					SpecialValues:      make(map[string]string),
-				}
-				msg := tgbotapi.NewMessage(chatID, "Чеклист сброшен. Давайте начнем заново.")
-				sendMessage(bot, msg)
-				showCriteriaButtons(bot, chatID)
-			} else if state := userStates[chatID]; state.Step == 5 {
-				msg := tgbotapi.NewMessage(chatID, "Пожалуйста, используйте кнопки для переопределения весов.")
-				sendMessage(bot, msg)
-				showOverrideCriteriaList(bot, chatID)
-			}
-		} else if update.CallbackQuery != nil {
-			chatID := update.CallbackQuery.Message.Chat.ID
+		if userStates[chatID] == nil {
+			userStates[chatID] = newUserState()
+		}
+		userStates[chatID].keepMessageIDs(isTelegramMessage)
+		rememberTelegramLang(chatID, update.Message.From)
+		lang := userLang(chatID)
 
-			logCallbackQuery(update.CallbackQuery)
+		if text == "/start" {
+			msg := tgbotapi.NewMessage(chatID, tr(lang, "Привет! Я бот для выбора типа СУБД (%s). Давайте начнём чеклист.", deploymentOptionNames()))
+			userStates[chatID] = newUserState()
+			sendMessage(bot, msg)
+			transition(bot, chatID, stateCriteria)
+		} else if text == "/reset" {
+			userStates[chatID] = newUserState()
+			msg := tgbotapi.NewMessage(chatID, tr(lang, "Чеклист сброшен. Давайте начнем заново."))
+			sendMessage(bot, msg)
+			transition(bot, chatID, stateCriteria)
+		} else if text == "/lang" {
+			showLangSelection(bot, chatID)
+		} else if text == "/history" {
+			showHistory(bot, chatID, 0, 0)
+		} else if text == "/profiles" {
+			showProfiles(bot, chatID)
+		} else if text == "/compare" {
+			showCompareSelection(bot, chatID)
+		} else if text == "/web" {
+			sendHandoffToken(bot, chatID)
+		} else if (text == "/apikey" || strings.HasPrefix(text, "/apikey ")) && isAdmin(chatID) {
+			handleAPIKeyCommand(bot, chatID, text)
+		} else {
+			dispatchText(bot, chatID, text)
+		}
+	} else if update.CallbackQuery != nil {
+		chatID := update.CallbackQuery.Message.Chat.ID
 
-			callback := tgbotapi.NewCallback(update.CallbackQuery.ID, "")
-			_, err := bot.Request(callback)
-			if err != nil {
-				logger.Printf("Ошибка при ответе на callback: %v", err)
-			}
+		logCallbackQuery(update.CallbackQuery)
+		if state := userStates[chatID]; state != nil {
+			state.keepMessageIDs(isTelegramMessage)
+		}
+		rememberTelegramLang(chatID, update.CallbackQuery.From)
 
-			if userStates[chatID] == nil {
-				logger.Printf("Состояние пользователя для chatID %d не найдено!", chatID)
-				msg := tgbotapi.NewMessage(chatID, "Произошла ошибка состояния. Пожалуйста, начните заново с /start.")
-				sendMessage(bot, msg)
-				continue
-			}
+		queueTelegram(bot, tgbotapi.NewCallback(update.CallbackQuery.ID, ""), nil, "Ошибка при ответе на callback")
+
+		if strings.HasPrefix(update.CallbackQuery.Data, "lang_") {
+			handleLangCallback(bot, update.CallbackQuery, chatID)
+			return
+		}
+
+		if strings.HasPrefix(update.CallbackQuery.Data, "hist_") {
+			handleHistoryCallback(bot, update.CallbackQuery, chatID)
+			return
+		}
+
+		if strings.HasPrefix(update.CallbackQuery.Data, "prof_") {
+			handleProfileCallback(bot, update.CallbackQuery, chatID)
+			return
+		}
+
+		if strings.HasPrefix(update.CallbackQuery.Data, "cmp_") {
+			handleCompareCallback(bot, update.CallbackQuery, chatID)
+			return
+		}
+
+		if strings.HasPrefix(update.CallbackQuery.Data, "conf_") {
+			showConfidence(bot, update.CallbackQuery, chatID)
+			return
+		}
 
-			handleCallbackQuery(bot, update.CallbackQuery, chatID)
+		if strings.HasPrefix(update.CallbackQuery.Data, "vend_") {
+			startVendorStage(bot, update.CallbackQuery, chatID)
+			return
+		}
+
+		if userStates[chatID] == nil {
+			logger.Printf("Состояние пользователя для chatID %d не найдено!", chatID)
+			msg := tgbotapi.NewMessage(chatID, tr(userLang(chatID), "Произошла ошибка состояния. Пожалуйста, начните заново с /start."))
+			sendMessage(bot, msg)
+			return
 		}
+
+		handleCallbackQuery(bot, update.CallbackQuery, chatID)
 	}
 }
 
@@ -346,7 +703,7 @@ func startHTTPServer() {
 		port = "8080"
 	}
 
-	http.HandleFunc("/api/recommend", corsMiddleware(recommendHandler))
+	registerAPIRoutes(http.DefaultServeMux)
 
 	logger.Printf("HTTP сервер запущен на порту %s", port)
 	if err := http.ListenAndServe(":"+port, nil); err != nil {
@@ -354,67 +711,49 @@ func startHTTPServer() {
 	}
 }
 
-func corsMiddleware(next http.HandlerFunc) http.HandlerFunc {
-	return func(w http.ResponseWriter, r *http.Request) {
-		w.Header().Set("Access-Control-Allow-Origin", "*")
-		w.Header().Set("Access-Control-Allow-Methods", "POST, OPTIONS")
-		w.Header().Set("Access-Control-Allow-Headers", "Content-Type, Authorization")
-
-		if r.Method == "OPTIONS" {
-			w.WriteHeader(http.StatusOK)
-			return
-		}
-
-		next(w, r)
-	}
-}
-
 func recommendHandler(w http.ResponseWriter, r *http.Request) {
+	lang := requestLang(r)
+	w.Header().Set("Content-Language", string(lang))
+
 	if r.Method != "POST" {
-		http.Error(w, "Метод не поддерживается", http.StatusMethodNotAllowed)
+		http.Error(w, tr(lang, "Метод не поддерживается"), http.StatusMethodNotAllowed)
 		return
 	}
 
 	var req RecommendationRequest
-	err := json.NewDecoder(r.Body).Decode(&req)
-	if err != nil {
-		http.Error(w, "Ошибка парсинга JSON: "+err.Error(), http.StatusBadRequest)
+	if errs := decodeJSONBody(lang, r, &req); len(errs) > 0 {
+		writeValidationErrors(w, lang, errs)
 		return
 	}
+	req.Lang = lang
 
-	if len(req.SelectedCriteria) == 0 {
-		http.Error(w, "Необходимо выбрать хотя бы один критерий", http.StatusBadRequest)
+	if err := legacyCriterionNames(w, r, lang, func(m, vm *keyMapper) { mapRequestCriteria(&req, m, vm) }); err != nil {
+		writeValidationErrors(w, lang, []FieldError{{Message: err.Error()}})
+		return
+	}
+	if errs := validateRecommendationRequest(lang, "", &req); len(errs) > 0 {
+		writeValidationErrors(w, lang, errs)
 		return
 	}
 
 	response, err := calculateRecommendation(req)
 	if err != nil {
-		http.Error(w, "Ошибка при расчете рекомендации: "+err.Error(), http.StatusInternalServerError)
+		http.Error(w, tr(lang, "Ошибка при расчете рекомендации: ")+err.Error(), http.StatusInternalServerError)
 		return
 	}
 
 	userInputJSON, err := json.Marshal(req)
 	if err == nil && pool != nil {
-		insertSQL := `INSERT INTO answers (user_id, user_input, algorithm_result, gpt_answer, equal) 
-                      VALUES ($1, $2, $3, $4, $5)`
-
-		equal := false
-		if response.AIAnalysis != "" {
-			simpleRec := strings.Split(response.Recommendation, " ")[0]
-			if strings.Contains(strings.ToLower(response.AIAnalysis), strings.ToLower(simpleRec)) {
-				equal = true
-			}
-			if strings.HasPrefix(response.Recommendation, "Требуется") {
-				equal = false
-			}
-		}
+		insertSQL := `INSERT INTO answers (user_id, api_key_id, user_input, algorithm_result, outcome, gpt_answer, equal) 
+                      VALUES (NULL, $1, $2, $3, $4, $5, $6)`
 
 		_, err = pool.Exec(context.Background(), insertSQL,
-			0, // API запрос, используем 0 как идентификатор
+			apiKeyIDOf(apiClientFrom(r)), // API запрос: вместо пользователя — ключ клиента
 			userInputJSON,
 			response.Recommendation,
+			response.Outcome,
 			response.AIAnalysis,
-			equal)
+			aiAgrees(response, response.AIAnalysis))
 
 		if err != nil {
 			logger.Printf("Ошибка сохранения API результата в БД: %v", err)
@@ -427,12 +766,76 @@ func recommendHandler(w http.ResponseWriter, r *http.Request) {
 }
 
 func calculateRecommendation(req RecommendationRequest) (*RecommendationResponse, error) {
-	onPremTotal := 0
-	privateTotal := 0
-	publicTotal := 0
+	response := evaluateRecommendation(req)
+
+	// Данные для LLM передаются по-русски, как и промпт; язык ответа задается отдельно.
+	var detailsMsg strings.Builder
+	for _, detail := range response.Details {
+		detailsMsg.WriteString(fmt.Sprintf("Критерий: %s\n", detail.Name))
+		detailsMsg.WriteString(fmt.Sprintf("  %s\n", formatDetailWeight(langRU, detail)))
+		detailsMsg.WriteString(fmt.Sprintf("  С учетом приоритета: %s\n\n", formatScores(detail.Weighted)))
+	}
+	if len(response.Excluded) > 0 {
+		detailsMsg.WriteString(formatExclusions(langRU, response.Excluded))
+	}
+
+	aiAnalysis, err := getAISuggestions(detailsMsg.String(), response.Language)
+	if err == nil {
+		response.AIAnalysis = aiAnalysis
+	} else {
+		logger.Printf("Ошибка получения AI рекомендации: %v", err)
+	}
+
+	if resolveTieWithAI(response, response.AIAnalysis) {
+		finishRecommendation(response, req)
+	}
+
+	return response, nil
+}
+
+// evaluateRecommendation считает баллы по выбранным критериям без обращения к AI.
+func evaluateRecommendation(req RecommendationRequest) *RecommendationResponse {
+	if req.Lang == "" {
+		req.Lang = defaultLang
+	}
+	totals := make([]float64, len(deploymentOptions))
 
 	details := make([]CriterionDetail, 0, len(req.SelectedCriteria))
 
+	var estimate *CostEstimate
+	var derivedScores map[string]Scores
+	if req.Cost != nil && priceSheet != nil {
+		estimate = estimateCost(*req.Cost)
+		if req.Cost.ApplyToScores {
+			derivedScores = economicScores(estimate)
+		}
+	}
+
+	followUpResults, followUpAnswers := evaluateFollowUps(req)
+
+	weights := req.CriteriaWeights
+	var ahp *AHPResult
+	if len(req.AHPJudgments) > 0 {
+		ahp = computeAHP(req.SelectedCriteria, req.AHPJudgments)
+		weights = ahp.Weights
+	}
+
+	// Веса критериев нормируются: приоритеты (или заданные напрямую веса) делятся на их сумму.
+	direct := len(weights) > 0
+	weightSum := 0.0
+	for _, cName := range req.SelectedCriteria {
+		if findCriterionByName(cName).Name == "" {
+			continue
+		}
+		if direct {
+			weightSum += weights[cName]
+		} else if prio, ok := req.CriteriaPriorities[cName]; ok {
+			weightSum += float64(prio)
+		} else {
+			weightSum += float64(priorityScale.Min)
+		}
+	}
+
 	for _, cName := range req.SelectedCriteria {
 		crit := findCriterionByName(cName)
 		if crit.Name == "" {
@@ -442,120 +845,220 @@ func calculateRecommendation(req RecommendationRequest) (*RecommendationResponse
 
 		prio, prioOk := req.CriteriaPriorities[cName]
 		if !prioOk {
-			prio = 1
+			prio = priorityScale.Min
+		}
+
+		weight := float64(prio)
+		if direct {
+			prio = 0
+			weight = weights[cName]
+		}
+		if weightSum > 0 {
+			weight /= weightSum
 		}
 
 		scores := crit.BaseScores
-		source := "базовый"
+		source := sourceBase
+		specialValue := ""
 
 		if crit.IsSpecial {
 			val, valOk := req.SpecialValues[cName]
 			if valOk {
 				scores = getScoresForSpecialCriterion(crit.Name, val)
 				source = fmt.Sprintf("специальный (%s)", val)
+				specialValue = val
 			}
 		}
 
-		if overridden, ok := req.OverriddenScores[cName]; ok {
-			scores = overridden
-			source = "переопределенный"
+		if derived, ok := derivedScores[cName]; ok {
+			scores = derived
+			source = sourceCost
 		}
 
-		onPremWeighted := scores.OnPrem * prio
-		privateWeighted := scores.Private * prio
-		publicWeighted := scores.Public * prio
+		var adjustments []string
+		if choices, ok := followUpAnswers[cName]; ok {
+			scores = adjustScores(scores, cName, choices)
+			for _, choice := range choices {
+				adjustments = append(adjustments, formatFollowUpAdjustment(req.Lang, cName, choice))
+			}
+		}
+
+		if overridden, ok := req.OverriddenScores[cName]; ok {
+			scores = mergeScores(scores, overridden)
+			source = sourceOverridden
+		}
 
-		onPremTotal += onPremWeighted
-		privateTotal += privateWeighted
-		publicTotal += publicWeighted
+		values := scoreVector(scores)
+		weightedValues := make([]float64, len(values))
+		for i, v := range values {
+			weightedValues[i] = v * weight
+			totals[i] += weightedValues[i]
+		}
 
 		detail := CriterionDetail{
-			Name:            cName,
-			Priority:        prio,
-			Source:          source,
-			OnPremScore:     scores.OnPrem,
-			PrivateScore:    scores.Private,
-			PublicScore:     scores.Public,
-			OnPremWeighted:  onPremWeighted,
-			PrivateWeighted: privateWeighted,
-			PublicWeighted:  publicWeighted,
+			ID:           crit.ID,
+			Name:         cName,
+			Priority:     prio,
+			Weight:       weight,
+			Source:       source,
+			SpecialValue: specialValue,
+			Adjustments:  adjustments,
+			Scores:       scoresFromVector(values),
+			Weighted:     scoresFromVector(weightedValues),
 		}
+		fillLegacyScores(&detail)
 		details = append(details, detail)
 	}
 
-	var recommendation string
-	if onPremTotal > privateTotal && onPremTotal > publicTotal {
-		recommendation = "On-Premise"
-	} else if privateTotal > onPremTotal && privateTotal > publicTotal {
-		recommendation = "Private Cloud"
-	} else if publicTotal > onPremTotal && publicTotal > privateTotal {
-		recommendation = "Public Cloud"
-	} else {
-		equalOptions := []string{}
-		maxScore := 0
-		if onPremTotal >= maxScore {
-			maxScore = onPremTotal
-		}
-		if privateTotal >= maxScore {
-			maxScore = privateTotal
-		}
-		if publicTotal >= maxScore {
-			maxScore = publicTotal
+	exclusions := applyVetoRules(req.Lang, details, req.SpecialValues)
+	excluded := excludedMask(exclusions)
+
+	response := &RecommendationResponse{
+		Options:   deploymentOptions,
+		Totals:    scoresFromVector(totals),
+		Method:    methodWeightedSum,
+		Details:   details,
+		Excluded:  exclusions,
+		AHP:       ahp,
+		Cost:      estimate,
+		FollowUps: followUpResults,
+		Language:  req.Lang,
+	}
+	setOutcome(response, pickRecommendation(totals, excluded))
+	response.TotalsPercent = make(Scores, len(deploymentOptions))
+	for id, total := range response.Totals {
+		response.TotalsPercent[id] = total * 100 / maxScore
+	}
+	response.OnPremTotal = response.Totals["on_prem"]
+	response.PrivateTotal = response.Totals["private"]
+	response.PublicTotal = response.Totals["public"]
+
+	if req.CompareMethods {
+		for _, method := range decisionMethods {
+			response.Methods = append(response.Methods, evaluateMethod(method, details, excluded))
 		}
+		agree := methodsAgree(response.Methods)
+		response.MethodsAgree = &agree
+	}
 
-		if onPremTotal == maxScore {
-			equalOptions = append(equalOptions, "On-Premise")
+	if req.Method != "" && req.Method != methodWeightedSum {
+		result := evaluateMethod(req.Method, details, excluded)
+		if !req.CompareMethods {
+			response.Methods = []MethodResult{result}
 		}
-		if privateTotal == maxScore {
-			equalOptions = append(equalOptions, "Private Cloud")
+		response.Method = result.Method
+		setOutcome(response, result.Outcome)
+	} else {
+		// Анализ чувствительности построен на итогах взвешенной суммы.
+		response.Sensitivity = analyzeSensitivity(response)
+	}
+	breakTie(response, req.TieBreakAnswer)
+	if req.Uncertainty != nil {
+		response.Uncertainty = simulateUncertainty(details, *req.Uncertainty, excluded)
+	}
+	finishRecommendation(response, req)
+
+	return response
+}
+
+// finishRecommendation строит объяснение и второй этап по окончательной рекомендации.
+func finishRecommendation(response *RecommendationResponse, req RecommendationRequest) {
+	response.Explanation = explainRecommendation(response)
+
+	response.Vendors = nil
+	if req.Vendors != nil {
+		if option := vendorStageOption(req.Vendors, response.Outcome); option != "" {
+			response.Vendors = evaluateVendors(response.Language, option, req.Vendors.CriteriaPriorities)
 		}
-		if publicTotal == maxScore {
-			equalOptions = append(equalOptions, "Public Cloud")
+	}
+}
+
+// fillLegacyScores заполняет поля совместимости для трех стандартных вариантов.
+func fillLegacyScores(d *CriterionDetail) {
+	d.OnPremScore, d.OnPremWeighted = d.Scores["on_prem"], d.Weighted["on_prem"]
+	d.PrivateScore, d.PrivateWeighted = d.Scores["private"], d.Weighted["private"]
+	d.PublicScore, d.PublicWeighted = d.Scores["public"], d.Weighted["public"]
+}
+
+// pickRecommendation выбирает допустимый вариант с наибольшим итогом; при равенстве лидеров
+// возвращает их всех в Tied.
+func pickRecommendation(totals []float64, excluded []bool) Outcome {
+	maxTotal := math.Inf(-1)
+	for option, total := range totals {
+		if !excluded[option] {
+			maxTotal = math.Max(maxTotal, total)
 		}
+	}
 
-		if len(equalOptions) > 1 {
-			recommendation = "Требуется дополнительная оценка (" + strings.Join(equalOptions, "/") + ")"
-		} else if len(equalOptions) == 1 {
-			recommendation = equalOptions[0]
-		} else {
-			recommendation = "Требуется дополнительная оценка"
+	var equalOptions []string
+	for option, total := range totals {
+		if !excluded[option] && maxTotal-total <= scoreEpsilon {
+			equalOptions = append(equalOptions, deploymentOptions[option].ID)
 		}
 	}
 
-	response := &RecommendationResponse{
-		OnPremTotal:    onPremTotal,
-		PrivateTotal:   privateTotal,
-		PublicTotal:    publicTotal,
-		Recommendation: recommendation,
-		Details:        details,
+	if len(equalOptions) > 1 {
+		return Outcome{Tied: equalOptions}
 	}
+	if len(equalOptions) == 1 {
+		return Outcome{OptionID: equalOptions[0]}
+	}
+	return Outcome{}
+}
 
-	var detailsMsg strings.Builder
-	for _, detail := range details {
-		detailsMsg.WriteString(fmt.Sprintf("Критерий: %s\n", detail.Name))
-		detailsMsg.WriteString(fmt.Sprintf("  Приоритет: %d\n", detail.Priority))
-		detailsMsg.WriteString(fmt.Sprintf("  С учетом приоритета: OnPrem=%d, Private=%d, Public=%d\n\n",
-			detail.OnPremWeighted, detail.PrivateWeighted, detail.PublicWeighted))
+// setOutcome записывает итог в ответ вместе с текстом рекомендации для совместимости.
+func setOutcome(response *RecommendationResponse, outcome Outcome) {
+	response.Outcome = outcome
+	response.Recommendation = recommendationLabel(langRU, outcome)
+}
+
+func sameOutcome(a, b Outcome) bool {
+	return a.OptionID == b.OptionID && slices.Equal(a.Tied, b.Tied)
+}
+
+// optionNamesByID возвращает названия вариантов развертывания по идентификаторам.
+func optionNamesByID(ids []string) []string {
+	names := make([]string, 0, len(ids))
+	for _, id := range ids {
+		if option, ok := findDeploymentOption(id); ok {
+			names = append(names, option.Name)
+		} else {
+			names = append(names, id)
+		}
 	}
+	return names
+}
 
-	aiAnalysis, err := getAISuggestions(detailsMsg.String())
-	if err == nil {
-		response.AIAnalysis = aiAnalysis
-	} else {
-		logger.Printf("Ошибка получения AI рекомендации: %v", err)
+// recommendationLabel выводит итог на языке lang.
+func recommendationLabel(lang Lang, outcome Outcome) string {
+	switch {
+	case outcome.OptionID != "":
+		return optionNamesByID([]string{outcome.OptionID})[0]
+	case len(outcome.Tied) > 0:
+		return tr(lang, "Требуется дополнительная оценка (%s)", strings.Join(optionNamesByID(outcome.Tied), "/"))
+	default:
+		return tr(lang, recommendationNoOptions)
 	}
+}
 
-	return response, nil
+// aiAgrees — рекомендация однозначна и AI называет тот же вариант.
+func aiAgrees(response *RecommendationResponse, aiAnalysis string) bool {
+	if response.Outcome.OptionID == "" || aiAnalysis == "" {
+		return false
+	}
+	simpleRecommendation := strings.Split(recommendationLabel(langRU, response.Outcome), " ")[0]
+	return strings.Contains(strings.ToLower(aiAnalysis), strings.ToLower(simpleRecommendation))
 }
 
 func showCriteriaButtons(bot *tgbotapi.BotAPI, chatID int64) {
 	state := userStates[chatID]
+	lang := userLang(chatID)
 	var keyboardRows [][]tgbotapi.InlineKeyboardButton
 
 	for _, crit := range defaultCriteria {
 		isSelected := contains(state.SelectedCriteria, crit.Name)
 
-		buttonText := crit.Name
+		buttonText := crit.text(lang).Name
 		if isSelected {
 			buttonText = "✓ " + buttonText
 		} else {
@@ -568,7 +1071,7 @@ func showCriteriaButtons(bot *tgbotapi.BotAPI, chatID int64) {
 	}
 
 	keyboardRows = append(keyboardRows, []tgbotapi.InlineKeyboardButton{
-		tgbotapi.NewInlineKeyboardButtonData("✅ Готово", "done_criteria"),
+		tgbotapi.NewInlineKeyboardButtonData(tr(lang, "✅ Готово"), "done_criteria"),
 	})
 
 	keyboard := tgbotapi.NewInlineKeyboardMarkup(keyboardRows...)
@@ -579,100 +1082,53 @@ func showCriteriaButtons(bot *tgbotapi.BotAPI, chatID int64) {
 			state.CriteriaMessageID,
 			keyboard,
 		)
-		if _, err := editMessageReplyMarkup(bot, editMsg); err != nil {
-			logger.Printf("Ошибка обновления сообщения: %v", err)
-		}
+		editMessageReplyMarkup(bot, editMsg)
 	} else {
-		msg := tgbotapi.NewMessage(chatID, "Выберите критерии, которые важны для вашей компании:")
+		msg := tgbotapi.NewMessage(chatID, tr(lang, "Выберите критерии, которые важны для вашей компании:"))
 		msg.ReplyMarkup = keyboard
 
-		sentMsg, err := sendMessage(bot, msg)
-		if err == nil {
-			state.CriteriaMessageID = sentMsg.MessageID
-		} else {
-			logger.Printf("Ошибка отправки сообщения: %v", err)
-		}
+		sendTrackedMessage(bot, msg, &state.CriteriaMessageID)
 	}
 }
 
-func getSpecialValueFromCallback(callbackPrefix string, index int) string {
-	switch callbackPrefix {
-	case "sdata":
-		options := []string{"Малый", "Средний", "Большой"}
-		if index >= 0 && index < len(options) {
-			return options[index]
-		}
-	case "susage":
-		options := []string{"Краткосрочный", "Долгосрочный"}
-		if index >= 0 && index < len(options) {
-			return options[index]
-		}
-	case "sother":
-		options := []string{"Опция 1", "Опция 2", "Опция 3"}
-		if index >= 0 && index < len(options) {
-			return options[index]
-		}
-	}
-	return ""
+func handleCallbackQuery(bot *tgbotapi.BotAPI, query *tgbotapi.CallbackQuery, chatID int64) {
+	dispatchCallback(bot, chatID, query.Data)
 }
 
-func getSpecialCriterionName(callbackPrefix string) string {
-	switch callbackPrefix {
-	case "sdata":
-		return "Объём данных"
-	case "susage":
-		return "Срок использования"
-	case "sother":
-		return "Другой критерий"
+func handleSpecialValueCallback(bot *tgbotapi.BotAPI, chatID int64, callbackData string) {
+	state := userStates[chatID]
+
+	parts := strings.Split(callbackData, "_")
+	if len(parts) != 3 {
+		return
+	}
+	critIndex, critErr := strconv.Atoi(parts[1])
+	optionIndex, optionErr := strconv.Atoi(parts[2])
+	if critErr != nil || optionErr != nil ||
+		critIndex < 0 || critIndex >= len(defaultCriteria) ||
+		optionIndex < 0 || optionIndex >= len(defaultCriteria[critIndex].SpecialOptions) {
+		return
 	}
-	return ""
-}
 
-func handleCallbackQuery(bot *tgbotapi.BotAPI, query *tgbotapi.CallbackQuery, chatID int64) {
-	state := userStates[chatID]
-	callbackData := query.Data
+	criterionName := defaultCriteria[critIndex].Name
+	value := defaultCriteria[critIndex].SpecialOptions[optionIndex].Value
 
-	if strings.HasPrefix(callbackData, "sdata_") ||
-		strings.HasPrefix(callbackData, "susage_") ||
-		strings.HasPrefix(callbackData, "sother_") {
+	state.SpecialValues[criterionName] = value
 
-		parts := strings.Split(callbackData, "_")
-		if len(parts) == 2 {
-			prefix := parts[0]
-			index, err := strconv.Atoi(parts[1])
-
-			if err == nil {
-				criterionName := getSpecialCriterionName(prefix)
-				value := getSpecialValueFromCallback(prefix, index)
-
-				if criterionName != "" && value != "" {
-					state.SpecialValues[criterionName] = value
-
-					logger.LogTelegramAction("Выбрано специальное значение", map[string]interface{}{
-						"Критерий": criterionName,
-						"Значение": value,
-					})
-
-					allSpecialSet := true
-					for _, critName := range state.SelectedCriteria {
-						crit := findCriterionByName(critName)
-						if crit.IsSpecial && state.SpecialValues[critName] == "" {
-							allSpecialSet = false
-							break
-						}
-					}
-
-					if allSpecialSet {
-						state.Step = 4
-						askOverride(bot, chatID)
-					} else {
-						showSpecialCriteriaOptions(bot, chatID)
-					}
-				}
-			}
-		}
-		return
+	logger.LogTelegramAction("Выбрано специальное значение", map[string]interface{}{
+		"Критерий": criterionName,
+		"Значение": value,
+	})
+
+	if hasMissingSpecialValues(state) {
+		showSpecialCriteriaOptions(bot, chatID)
+	} else {
+		transition(bot, chatID, stateFollowUps)
 	}
+}
+
+func handleCriteriaCallback(bot *tgbotapi.BotAPI, chatID int64, callbackData string) {
+	state := userStates[chatID]
 
 	if strings.HasPrefix(callbackData, "crit_") {
 		criterionName := strings.TrimPrefix(callbackData, "crit_")
@@ -695,128 +1151,180 @@ func handleCallbackQuery(bot *tgbotapi.BotAPI, query *tgbotapi.CallbackQuery, ch
 		}
 
 		showCriteriaButtons(bot, chatID)
-	} else if callbackData == "done_criteria" {
-		if len(state.SelectedCriteria) == 0 {
-			msg := tgbotapi.NewMessage(chatID, "Пожалуйста, выберите хотя бы один критерий.")
-			sendMessage(bot, msg)
-			showCriteriaButtons(bot, chatID)
-		} else {
-			logger.LogTelegramAction("Завершен выбор критериев", map[string]interface{}{
-				"Выбрано критериев": len(state.SelectedCriteria),
-				"Критерии":          state.SelectedCriteria,
-			})
-			state.Step = 2
-			startPrioritySelection(bot, chatID)
+		return
+	}
+
+	if len(state.SelectedCriteria) == 0 {
+		msg := tgbotapi.NewMessage(chatID, tr(userLang(chatID), "Пожалуйста, выберите хотя бы один критерий."))
+		sendMessage(bot, msg)
+		showCriteriaButtons(bot, chatID)
+		return
+	}
+
+	logger.LogTelegramAction("Завершен выбор критериев", map[string]interface{}{
+		"Выбрано критериев": len(state.SelectedCriteria),
+		"Критерии":          state.SelectedCriteria,
+	})
+	pruneUnselectedInput(state)
+	transition(bot, chatID, statePriorities)
+}
+
+func handlePriorityCallback(bot *tgbotapi.BotAPI, chatID int64, callbackData string) {
+	state := userStates[chatID]
+
+	if strings.HasPrefix(callbackData, "wmode_") {
+		handleWeightingModeCallback(bot, chatID, callbackData)
+		return
+	}
+
+	parts := strings.Split(callbackData, "_")
+	if len(parts) != 3 {
+		return
+	}
+	criterionName := parts[1]
+	priority, err := strconv.Atoi(parts[2])
+
+	// Кнопка от старого сообщения или подделанные данные: вопрос задается заново.
+	if err != nil || !contains(state.SelectedCriteria, criterionName) || !scaleContains(priorityScale, priority) {
+		logger.Printf("Некорректный приоритет: %s от chatID %d", callbackData, chatID)
+		startPrioritySelection(bot, chatID)
+		return
+	}
+
+	state.CriteriaPriorities[criterionName] = priority
+	logger.LogTelegramAction("Установлен приоритет", map[string]interface{}{
+		"Критерий":  criterionName,
+		"Приоритет": priority,
+	})
+
+	if len(state.CriteriaPriorities) == len(state.SelectedCriteria) {
+		transition(bot, chatID, stateSpecial)
+	} else {
+		startPrioritySelection(bot, chatID)
+	}
+}
+
+func handleOverrideCallback(bot *tgbotapi.BotAPI, chatID int64, callbackData string) {
+	state := userStates[chatID]
+
+	switch {
+	case callbackData == "override_yes":
+		transition(bot, chatID, stateOverrideEdit)
+	case callbackData == "override_no":
+		logger.LogTelegramAction("Отказ от переопределения баллов", nil)
+		transition(bot, chatID, stateResult)
+	case strings.HasPrefix(callbackData, "override_select_"):
+		criterionName := strings.TrimPrefix(callbackData, "override_select_")
+		showCriterionOverrideOptions(bot, chatID, criterionName)
+	case callbackData == "override_done":
+		transition(bot, chatID, stateResult)
+	case callbackData == "override_cancel":
+		if state != nil {
+			finishOverrideEdit(state)
+		}
+		showOverrideCriteriaList(bot, chatID)
+	case strings.HasPrefix(callbackData, "weight_"):
+		parts := strings.Split(callbackData, "_")
+		if len(parts) != 3 {
+			return
+		}
+		step, errStep := strconv.Atoi(parts[1])
+		value, errValue := strconv.Atoi(parts[2])
+
+		// Кнопка от старого сообщения: редактирование не начато, уже завершено или
+		// идет другой шаг.
+		if state == nil || state.CurrentOverride == "" || state.TempOverride == nil {
+			logger.Printf("Переопределение не начато, callback %s от chatID %d пропущен", callbackData, chatID)
+			return
+		}
+		if errStep != nil || step != state.OverrideStep || step >= len(deploymentOptions) {
+			logger.Printf("Некорректный шаг переопределения: %s (ожидался %d)", callbackData, state.OverrideStep)
+			return
+		}
+		if errValue != nil || value < minScore || value > maxScore {
+			logger.Printf("Некорректный балл переопределения: %s", callbackData)
+			return
 		}
-	} else if strings.HasPrefix(callbackData, "prio_") {
-		parts := strings.Split(callbackData, "_")
-		if len(parts) == 3 {
-			criterionName := parts[1]
-			priority, _ := strconv.Atoi(parts[2])
-
-			state.CriteriaPriorities[criterionName] = priority
-			logger.LogTelegramAction("Установлен приоритет", map[string]interface{}{
-				"Критерий":  criterionName,
-				"Приоритет": priority,
-			})
-
-			if len(state.CriteriaPriorities) == len(state.SelectedCriteria) {
-				hasSpecialCriteria := false
-				for _, critName := range state.SelectedCriteria {
-					crit := findCriterionByName(critName)
-					if crit.IsSpecial {
-						hasSpecialCriteria = true
-						break
-					}
-				}
+		state.TempOverride[deploymentOptions[step].ID] = float64(value)
 
-				if hasSpecialCriteria {
-					state.Step = 3
-					showSpecialCriteriaOptions(bot, chatID)
-				} else {
-					state.Step = 4
-					askOverride(bot, chatID)
-				}
-			} else {
-				startPrioritySelection(bot, chatID)
-			}
+		if step < len(deploymentOptions)-1 {
+			state.OverrideStep++
+			showWeightOptions(bot, chatID, state.CurrentOverride)
+			return
 		}
-	} else if strings.HasPrefix(callbackData, "special_") {
-		parts := strings.Split(callbackData, "_")
-		if len(parts) == 3 {
-			criterionName := parts[1]
-			value := parts[2]
 
-			state.SpecialValues[criterionName] = value
-			logger.LogTelegramAction("Выбрано специальное значение", map[string]interface{}{
-				"Критерий": criterionName,
-				"Значение": value,
-			})
+		if state.OverriddenScores == nil {
+			state.OverriddenScores = make(map[string]Scores)
+		}
+		state.OverriddenScores[state.CurrentOverride] = cloneScores(state.TempOverride)
 
-			allSpecialSet := true
-			for _, critName := range state.SelectedCriteria {
-				crit := findCriterionByName(critName)
-				if crit.IsSpecial && state.SpecialValues[critName] == "" {
-					allSpecialSet = false
-					break
-				}
-			}
+		logger.LogTelegramAction("Баллы переопределены", map[string]interface{}{
+			"Критерий": state.CurrentOverride,
+			"Баллы":    state.TempOverride,
+		})
+		finishOverrideEdit(state)
 
-			if allSpecialSet {
-				state.Step = 4
-				askOverride(bot, chatID)
-			} else {
-				showSpecialCriteriaOptions(bot, chatID)
-			}
-		}
+		showOverrideCriteriaList(bot, chatID)
 	}
+}
 
-	if callbackData == "override_yes" {
-		showOverrideCriteriaList(bot, chatID)
-	} else if strings.HasPrefix(callbackData, "override_select_") {
-		criterionName := strings.TrimPrefix(callbackData, "override_select_")
-		showCriterionOverrideOptions(bot, chatID, criterionName)
-	} else if callbackData == "override_done" {
-		state.Step = 6
-		calcAndShowResult(bot, chatID)
-	} else if callbackData == "override_cancel" {
-		showOverrideCriteriaList(bot, chatID)
-	} else if strings.HasPrefix(callbackData, "weight_") {
-		parts := strings.Split(callbackData, "_")
-		if len(parts) == 3 {
-			step, _ := strconv.Atoi(parts[1])
-			value, _ := strconv.Atoi(parts[2])
-
-			switch step {
-			case 0:
-				state.TempOverride.OnPrem = value
-			case 1:
-				state.TempOverride.Private = value
-			case 2:
-				state.TempOverride.Public = value
-			}
+// finishOverrideEdit сбрасывает редактируемый критерий, чтобы кнопки баллов из старых
+// сообщений больше ничего не меняли.
+func finishOverrideEdit(state *UserState) {
+	state.CurrentOverride = ""
+	state.TempOverride = nil
+	state.OverrideStep = 0
+}
 
-			if step < 2 {
-				state.OverrideStep++
-				showWeightOptions(bot, chatID, state.CurrentOverride)
-			} else {
-				state.OverriddenScores[state.CurrentOverride] = state.TempOverride
+func hasMissingSpecialValues(state *UserState) bool {
+	for _, critName := range state.SelectedCriteria {
+		crit := findCriterionByName(critName)
+		if crit.IsSpecial && state.SpecialValues[critName] == "" {
+			return true
+		}
+	}
+	return false
+}
 
-				logger.LogTelegramAction("Баллы переопределены", map[string]interface{}{
-					"Критерий": state.CurrentOverride,
-					"OnPrem":   state.TempOverride.OnPrem,
-					"Private":  state.TempOverride.Private,
-					"Public":   state.TempOverride.Public,
-				})
+// pruneUnselectedInput удаляет ответы по критериям, которые были сняты с выбора.
+func pruneUnselectedInput(state *UserState) {
+	for name := range state.CriteriaPriorities {
+		if !contains(state.SelectedCriteria, name) {
+			delete(state.CriteriaPriorities, name)
+		}
+	}
+	for name := range state.SpecialValues {
+		if !contains(state.SelectedCriteria, name) {
+			delete(state.SpecialValues, name)
+		}
+	}
+	for name := range state.OverriddenScores {
+		if !contains(state.SelectedCriteria, name) {
+			delete(state.OverriddenScores, name)
+		}
+	}
+	if state.Weighting == weightingAHP && !ahpWeightsComplete(state) {
+		state.CriteriaWeights = nil
+		state.AHPJudgments = nil
+	}
+	pruneFollowUpAnswers(state)
+}
 
-				showOverrideCriteriaList(bot, chatID)
-			}
+// priorityButtonRows строит кнопки шкалы приоритетов, не более priorityButtonsPerRow в ряду.
+func priorityButtonRows(callbackData func(p int) string) [][]tgbotapi.InlineKeyboardButton {
+	var rows [][]tgbotapi.InlineKeyboardButton
+	var row []tgbotapi.InlineKeyboardButton
+	for p := priorityScale.Min; p <= priorityScale.Max; p++ {
+		row = append(row, tgbotapi.NewInlineKeyboardButtonData(fmt.Sprintf("%d", p), callbackData(p)))
+		if len(row) == priorityButtonsPerRow {
+			rows = append(rows, row)
+			row = nil
 		}
-	} else if callbackData == "override_no" {
-		state.Step = 6
-		logger.LogTelegramAction("Отказ от переопределения баллов", nil)
-		calcAndShowResult(bot, chatID)
 	}
+	if len(row) > 0 {
+		rows = append(rows, row)
+	}
+	return rows
 }
 
 func startPrioritySelection(bot *tgbotapi.BotAPI, chatID int64) {
@@ -834,19 +1342,14 @@ func startPrioritySelection(bot *tgbotapi.BotAPI, chatID int64) {
 		return
 	}
 
-	crit := findCriterionByName(criterionToRate)
+	critText := findCriterionByName(criterionToRate).text(userLang(chatID))
 
-	text := fmt.Sprintf("Установите приоритет для критерия:\n\n*%s*\n%s",
-		criterionToRate, crit.Description)
-
-	var keyboardRow []tgbotapi.InlineKeyboardButton
-	for i := 1; i <= 5; i++ {
-		keyboardRow = append(keyboardRow,
-			tgbotapi.NewInlineKeyboardButtonData(fmt.Sprintf("%d", i),
-				fmt.Sprintf("prio_%s_%d", criterionToRate, i)))
-	}
+	text := tr(userLang(chatID), "Установите приоритет для критерия:\n\n*%s*\n%s",
+		critText.Name, critText.Description)
 
-	keyboard := tgbotapi.NewInlineKeyboardMarkup(keyboardRow)
+	keyboard := tgbotapi.NewInlineKeyboardMarkup(priorityButtonRows(func(p int) string {
+		return fmt.Sprintf("prio_%s_%d", criterionToRate, p)
+	})...)
 
 	logger.LogTelegramAction("Запрос приоритета", map[string]interface{}{
 		"Критерий": criterionToRate,
@@ -861,20 +1364,13 @@ func startPrioritySelection(bot *tgbotapi.BotAPI, chatID int64) {
 		)
 		editMsg.ParseMode = "Markdown"
 
-		if _, err := editMessageText(bot, editMsg); err != nil {
-			logger.Printf("Ошибка обновления сообщения приоритета: %v", err)
-		}
+		editMessageText(bot, editMsg)
 	} else {
 		msg := tgbotapi.NewMessage(chatID, text)
 		msg.ParseMode = "Markdown"
 		msg.ReplyMarkup = keyboard
 
-		sentMsg, err := sendMessage(bot, msg)
-		if err == nil {
-			state.PriorityMessageID = sentMsg.MessageID
-		} else {
-			logger.Printf("Ошибка отправки сообщения с приоритетами: %v", err)
-		}
+		sendTrackedMessage(bot, msg, &state.PriorityMessageID)
 	}
 }
 
@@ -894,43 +1390,35 @@ func showSpecialCriteriaOptions(bot *tgbotapi.BotAPI, chatID int64) {
 		return
 	}
 
-	var options []string
-	var callbackPrefix string
-	var msgText string
-
-	if criterionToSpecify == "Объём данных" {
-		options = []string{"Малый", "Средний", "Большой"}
-		callbackPrefix = "sdata"
-		msgText = "Укажите объем данных:\n\n" +
-			"• *Малый* — до 100 ГБ данных (несколько таблиц, тысячи-миллионы записей)\n" +
-			"• *Средний* — от 100 ГБ до 1 ТБ (множество таблиц, миллионы-миллиарды записей)\n" +
-			"• *Большой* — более 1 ТБ (сложная структура, миллиарды записей и выше)"
-	} else if criterionToSpecify == "Срок использования" {
-		options = []string{"Краткосрочный", "Долгосрочный"}
-		callbackPrefix = "susage"
-		msgText = "Укажите планируемый срок использования:\n\n" +
-			"• *Краткосрочный* — до 1-2 лет (временные проекты, эксперименты)\n" +
-			"• *Долгосрочный* — от 3 лет и более (постоянные, долгосрочные системы)"
-	} else {
-		options = []string{"Опция 1", "Опция 2", "Опция 3"}
-		callbackPrefix = "sother"
-		msgText = fmt.Sprintf("Укажите значение для '%s':", criterionToSpecify)
-	}
+	critIndex := findCriterionIndex(criterionToSpecify)
+	crit := defaultCriteria[critIndex]
+	lang := userLang(chatID)
+	critText := crit.text(lang)
 
-	logger.LogTelegramAction("Запрос специального значения", map[string]interface{}{
-		"Критерий": criterionToSpecify,
-		"Опции":    options,
-	})
+	msgText := critText.SpecialPrompt
+	if msgText == "" {
+		msgText = tr(lang, "Укажите значение для '%s':", critText.Name)
+	}
+	msgText += "\n"
 
+	var options []string
 	var keyboardRows [][]tgbotapi.InlineKeyboardButton
-	for i, option := range options {
-		callbackData := fmt.Sprintf("%s_%d", callbackPrefix, i)
+	for i, option := range crit.SpecialOptions {
+		optionText := option.text(lang)
+		options = append(options, option.Value)
+		msgText += fmt.Sprintf("\n• *%s* — %s", optionText.Value, optionText.Description)
 
+		callbackData := fmt.Sprintf("spec_%d_%d", critIndex, i)
 		keyboardRows = append(keyboardRows, []tgbotapi.InlineKeyboardButton{
-			tgbotapi.NewInlineKeyboardButtonData(option, callbackData),
+			tgbotapi.NewInlineKeyboardButtonData(optionText.Value, callbackData),
 		})
 	}
 
+	logger.LogTelegramAction("Запрос специального значения", map[string]interface{}{
+		"Критерий": criterionToSpecify,
+		"Опции":    options,
+	})
+
 	keyboard := tgbotapi.NewInlineKeyboardMarkup(keyboardRows...)
 
 	if state.SpecialMessageID != 0 {
@@ -942,30 +1430,23 @@ func showSpecialCriteriaOptions(bot *tgbotapi.BotAPI, chatID int64) {
 		)
 		editMsg.ParseMode = "Markdown"
 
-		if sent, err := editMessageText(bot, editMsg); err == nil {
-			state.SpecialMessageID = sent.MessageID
-		} else {
-			logger.Printf("Ошибка обновления сообщения специальных значений: %v", err)
-		}
+		editMessageText(bot, editMsg)
 	} else {
 		msg := tgbotapi.NewMessage(chatID, msgText)
 		msg.ParseMode = "Markdown"
 		msg.ReplyMarkup = keyboard
 
-		if sent, err := sendMessage(bot, msg); err == nil {
-			state.SpecialMessageID = sent.MessageID
-		} else {
-			logger.Printf("Ошибка отправки сообщения со специальными значениями: %v", err)
-		}
+		sendTrackedMessage(bot, msg, &state.SpecialMessageID)
 	}
 }
 
 func showOverrideCriteriaList(bot *tgbotapi.BotAPI, chatID int64) {
 	state := userStates[chatID]
+	lang := userLang(chatID)
 	var keyboardRows [][]tgbotapi.InlineKeyboardButton
 
 	for _, critName := range state.SelectedCriteria {
-		buttonText := critName
+		buttonText := criterionLabel(lang, critName)
 		if _, ok := state.OverriddenScores[critName]; ok {
 			buttonText = "✓ " + buttonText
 		}
@@ -976,18 +1457,15 @@ func showOverrideCriteriaList(bot *tgbotapi.BotAPI, chatID int64) {
 	}
 
 	keyboardRows = append(keyboardRows, []tgbotapi.InlineKeyboardButton{
-		tgbotapi.NewInlineKeyboardButtonData("✅ Готово", "override_done"),
+		tgbotapi.NewInlineKeyboardButtonData(tr(lang, "✅ Готово"), "override_done"),
 	})
 
 	keyboard := tgbotapi.NewInlineKeyboardMarkup(keyboardRows...)
 
-	msg := tgbotapi.NewMessage(chatID, "Выберите критерий, для которого хотите изменить веса:")
+	msg := tgbotapi.NewMessage(chatID, tr(lang, "Выберите критерий, для которого хотите изменить веса:"))
 	msg.ReplyMarkup = keyboard
 
-	sentMsg, err := sendMessage(bot, msg)
-	if err == nil && sentMsg.MessageID != 0 {
-		state.OverrideMessageID = sentMsg.MessageID
-	}
+	sendTrackedMessage(bot, msg, &state.OverrideMessageID)
 }
 
 func showCriterionOverrideOptions(bot *tgbotapi.BotAPI, chatID int64, criterionName string) {
@@ -995,12 +1473,17 @@ func showCriterionOverrideOptions(bot *tgbotapi.BotAPI, chatID int64, criterionN
 
 	crit := findCriterionByName(criterionName)
 	scores := crit.BaseScores
+	if crit.IsSpecial {
+		if value, ok := state.SpecialValues[criterionName]; ok {
+			scores = getScoresForSpecialCriterion(criterionName, value)
+		}
+	}
 
 	if overridden, ok := state.OverriddenScores[criterionName]; ok {
-		scores = overridden
+		scores = mergeScores(scores, overridden)
 	}
 
-	state.TempOverride = scores
+	state.TempOverride = cloneScores(scores)
 	state.CurrentOverride = criterionName
 	state.OverrideStep = 0
 
@@ -1010,35 +1493,25 @@ func showCriterionOverrideOptions(bot *tgbotapi.BotAPI, chatID int64, criterionN
 func showWeightOptions(bot *tgbotapi.BotAPI, chatID int64, criterionName string) {
 	state := userStates[chatID]
 
-	var deploymentType string
-	var currentValue int
+	lang := userLang(chatID)
+	deploymentType := deploymentOptions[state.OverrideStep].Name
+	currentValue := state.TempOverride[deploymentOptions[state.OverrideStep].ID]
 
-	switch state.OverrideStep {
-	case 0:
-		deploymentType = "On-Premise"
-		currentValue = state.TempOverride.OnPrem
-	case 1:
-		deploymentType = "Private Cloud"
-		currentValue = state.TempOverride.Private
-	case 2:
-		deploymentType = "Public Cloud"
-		currentValue = state.TempOverride.Public
+	msgText := tr(lang, "Изменение весов для критерия *%s*", criterionLabel(lang, criterionName)) + "\n\n"
+	msgText += tr(lang, "*Текущие веса:*") + "\n"
+	for _, option := range deploymentOptions {
+		msgText += fmt.Sprintf("• %s: %s\n", option.Name, formatNumber(state.TempOverride[option.ID]))
 	}
+	msgText += "\n"
 
-	msgText := fmt.Sprintf("Изменение весов для критерия *%s*\n\n", criterionName)
-	msgText += fmt.Sprintf("*Текущие веса:*\n")
-	msgText += fmt.Sprintf("• On-Premise: %d\n", state.TempOverride.OnPrem)
-	msgText += fmt.Sprintf("• Private Cloud: %d\n", state.TempOverride.Private)
-	msgText += fmt.Sprintf("• Public Cloud: %d\n\n", state.TempOverride.Public)
-
-	msgText += fmt.Sprintf("Выберите новое значение для *%s*:", deploymentType)
+	msgText += tr(lang, "Выберите новое значение для *%s*:", deploymentType)
 
 	var rows [][]tgbotapi.InlineKeyboardButton
 	var row []tgbotapi.InlineKeyboardButton
 
 	for i := 1; i <= 10; i++ {
 		buttonText := fmt.Sprintf("%d", i)
-		if i == currentValue {
+		if float64(i) == currentValue {
 			buttonText = "• " + buttonText + " •"
 		}
 
@@ -1052,7 +1525,7 @@ func showWeightOptions(bot *tgbotapi.BotAPI, chatID int64, criterionName string)
 	}
 
 	rows = append(rows, []tgbotapi.InlineKeyboardButton{
-		tgbotapi.NewInlineKeyboardButtonData("❌ Отмена", "override_cancel"),
+		tgbotapi.NewInlineKeyboardButtonData(tr(lang, "❌ Отмена"), "override_cancel"),
 	})
 
 	keyboard := tgbotapi.NewInlineKeyboardMarkup(rows...)
@@ -1066,28 +1539,24 @@ func showWeightOptions(bot *tgbotapi.BotAPI, chatID int64, criterionName string)
 		)
 		editMsg.ParseMode = "Markdown"
 
-		if _, err := editMessageText(bot, editMsg); err != nil {
-			logger.Printf("Ошибка обновления сообщения для переопределения весов: %v", err)
-		}
+		editMessageText(bot, editMsg)
 	} else {
 		msg := tgbotapi.NewMessage(chatID, msgText)
 		msg.ParseMode = "Markdown"
 		msg.ReplyMarkup = keyboard
 
-		sentMsg, err := sendMessage(bot, msg)
-		if err == nil && sentMsg.MessageID != 0 {
-			state.OverrideMessageID = sentMsg.MessageID
-		}
+		sendTrackedMessage(bot, msg, &state.OverrideMessageID)
 	}
 }
 
 func askOverride(bot *tgbotapi.BotAPI, chatID int64) {
-	msg := tgbotapi.NewMessage(chatID, "Хотите ли переопределить базовые баллы (веса) для выбранных критериев?")
+	lang := userLang(chatID)
+	msg := tgbotapi.NewMessage(chatID, tr(lang, "Хотите ли переопределить базовые баллы (веса) для выбранных критериев?"))
 
 	keyboard := tgbotapi.NewInlineKeyboardMarkup(
 		tgbotapi.NewInlineKeyboardRow(
-			tgbotapi.NewInlineKeyboardButtonData("Да", "override_yes"),
-			tgbotapi.NewInlineKeyboardButtonData("Нет", "override_no"),
+			tgbotapi.NewInlineKeyboardButtonData(tr(lang, "Да"), "override_yes"),
+			tgbotapi.NewInlineKeyboardButtonData(tr(lang, "Нет"), "override_no"),
 		),
 	)
 
@@ -1096,18 +1565,138 @@ func askOverride(bot *tgbotapi.BotAPI, chatID int64) {
 	sendMessage(bot, msg)
 }
 
+// newStateFromInput создает состояние чеклиста, заполненное ранее сохраненными ответами.
+func newStateFromInput(input UserInputData) *UserState {
+	req := inputToRequest(input)
+	state := newUserState()
+
+	for _, name := range req.SelectedCriteria {
+		if findCriterionByName(name).Name != "" && !contains(state.SelectedCriteria, name) {
+			state.SelectedCriteria = append(state.SelectedCriteria, name)
+		}
+	}
+	for name, prio := range req.CriteriaPriorities {
+		state.CriteriaPriorities[name] = prio
+	}
+	for name, scores := range req.OverriddenScores {
+		state.OverriddenScores[name] = cloneScores(scores)
+	}
+	for name, value := range req.SpecialValues {
+		state.SpecialValues[name] = value
+	}
+	for id, value := range req.FollowUpAnswers {
+		state.FollowUpAnswers[id] = value
+	}
+	if len(state.CriteriaPriorities) > 0 {
+		state.Weighting = weightingPriority
+	}
+	if len(input.CriteriaWeights) > 0 {
+		state.Weighting = weightingAHP
+		state.CriteriaWeights = input.CriteriaWeights
+		state.AHPJudgments = input.AHPJudgments
+	}
+	state.Cost = input.Cost
+	pruneUnselectedInput(state)
+
+	return state
+}
+
+func showReview(bot *tgbotapi.BotAPI, chatID int64) {
+	state := userStates[chatID]
+	lang := userLang(chatID)
+
+	var text strings.Builder
+	text.WriteString(tr(lang, "Проверьте ответы перед расчетом:") + "\n\n")
+	for _, name := range state.SelectedCriteria {
+		label := criterionLabel(lang, name)
+		prio, ok := state.CriteriaPriorities[name]
+		if weight, weighted := state.CriteriaWeights[name]; weighted {
+			text.WriteString(tr(lang, "• %s — вес %.1f%%", label, weight*100))
+		} else if ok {
+			text.WriteString(tr(lang, "• %s — приоритет %d", label, prio))
+		} else {
+			text.WriteString(tr(lang, "• %s — приоритет не задан", label))
+		}
+		if value, ok := state.SpecialValues[name]; ok {
+			text.WriteString(tr(lang, ", значение: %s", specialValueLabel(lang, name, value)))
+		}
+		if scores, ok := state.OverriddenScores[name]; ok {
+			text.WriteString(tr(lang, ", баллы: ") + formatScores(scores))
+		}
+		text.WriteString("\n")
+	}
+	for _, followUp := range applicableFollowUps(followUpRequest(state)) {
+		if value, ok := state.FollowUpAnswers[followUp.ID]; ok {
+			text.WriteString(fmt.Sprintf("\n%s — %s", followUp.question(lang), followUp.answerLabel(lang, value)))
+		}
+	}
+	if state.Cost != nil {
+		text.WriteString("\n" + tr(lang, "Оценка стоимости: %s, экземпляров %d, инженеров %d, горизонт %d г.",
+			formatDataSize(lang, state.Cost.DataGB), state.Cost.Instances, state.Cost.TeamSize, state.Cost.HorizonYears) + "\n")
+	}
+
+	keyboard := tgbotapi.NewInlineKeyboardMarkup(
+		tgbotapi.NewInlineKeyboardRow(
+			tgbotapi.NewInlineKeyboardButtonData(tr(lang, "✅ Рассчитать"), "review_calc"),
+		),
+		tgbotapi.NewInlineKeyboardRow(
+			tgbotapi.NewInlineKeyboardButtonData(tr(lang, "✏️ Критерии"), "review_criteria"),
+			tgbotapi.NewInlineKeyboardButtonData(tr(lang, "🔢 Приоритеты"), "review_priorities"),
+		),
+		tgbotapi.NewInlineKeyboardRow(
+			tgbotapi.NewInlineKeyboardButtonData(tr(lang, "⚖️ Переопределить баллы"), "override_yes"),
+		),
+	)
+
+	logger.LogTelegramAction("Показ проверки ответов", map[string]interface{}{
+		"ChatID":   chatID,
+		"Критерии": state.SelectedCriteria,
+	})
+
+	msg := tgbotapi.NewMessage(chatID, text.String())
+	msg.ReplyMarkup = keyboard
+	sendMessage(bot, msg)
+}
+
+func handleReviewCallback(bot *tgbotapi.BotAPI, chatID int64, callbackData string) {
+	state := userStates[chatID]
+
+	switch callbackData {
+	case "review_calc":
+		switch {
+		case len(state.SelectedCriteria) == 0:
+			sendMessage(bot, tgbotapi.NewMessage(chatID, tr(userLang(chatID), "Пожалуйста, выберите хотя бы один критерий.")))
+			transition(bot, chatID, stateCriteria)
+		case !prioritiesComplete(state):
+			transition(bot, chatID, statePriorities)
+		case hasMissingSpecialValues(state):
+			transition(bot, chatID, stateSpecial)
+		case pendingFollowUp(state) >= 0:
+			transition(bot, chatID, stateFollowUps)
+		default:
+			transition(bot, chatID, stateResult)
+		}
+	case "review_criteria":
+		state.CriteriaMessageID = 0
+		transition(bot, chatID, stateCriteria)
+	case "review_priorities":
+		state.CriteriaPriorities = make(map[string]int)
+		state.CriteriaWeights = nil
+		state.AHPJudgments = nil
+		state.Weighting = ""
+		state.PriorityMessageID = 0
+		transition(bot, chatID, statePriorities)
+	case "override_yes":
+		transition(bot, chatID, stateOverrideEdit)
+	}
+}
+
 func calcAndShowResult(bot *tgbotapi.BotAPI, chatID int64) {
 	state := userStates[chatID]
 	if state == nil {
 		logger.Printf("Критическая ошибка: state = nil в calcAndShowResult для chatID %d", chatID)
 		return
 	}
-	onPremTotal := 0
-	privateTotal := 0
-	publicTotal := 0
-
-	var detailsMsg strings.Builder
-	detailsMsg.WriteString("Детализация расчета:\n\n")
 
 	logger.LogTelegramAction("Начат расчет результатов", map[string]interface{}{
 		"ChatID":            chatID,
@@ -1118,155 +1707,273 @@ func calcAndShowResult(bot *tgbotapi.BotAPI, chatID int64) {
 	})
 
 	userInput := UserInputData{
+		SelectedCriteria:   state.SelectedCriteria,
 		CriteriaPriorities: state.CriteriaPriorities,
 		OverriddenScores:   state.OverriddenScores,
 		SpecialValues:      state.SpecialValues,
+		Weighting:          state.Weighting,
+		CriteriaWeights:    state.CriteriaWeights,
+		AHPJudgments:       state.AHPJudgments,
+		Cost:               state.Cost,
+		TieBreakAnswer:     state.TieBreakAnswer,
+		FollowUpAnswers:    state.FollowUpAnswers,
 	}
 
 	for _, cName := range state.SelectedCriteria {
-		crit := findCriterionByName(cName)
-		prio, prioOk := state.CriteriaPriorities[cName]
-		if !prioOk {
+		if _, ok := state.CriteriaWeights[cName]; ok {
+			continue
+		}
+		if _, ok := state.CriteriaPriorities[cName]; !ok {
 			logger.Printf("Внимание: не найден приоритет для критерия '%s' у пользователя %d. Используется 1.", cName, chatID)
-			prio = 1
 		}
+	}
 
-		scores := crit.BaseScores
-		source := "базовый"
+	lang := userLang(chatID)
+	req := inputToRequest(userInput)
+	req.CompareMethods = true
+	req.Lang = lang
+	response := evaluateRecommendation(req)
+	if tie := response.TieBreak; tie != nil && tie.Question != nil && !tie.Applied {
+		state.TieBreakQuestion = tie.Question
+		transition(bot, chatID, stateTieBreak)
+		return
+	}
+	logger.LogTelegramAction("Результаты расчета", map[string]interface{}{
+		"ChatID":        chatID,
+		"Итоги":         response.Totals,
+		"Рекомендуется": response.Recommendation,
+	})
 
-		if crit.IsSpecial {
-			val, valOk := state.SpecialValues[cName]
-			if !valOk {
-				logger.Printf("Внимание: не найдено специальное значение для критерия '%s' у пользователя %d. Используются дефолтные баллы.", cName, chatID)
-			} else {
-				scores = getScoresForSpecialCriterion(crit.Name, val)
-				source = fmt.Sprintf("специальный (%s)", val)
-			}
+	// Чеклист завершен: возвращаться из расчета некуда.
+	state.History = nil
+	job := &resultJob{
+		bot:       bot,
+		chatID:    chatID,
+		userID:    &chatID,
+		lang:      lang,
+		state:     state,
+		userInput: userInput,
+		response:  response,
+	}
+	if session := wizardOutputs[chatID]; session != nil {
+		job.session = session
+		job.apiKeyID = apiKeyIDOf(session.client)
+		if session.webOnly {
+			// У чеклиста, начатого на сайте, нет пользователя Telegram: владелец — ключ API.
+			job.userID = nil
 		}
+	}
+	pendingResults = append(pendingResults, job)
+}
 
-		if overridden, ok := state.OverriddenScores[cName]; ok {
-			scores = overridden
-			source = "переопределенный"
-		}
+// resultJob — рассчитанный результат чеклиста, который еще нужно показать и сохранить.
+type resultJob struct {
+	bot       *tgbotapi.BotAPI
+	chatID    int64
+	userID    *int64
+	lang      Lang
+	state     *UserState
+	userInput UserInputData
+	response  *RecommendationResponse
+	apiKeyID  *int64
+	answerID  int64
+	// session — веб-сессия, для которой рассчитан результат; ее сообщения копятся в messages
+	// и передаются в сессию в commit.
+	session  *wizardSession
+	messages []tgbotapi.MessageConfig
+}
 
-		onPremTotal += scores.OnPrem * prio
-		privateTotal += scores.Private * prio
-		publicTotal += scores.Public * prio
+// pendingResults — результаты, рассчитанные под stateMu и ожидающие finishResults.
+var pendingResults []*resultJob
 
-		detailsMsg.WriteString(fmt.Sprintf("Критерий: %s\n", cName))
-		detailsMsg.WriteString(fmt.Sprintf("  Приоритет: %d\n", prio))
-		detailsMsg.WriteString(fmt.Sprintf("  Баллы (%s): OnPrem=%d, Private=%d, Public=%d\n",
-			source, scores.OnPrem, scores.Private, scores.Public))
-		detailsMsg.WriteString(fmt.Sprintf("  С учетом приоритета: OnPrem=%d, Private=%d, Public=%d\n\n",
-			scores.OnPrem*prio, scores.Private*prio, scores.Public*prio))
+// finishResults показывает и сохраняет рассчитанные результаты. Вызывается под stateMu:
+// на время запроса к AI, записи в БД и отправки сообщений блокировка отпускается, затем
+// берется снова, чтобы передать итог в веб-сессию и очистить состояние чеклиста.
+func finishResults() {
+	jobs := pendingResults
+	pendingResults = nil
+	if len(jobs) == 0 {
+		return
 	}
 
-	resultMsg := fmt.Sprintf(
-		"Итоговые баллы:\nOn-Premise: %d\nPrivate Cloud: %d\nPublic Cloud: %d\n\n",
-		onPremTotal, privateTotal, publicTotal,
-	)
-
-	var recommendation string
-	if onPremTotal > privateTotal && onPremTotal > publicTotal {
-		recommendation = "On-Premise"
-		resultMsg += "Рекомендуется On-Premise."
-	} else if privateTotal > onPremTotal && privateTotal > publicTotal {
-		recommendation = "Private Cloud"
-		resultMsg += "Рекомендуется Private Cloud."
-	} else if publicTotal > onPremTotal && publicTotal > privateTotal {
-		recommendation = "Public Cloud"
-		resultMsg += "Рекомендуется Public Cloud."
-	} else {
-		equalOptions := []string{}
-		maxScore := 0
-		if onPremTotal >= maxScore {
-			maxScore = onPremTotal
-		}
-		if privateTotal >= maxScore {
-			maxScore = privateTotal
-		}
-		if publicTotal >= maxScore {
-			maxScore = publicTotal
+	func() {
+		stateMu.Unlock()
+		defer stateMu.Lock()
+		for _, job := range jobs {
+			job.publish()
 		}
+	}()
+	for _, job := range jobs {
+		job.commit()
+	}
+}
 
-		if onPremTotal == maxScore {
-			equalOptions = append(equalOptions, "On-Premise")
-		}
-		if privateTotal == maxScore {
-			equalOptions = append(equalOptions, "Private Cloud")
-		}
-		if publicTotal == maxScore {
-			equalOptions = append(equalOptions, "Public Cloud")
-		}
+func (job *resultJob) send(msg tgbotapi.MessageConfig) {
+	if job.session != nil {
+		job.messages = append(job.messages, msg)
+		return
+	}
+	sendTelegramMessage(job.bot, msg)
+}
 
-		if len(equalOptions) > 1 {
-			recommendation = "Требуется дополнительная оценка (" + strings.Join(equalOptions, "/") + ")"
-			resultMsg += "Варианты (" + strings.Join(equalOptions, ", ") + ") равны по баллам, нужна дополнительная оценка."
-		} else {
-			recommendation = "Требуется дополнительная оценка"
-			resultMsg += "Не удалось однозначно определить лучший вариант, нужна дополнительная оценка."
-		}
+// publish отправляет результат, запрашивает анализ AI и сохраняет ответ в БД. Выполняется
+// без stateMu, поэтому обращается только к полям job.
+func (job *resultJob) publish() {
+	chatID, lang, response := job.chatID, job.lang, job.response
+
+	job.send(tgbotapi.NewMessage(chatID, formatResultMessage(response)))
+
+	if response.Cost != nil {
+		job.send(tgbotapi.NewMessage(chatID, formatCostMessage(lang, response.Cost)))
 	}
 
-	logger.LogTelegramAction("Результаты расчета", map[string]interface{}{
-		"ChatID":        chatID,
-		"OnPrem":        onPremTotal,
-		"Private":       privateTotal,
-		"Public":        publicTotal,
-		"Рекомендуется": recommendation,
-	})
+	job.send(tgbotapi.NewMessage(chatID, response.Explanation.Text))
+
+	job.send(tgbotapi.NewMessage(chatID, formatSensitivityMessage(lang, response.Sensitivity)))
 
-	sendMessage(bot, tgbotapi.NewMessage(chatID, resultMsg))
+	job.send(tgbotapi.NewMessage(chatID, formatMethodsMessage(lang, response.Methods, *response.MethodsAgree)))
 
-	sendMessage(bot, tgbotapi.NewMessage(chatID, detailsMsg.String()))
+	job.send(tgbotapi.NewMessage(chatID, formatDetailsMessage(lang, response.Details)))
 
 	aiAnalysis := ""
 	var aiErr error
-	filteredDetails := filterString(detailsMsg.String(), "Баллы", "С учетом приоритета:")
-	aiAnalysis, aiErr = getAISuggestions(filteredDetails)
+	filteredDetails := filterString(formatDetailsMessage(langRU, response.Details), "Баллы", "С учетом приоритета:")
+	if len(response.Excluded) > 0 {
+		filteredDetails += "\n\n" + formatExclusions(langRU, response.Excluded)
+	}
+	aiAnalysis, aiErr = getAISuggestions(filteredDetails, lang)
 	if aiErr != nil {
 		logger.Printf("Ошибка получения анализа AI для chatID %d: %v", chatID, aiErr)
-		sendMessage(bot, tgbotapi.NewMessage(chatID, "Не удалось получить рекомендацию от AI."))
+		job.send(tgbotapi.NewMessage(chatID, tr(lang, "Не удалось получить рекомендацию от AI.")))
 	} else {
-		msg := tgbotapi.NewMessage(chatID, fmt.Sprintf("*Рекомендация AI*:\n%s", aiAnalysis))
+		msg := tgbotapi.NewMessage(chatID, tr(lang, "*Рекомендация AI*:")+"\n"+aiAnalysis)
 		msg.ParseMode = "Markdown"
-		sendMessage(bot, msg)
+		job.send(msg)
 	}
 
-	equal := false
-	simpleRecommendation := strings.Split(recommendation, " ")[0]
-	if strings.Contains(strings.ToLower(aiAnalysis), strings.ToLower(simpleRecommendation)) {
-		equal = true
-	}
-	if strings.HasPrefix(recommendation, "Требуется") {
-		equal = false
+	if resolveTieWithAI(response, aiAnalysis) || (response.TieBreak != nil && response.TieBreak.Policy == tieBreakAI) {
+		job.send(tgbotapi.NewMessage(chatID, formatTieBreak(lang, response.TieBreak)))
 	}
+	equal := aiAgrees(response, aiAnalysis)
 
-	userInputJSON, err := json.Marshal(userInput)
+	userInputJSON, err := json.Marshal(job.userInput)
 	if err != nil {
 		logger.Printf("Ошибка сериализации userInput в JSON для chatID %d: %v", chatID, err)
 		userInputJSON = []byte("null")
 	}
 
-	insertSQL := `INSERT INTO answers (user_id, user_input, algorithm_result, gpt_answer, equal) VALUES ($1, $2, $3, $4, $5)`
-	_, err = pool.Exec(context.Background(), insertSQL, chatID, userInputJSON, recommendation, aiAnalysis, equal)
+	var answerID int64
+	insertSQL := `INSERT INTO answers (user_id, api_key_id, user_input, algorithm_result, outcome, gpt_answer, equal) VALUES ($1, $2, $3, $4, $5, $6, $7) RETURNING id`
+	err = pool.QueryRow(context.Background(), insertSQL, job.userID, job.apiKeyID, userInputJSON, response.Recommendation, response.Outcome, aiAnalysis, equal).Scan(&answerID)
 	if err != nil {
 		logger.Printf("Ошибка сохранения результата в БД для chatID %d: %v", chatID, err)
-		sendMessage(bot, tgbotapi.NewMessage(chatID, "Произошла ошибка при сохранении результатов."))
+		job.send(tgbotapi.NewMessage(chatID, tr(lang, "Произошла ошибка при сохранении результатов.")))
 	} else {
 		logger.LogTelegramAction("Результат сохранен в БД", map[string]interface{}{
 			"ChatID":           chatID,
-			"AlgorithmResult":  recommendation,
+			"AnswerID":         answerID,
+			"AlgorithmResult":  response.Recommendation,
 			"GPTAnswerPresent": aiAnalysis != "",
 			"Equal":            equal,
 		})
 	}
 
-	msg := tgbotapi.NewMessage(chatID, "Чтобы начать новый чеклист, введите /start")
-	sendMessage(bot, msg)
+	msg := tgbotapi.NewMessage(chatID, tr(lang, "Чтобы начать новый чеклист, введите /start"))
+	// Кнопки уверенности, профиля и поставщиков обрабатывает только бот.
+	if answerID != 0 && job.session == nil {
+		rows := [][]tgbotapi.InlineKeyboardButton{
+			tgbotapi.NewInlineKeyboardRow(
+				tgbotapi.NewInlineKeyboardButtonData(tr(lang, "🎲 Показать уверенность"), fmt.Sprintf("conf_%d", answerID)),
+			),
+			tgbotapi.NewInlineKeyboardRow(
+				tgbotapi.NewInlineKeyboardButtonData(tr(lang, "💾 Сохранить как профиль"), fmt.Sprintf("prof_save_%d", answerID)),
+			),
+		}
+		if option := response.Outcome.OptionID; option != "" && len(vendorsForOption(option)) > 0 {
+			rows = append(rows, tgbotapi.NewInlineKeyboardRow(
+				tgbotapi.NewInlineKeyboardButtonData(tr(lang, "🏷 Подобрать поставщика"), fmt.Sprintf("vend_%d", answerID)),
+			))
+		}
+		msg.ReplyMarkup = tgbotapi.NewInlineKeyboardMarkup(rows...)
+	}
+	job.send(msg)
+	job.answerID = answerID
+}
+
+// commit передает результат в веб-сессию и очищает состояние чеклиста. Вызывается под stateMu;
+// если пока шел расчет, пользователь начал новый чеклист, его состояние не трогается.
+func (job *resultJob) commit() {
+	if session := job.session; session != nil {
+		session.run(func() {
+			for _, msg := range job.messages {
+				sendMessage(nil, msg)
+			}
+		})
+		session.result, session.answerID = job.response, job.answerID
+	}
+	if userStates[job.chatID] == job.state {
+		delete(userStates, job.chatID)
+		logger.Printf("Состояние пользователя для chatID %d очищено.", job.chatID)
+	}
+}
+
+func formatResultMessage(response *RecommendationResponse) string {
+	lang := response.Language
+	resultMsg := tr(lang, "Итоговые баллы:") + "\n"
+	for _, option := range deploymentOptions {
+		resultMsg += fmt.Sprintf("%s: %s (%s%%)\n", option.Name,
+			formatNumber(response.Totals[option.ID]), formatNumber(response.TotalsPercent[option.ID]))
+	}
+	resultMsg += "\n"
+
+	switch outcome := response.Outcome; {
+	case outcome.OptionID != "":
+		resultMsg += tr(lang, "Рекомендуется %s.", recommendationLabel(lang, outcome))
+	case len(outcome.Tied) > 0:
+		resultMsg += tr(lang, "Варианты (%s) равны по баллам, нужна дополнительная оценка.", strings.Join(optionNamesByID(outcome.Tied), ", "))
+	default:
+		resultMsg += tr(lang, "Все варианты исключены жёсткими ограничениями, нужна дополнительная оценка.")
+	}
+
+	if len(response.Excluded) > 0 {
+		resultMsg += "\n\n" + formatExclusions(lang, response.Excluded)
+	}
+
+	return resultMsg
+}
+
+func formatDetailsMessage(lang Lang, details []CriterionDetail) string {
+	var detailsMsg strings.Builder
+	detailsMsg.WriteString(tr(lang, "Детализация расчета:") + "\n\n")
+
+	for _, detail := range details {
+		detailsMsg.WriteString(tr(lang, "Критерий: %s", criterionLabel(lang, detail.Name)) + "\n")
+		detailsMsg.WriteString(fmt.Sprintf("  %s\n", formatDetailWeight(lang, detail)))
+		detailsMsg.WriteString("  " + tr(lang, "Баллы (%s): %s", sourceLabel(lang, detail), formatScores(detail.Scores)) + "\n")
+		if len(detail.Adjustments) > 0 {
+			detailsMsg.WriteString("  " + tr(lang, "Уточнения: %s", strings.Join(detail.Adjustments, "; ")) + "\n")
+		}
+		detailsMsg.WriteString("  " + tr(lang, "С учетом приоритета: %s", formatScores(detail.Weighted)) + "\n\n")
+	}
+
+	return detailsMsg.String()
+}
+
+// formatDetailWeight выводит приоритет и нормированный вес критерия.
+// Для весов, заданных напрямую (AHP), приоритета нет.
+func formatDetailWeight(lang Lang, detail CriterionDetail) string {
+	if detail.Priority == 0 {
+		return tr(lang, "Вес: %.1f%%", detail.Weight*100)
+	}
+	return tr(lang, "Приоритет: %d (вес %.1f%%)", detail.Priority, detail.Weight*100)
+}
 
-	delete(userStates, chatID)
-	logger.Printf("Состояние пользователя для chatID %d очищено.", chatID)
+// sourceLabel выводит источник баллов критерия; в API источник остается русским.
+func sourceLabel(lang Lang, detail CriterionDetail) string {
+	if strings.HasPrefix(detail.Source, "специальный (") {
+		return tr(lang, "специальный (%s)", specialValueLabel(lang, detail.Name, detail.SpecialValue))
+	}
+	return tr(lang, detail.Source)
 }
 
 func findCriterionByName(name string) Criterion {
@@ -1279,36 +1986,34 @@ func findCriterionByName(name string) Criterion {
 	return Criterion{}
 }
 
-func getScoresForSpecialCriterion(name, userValue string) Scores {
-	switch name {
-	case "Объём данных":
-		lower := strings.ToLower(userValue)
-		switch lower {
-		case "малый":
-			return Scores{OnPrem: 8, Private: 7, Public: 9}
-		case "средний":
-			return Scores{OnPrem: 6, Private: 8, Public: 9}
-		case "большой":
-			return Scores{OnPrem: 4, Private: 8, Public: 9}
-		default:
-			logger.Printf("Неизвестное значение '%s' для спец. критерия '%s'. Возвращены дефолтные баллы.", userValue, name)
-			return Scores{OnPrem: 5, Private: 5, Public: 5}
-		}
-	case "Срок использования":
-		lower := strings.ToLower(userValue)
-		switch lower {
-		case "краткосрочный":
-			return Scores{OnPrem: 4, Private: 6, Public: 9}
-		case "долгосрочный":
-			return Scores{OnPrem: 9, Private: 7, Public: 6}
-		default:
-			logger.Printf("Неизвестное значение '%s' для спец. критерия '%s'. Возвращены дефолтные баллы.", userValue, name)
-			return Scores{OnPrem: 5, Private: 5, Public: 5}
+func findCriterionIndex(name string) int {
+	for i, c := range defaultCriteria {
+		if c.Name == name {
+			return i
 		}
-	default:
+	}
+	return -1
+}
+
+func getScoresForSpecialCriterion(name, userValue string) Scores {
+	crit := findCriterionByName(name)
+	if !crit.IsSpecial {
 		logger.Printf("Попытка получить баллы для неизвестного спец. критерия '%s'.", name)
-		return Scores{OnPrem: 0, Private: 0, Public: 0}
+		return scoresFromVector(make([]float64, len(deploymentOptions)))
+	}
+
+	for _, option := range crit.SpecialOptions {
+		if strings.EqualFold(option.Value, userValue) {
+			return cloneScores(option.Scores)
+		}
+	}
+
+	logger.Printf("Неизвестное значение '%s' для спец. критерия '%s'. Возвращены дефолтные баллы.", userValue, name)
+	defaults := make([]float64, len(deploymentOptions))
+	for i := range defaults {
+		defaults[i] = 5
 	}
+	return scoresFromVector(defaults)
 }
 
 func contains(arr []string, val string) bool {
@@ -1363,44 +2068,126 @@ func (l *CustomLogger) LogTelegramAction(action string, msg interface{}) {
 	fmt.Fprintf(l.out, "[%s] === %s ===\n%s\n\n", timestamp, action, string(jsonData))
 }
 
-func sendMessage(bot *tgbotapi.BotAPI, msg tgbotapi.MessageConfig) (tgbotapi.Message, error) {
+// sendMessage отправляет сообщение в чат или, во время шага веб-сессии, в сессию.
+func sendMessage(bot *tgbotapi.BotAPI, msg tgbotapi.MessageConfig) {
+	sendTrackedMessage(bot, msg, nil)
+}
+
+// sendTrackedMessage отправляет сообщение и записывает его ID в messageID, чтобы потом
+// изменять сообщение. Сообщение Telegram уходит из flushOutbox, тогда же записывается ID.
+func sendTrackedMessage(bot *tgbotapi.BotAPI, msg tgbotapi.MessageConfig, messageID *int) {
+	logSentMessage(msg)
+	deliverMessage(bot, msg, messageID)
+}
+
+// sendSecretMessage отправляет сообщение с секретом (ключом API, кодом передачи чеклиста);
+// в лог секрет не попадает.
+func sendSecretMessage(bot *tgbotapi.BotAPI, msg tgbotapi.MessageConfig, secret string) {
+	masked := msg
+	masked.Text = strings.ReplaceAll(msg.Text, secret, "[скрыто]")
+	logSentMessage(masked)
+	deliverMessage(bot, msg, nil)
+}
+
+func deliverMessage(bot *tgbotapi.BotAPI, msg tgbotapi.MessageConfig, messageID *int) {
+	if session := wizardOutputs[msg.ChatID]; session != nil {
+		sent := session.send(msg)
+		if messageID != nil {
+			*messageID = sent.MessageID
+		}
+		return
+	}
+	queueTelegram(bot, msg, messageID, fmt.Sprintf("Ошибка отправки сообщения в чат %d", msg.ChatID))
+}
+
+// sendTelegramMessage отправляет сообщение в Telegram сразу, минуя веб-сессии и очередь;
+// вызывается без stateMu.
+func sendTelegramMessage(bot *tgbotapi.BotAPI, msg tgbotapi.MessageConfig) {
+	logSentMessage(msg)
+	if _, err := bot.Send(msg); err != nil {
+		logger.Printf("Ошибка отправки сообщения в чат %d: %v", msg.ChatID, err)
+	}
+}
+
+func logSentMessage(msg tgbotapi.MessageConfig) {
 	logger.LogTelegramAction("Отправка сообщения", map[string]interface{}{
 		"ChatID":      msg.ChatID,
 		"Text":        msg.Text,
 		"ParseMode":   msg.ParseMode,
 		"HasKeyboard": msg.ReplyMarkup != nil,
 	})
-	sentMsg, err := bot.Send(msg)
-	if err != nil {
-		logger.Printf("Ошибка отправки сообщения в чат %d: %v", msg.ChatID, err)
-	}
-	return sentMsg, err
 }
 
-func editMessageText(bot *tgbotapi.BotAPI, msg tgbotapi.EditMessageTextConfig) (tgbotapi.Message, error) {
+func editMessageText(bot *tgbotapi.BotAPI, msg tgbotapi.EditMessageTextConfig) {
 	logger.LogTelegramAction("Редактирование текста сообщения", map[string]interface{}{
 		"ChatID":    msg.ChatID,
 		"MessageID": msg.MessageID,
 		"Text":      msg.Text,
 		"ParseMode": msg.ParseMode,
 	})
-	sentMsg, err := bot.Send(msg)
-	if err != nil {
-		logger.Printf("Ошибка редактирования текста сообщения %d в чате %d: %v", msg.MessageID, msg.ChatID, err)
+	if session := wizardOutputs[msg.ChatID]; session != nil {
+		session.editText(msg)
+		return
 	}
-	return sentMsg, err
+	queueTelegram(bot, msg, nil, fmt.Sprintf("Ошибка редактирования текста сообщения %d в чате %d", msg.MessageID, msg.ChatID))
 }
 
-func editMessageReplyMarkup(bot *tgbotapi.BotAPI, msg tgbotapi.EditMessageReplyMarkupConfig) (tgbotapi.Message, error) {
+func editMessageReplyMarkup(bot *tgbotapi.BotAPI, msg tgbotapi.EditMessageReplyMarkupConfig) {
 	logger.LogTelegramAction("Обновление кнопок сообщения", map[string]interface{}{
 		"ChatID":    msg.ChatID,
 		"MessageID": msg.MessageID,
 	})
-	sentMsg, err := bot.Send(msg)
-	if err != nil {
-		logger.Printf("Ошибка обновления кнопок сообщения %d в чате %d: %v", msg.MessageID, msg.ChatID, err)
+	if session := wizardOutputs[msg.ChatID]; session != nil {
+		session.editMarkup(msg)
+		return
+	}
+	queueTelegram(bot, msg, nil, fmt.Sprintf("Ошибка обновления кнопок сообщения %d в чате %d", msg.MessageID, msg.ChatID))
+}
+
+// telegramRequest — запрос к Telegram, отложенный до flushOutbox.
+type telegramRequest struct {
+	bot       *tgbotapi.BotAPI
+	request   tgbotapi.Chattable
+	messageID *int
+	failure   string
+}
+
+// outbox — запросы к Telegram, накопленные под stateMu при обработке обновления.
+var outbox []telegramRequest
+
+func queueTelegram(bot *tgbotapi.BotAPI, request tgbotapi.Chattable, messageID *int, failure string) {
+	outbox = append(outbox, telegramRequest{bot: bot, request: request, messageID: messageID, failure: failure})
+}
+
+// flushOutbox выполняет накопленные запросы к Telegram по порядку. Вызывается под stateMu:
+// на время запросов блокировка отпускается, затем берется снова, чтобы записать ID
+// отправленных сообщений.
+func flushOutbox() {
+	pending := outbox
+	outbox = nil
+	if len(pending) == 0 {
+		return
+	}
+
+	sent := make([]tgbotapi.Message, len(pending))
+	func() {
+		stateMu.Unlock()
+		defer stateMu.Lock()
+		for i, r := range pending {
+			resp, err := r.bot.Request(r.request)
+			if err == nil && r.messageID != nil {
+				err = json.Unmarshal(resp.Result, &sent[i])
+			}
+			if err != nil {
+				logger.Printf("%s: %v", r.failure, err)
+			}
+		}
+	}()
+	for i, r := range pending {
+		if r.messageID != nil && sent[i].MessageID != 0 {
+			*r.messageID = sent[i].MessageID
+		}
 	}
-	return sentMsg, err
 }
 
 func logCallbackQuery(query *tgbotapi.CallbackQuery) {
@@ -1427,7 +2214,9 @@ func logCallbackQuery(query *tgbotapi.CallbackQuery) {
 	})
 }
 
-func getAISuggestions(details string) (string, error) {
+// getAISuggestions запрашивает рекомендацию у Yandex GPT. Промпт и детали всегда передаются
+// по-русски, а для других языков модель просят ответить на языке пользователя.
+func getAISuggestions(details string, lang Lang) (string, error) {
 	apiKey := os.Getenv("YANDEX_API_KEY")
 	folderID := os.Getenv("YANDEX_FOLDER_ID")
 
@@ -1447,7 +2236,7 @@ func getAISuggestions(details string) (string, error) {
 		Messages: []yandexgpt.YandexGPTMessage{
 			{
 				Role: yandexgpt.YandexGPTMessageRoleSystem,
-				Text: descriptionLLM,
+				Text: descriptionLLM + catalogOptionsNote() + priorityScaleNote() + answerLanguageNote(lang),
 			},
 			{
 				Role: yandexgpt.YandexGPTMessageRoleUser,
@@ -1491,6 +2280,14 @@ func getAISuggestions(details string) (string, error) {
 	return aiText, nil
 }
 
+// answerLanguageNote просит модель отвечать на языке пользователя, сохраняя формат ответа.
+func answerLanguageNote(lang Lang) string {
+	if lang != langEN {
+		return ""
+	}
+	return "\nОтветь на английском языке. Первую строку с названием варианта оставь без изменений, а вместо \"Обоснование:\" напиши \"Rationale:\".\n"
+}
+
 const descriptionLLM = `
 Ты — эксперт по выбору инфраструктурных решений для баз данных. К тебе обращается пользователь, который прошел тест для определения оптимального типа развертывания СУБД: On-Premise, Private Cloud или Public Cloud.
 Пользователь выбрал важные для него критерии из списка и установил их приоритет от 1 (низкий) до 5 (высокий).
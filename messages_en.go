@@ -0,0 +1,327 @@
+package main
+
+// messagesEN — английский каталог сообщений. Ключ — русский текст из кода, значение —
+// перевод с теми же аргументами fmt в том же порядке.
+var messagesEN = map[string]string{
+	// i18n.go
+	"Выберите язык:":              "Choose a language:",
+	"Язык переключен на русский.": "Language switched to English.",
+
+	// main.go: бот
+	"Привет! Я бот для выбора типа СУБД (%s). Давайте начнём чеклист.": "Hi! I help you choose a database deployment type (%s). Let's start the checklist.",
+	"Чеклист сброшен. Давайте начнем заново.":                          "The checklist has been reset. Let's start over.",
+	"Произошла ошибка состояния. Пожалуйста, начните заново с /start.": "Something went wrong with the session state. Please start over with /start.",
+	"Выберите критерии, которые важны для вашей компании:":             "Select the criteria that matter to your company:",
+	"✅ Готово": "✅ Done",
+	"Пожалуйста, выберите хотя бы один критерий.":           "Please select at least one criterion.",
+	"Установите приоритет для критерия:\n\n*%s*\n%s":        "Set the priority for the criterion:\n\n*%s*\n%s",
+	"Укажите значение для '%s':":                            "Specify a value for '%s':",
+	"Выберите критерий, для которого хотите изменить веса:": "Select the criterion whose weights you want to change:",
+	"Изменение весов для критерия *%s*":                     "Changing weights for *%s*",
+	"*Текущие веса:*":                                       "*Current weights:*",
+	"Выберите новое значение для *%s*:":                     "Choose a new value for *%s*:",
+	"❌ Отмена": "❌ Cancel",
+	"Хотите ли переопределить базовые баллы (веса) для выбранных критериев?": "Do you want to override the base scores (weights) for the selected criteria?",
+	"Да":  "Yes",
+	"Нет": "No",
+	"Проверьте ответы перед расчетом:": "Review your answers before the calculation:",
+	"• %s — вес %.1f%%":                "• %s — weight %.1f%%",
+	"• %s — приоритет %d":              "• %s — priority %d",
+	"• %s — приоритет не задан":        "• %s — no priority set",
+	", значение: %s":                   ", value: %s",
+	", баллы: ":                        ", scores: ",
+	"Оценка стоимости: %s, экземпляров %d, инженеров %d, горизонт %d г.": "Cost estimate: %s, %d instances, %d engineers, %d-year horizon",
+	"✅ Рассчитать":                                 "✅ Calculate",
+	"✏️ Критерии":                                  "✏️ Criteria",
+	"🔢 Приоритеты":                                 "🔢 Priorities",
+	"⚖️ Переопределить баллы":                      "⚖️ Override scores",
+	"Не удалось получить рекомендацию от AI.":      "Could not get a recommendation from the AI.",
+	"*Рекомендация AI*:":                           "*AI recommendation*:",
+	"Произошла ошибка при сохранении результатов.": "An error occurred while saving the results.",
+	"Чтобы начать новый чеклист, введите /start":   "To start a new checklist, type /start",
+	"🎲 Показать уверенность":                       "🎲 Show confidence",
+	"💾 Сохранить как профиль":                      "💾 Save as profile",
+	"🏷 Подобрать поставщика":                       "🏷 Find a vendor",
+
+	// main.go: результат и детализация
+	"Итоговые баллы:": "Total scores:",
+	"Все варианты исключены жёсткими ограничениями, нужна дополнительная оценка.": "All options are excluded by hard constraints; further assessment is needed.",
+	"Рекомендуется %s.": "Recommended: %s.",
+	"Варианты (%s) равны по баллам, нужна дополнительная оценка.": "Options (%s) have equal scores; further assessment is needed.",
+	"Требуется дополнительная оценка (%s)":                        "Further assessment required (%s)",
+	"Нет допустимых вариантов":                                    "No acceptable options",
+	"Детализация расчета:":                                        "Calculation details:",
+	"Критерий: %s":                                                "Criterion: %s",
+	"Баллы (%s): %s":                                              "Scores (%s): %s",
+	"Уточнения: %s":                                               "Refinements: %s",
+	"С учетом приоритета: %s":                                     "Weighted by priority: %s",
+	"Вес: %.1f%%":                                                 "Weight: %.1f%%",
+	"Приоритет: %d (вес %.1f%%)":                                  "Priority: %d (weight %.1f%%)",
+	"базовый":                                                     "base",
+	"переопределенный":                                            "overridden",
+	"расчет TCO":                                                  "TCO estimate",
+	"специальный (%s)":                                            "special (%s)",
+
+	// main.go: API
+	"Метод не поддерживается":                                       "Method not allowed",
+	"Ошибка парсинга JSON: ":                                        "JSON parse error: ",
+	"Необходимо выбрать хотя бы один критерий":                      "At least one criterion must be selected",
+	"Неизвестный метод %q, допустимые значения: %s":                 "Unknown method %q, allowed values: %s",
+	"Ошибка при расчете рекомендации: ":                             "Error calculating the recommendation: ",
+	"неизвестный вариант развертывания %q, допустимые значения: %s": "unknown deployment option %q, allowed values: %s",
+
+	// ahp.go
+	"Равны": "Equal",
+	"критерий %s сравнивается сам с собой":                   "criterion %s is compared with itself",
+	"сравнение %s/%s указано дважды":                         "comparison %s/%s is given twice",
+	"нужно %d попарных сравнений, получено %d":               "%d pairwise comparisons are required, got %d",
+	"«%s» важнее «%s» в %.0f раз(а)":                         "«%[1]s» is %[3].0f× more important than «%[2]s»",
+	"Веса критериев (AHP):":                                  "Criterion weights (AHP):",
+	"Отношение согласованности: %.3f":                        "Consistency ratio: %.3f",
+	"Как задать важность критериев?":                         "How do you want to set criterion importance?",
+	"• *Приоритеты 1–5* — оценка каждого критерия отдельно.": "• *Priorities 1–5* — rate each criterion separately.",
+	"• *Попарное сравнение (AHP)* — %d вопросов «что важнее», веса рассчитываются автоматически.": "• *Pairwise comparison (AHP)* — %d “which matters more” questions, weights are calculated automatically.",
+	"Приоритеты 1–5":     "Priorities 1–5",
+	"Попарное сравнение": "Pairwise comparison",
+	"Сравнение %d из %d. Что важнее для вашей системы?":              "Comparison %d of %d. What matters more for your system?",
+	"3 — немного важнее, 5 — заметно важнее, 9 — несравнимо важнее.": "3 — slightly more important, 5 — clearly more important, 9 — far more important.",
+	" — больше допустимых %.1f, ответы противоречат друг другу.":     " — above the allowed %.1f, the answers contradict each other.",
+	"Стоит пересмотреть сравнения:":                                  "Consider revisiting these comparisons:",
+	"Пересмотреть %d":  "Revisit %d",
+	"Принять как есть": "Accept as is",
+
+	// api_versions.go
+	"Версия API отключена, используйте ": "This API version has been shut down, use ",
+
+	// apikeys.go
+	"Требуется ключ API: заголовок Authorization: Bearer <ключ>": "An API key is required: Authorization: Bearer <key> header",
+	"Неверный или отозванный ключ API":                           "Invalid or revoked API key",
+	"Превышен лимит запросов ключа API: %d в минуту":             "API key rate limit exceeded: %d per minute",
+	"Исчерпана дневная квота ключа API: %d запросов":             "API key daily quota exhausted: %d requests",
+	"Команды:\n/apikey — список ключей\n/apikey new <название> [запросов в минуту] [запросов в сутки] — выпустить ключ (0 — без ограничения)\n/apikey revoke <id> — отозвать ключ": "Commands:\n/apikey — list keys\n/apikey new <name> [requests per minute] [requests per day] — issue a key (0 — unlimited)\n/apikey revoke <id> — revoke a key",
+	"Не удалось загрузить ключи API.": "Failed to load API keys.",
+	"Ключей API нет.":                 "There are no API keys.",
+	"Ключи API:":                      "API keys:",
+	"#%d %s (%s…): %s в минуту, сегодня %d из %s, создан %s": "#%d %s (%s…): %s per minute, %d of %s today, created %s",
+	", отозван %s": ", revoked %s",
+	"Лимит должен быть неотрицательным целым числом: %s":                                          "The limit must be a non-negative integer: %s",
+	"Не удалось выпустить ключ API.":                                                              "Failed to issue the API key.",
+	"Ключ #%d «%s»: %s в минуту, %s в сутки.\n\n%s\n\nКлюч показывается один раз, сохраните его.": "Key #%d «%s»: %s per minute, %s per day.\n\n%s\n\nThe key is shown only once, save it.",
+	"Действующий ключ #%d не найден.":                                                             "Active key #%d not found.",
+	"Не удалось отозвать ключ API.":                                                               "Failed to revoke the API key.",
+	"Ключ #%d отозван.":                                                                           "Key #%d revoked.",
+	"без ограничения":                                                                             "unlimited",
+
+	// compare.go
+	"Сравнение: A — %s, B — %s":                                "Comparison: A — %s, B — %s",
+	"Итоговые баллы (A → B):":                                  "Total scores (A → B):",
+	"Рекомендация изменилась: %s → %s":                         "Recommendation changed: %s → %s",
+	"Рекомендация не изменилась: %s":                           "Recommendation unchanged: %s",
+	"Различий по критериям нет.":                               "No differences in criteria.",
+	"Различия по критериям:":                                   "Differences in criteria:",
+	"• %s: только в B (приоритет %d)":                          "• %s: only in B (priority %d)",
+	"• %s: только в A (приоритет %d)":                          "• %s: only in A (priority %d)",
+	"• %s: приоритет %d → %d":                                  "• %s: priority %d → %d",
+	"• %s: изменены баллы":                                     "• %s: scores changed",
+	"Для сравнения нужно хотя бы два пройденных чеклиста.":     "You need at least two completed checklists to compare.",
+	"Выберите два чеклиста для сравнения: сначала A, затем B.": "Choose two checklists to compare: first A, then B.",
+	"Вариант A выбран. Теперь выберите вариант B.":             "Option A selected. Now choose option B.",
+	"Необходимо указать ровно два answer_ids":                  "Exactly two answer_ids must be given",
+	"База данных недоступна":                                   "Database unavailable",
+	"Запись %d не найдена":                                     "Record %d not found",
+	"Необходимо указать answer_ids или оба запроса a и b":      "Either answer_ids or both requests a and b must be given",
+
+	// cost.go
+	"прайс-лист не настроен":                "price sheet is not configured",
+	"data_gb не может быть отрицательным":   "data_gb cannot be negative",
+	"instances должно быть не меньше 1":     "instances must be at least 1",
+	"team_size не может быть отрицательным": "team_size cannot be negative",
+	"horizon_years должно быть от 1 до %d":  "horizon_years must be between 1 and %d",
+	"Оценка стоимости владения (TCO):":      "Total cost of ownership (TCO):",
+	"%s: разово %s %s, в год %s %s":         "%s: upfront %s %s, per year %s %s",
+	"Дешевле всего на горизонте %s: %s":     "Cheapest over %s: %s",
+	"%d ТБ": "%d TB",
+	"%d ГБ": "%d GB",
+	"%d г.": "%d yr",
+	"Оценить стоимость владения (TCO) для каждого варианта развертывания?":    "Estimate the total cost of ownership (TCO) for each deployment option?",
+	"Какой объём данных планируется хранить?":                                 "How much data do you plan to store?",
+	"Сколько экземпляров СУБД (с репликами) потребуется?":                     "How many database instances (including replicas) will you need?",
+	"Сколько инженеров будет сопровождать СУБД?":                              "How many engineers will operate the database?",
+	"На какой срок считать стоимость (лет)?":                                  "Over how many years should the cost be calculated?",
+	"Использовать рассчитанную стоимость для баллов экономических критериев?": "Use the estimated cost for the scores of the cost criteria?",
+
+	// cors.go
+	"Запрос с этого источника запрещен политикой CORS": "Requests from this origin are forbidden by the CORS policy",
+
+	// criterion_ids.go
+	"Некорректный запрос: ":                       "Invalid request: ",
+	"неизвестные %s: %s, допустимые значения: %s": "unknown %s: %s, valid values: %s",
+	"%s указаны повторно: %s":                     "%s specified more than once: %s",
+	"критерии":                                    "criteria",
+	"критерии поставщиков":                        "vendor criteria",
+
+	// explain.go
+	"Почему такой результат:": "Why this result:",
+	"Все варианты исключены жёсткими ограничениями, сравнивать баллы не из чего.": "All options are excluded by hard constraints, so there are no scores to compare.",
+	"Однозначной рекомендации нет: варианты (%s) равны по оценке.":                "No clear recommendation: options (%s) are scored equally.",
+	"Однозначной рекомендации нет.":                                               "No clear recommendation.",
+	"Рекомендуется %s: остальные варианты исключены жёсткими ограничениями.":      "%s is recommended: the other options are excluded by hard constraints.",
+	"Рекомендуется %s (метод «%s»), ближайшая альтернатива — %s.":                 "%s is recommended (method «%s»), the closest alternative is %s.",
+	"Рекомендуется %s: %s баллов против %s у %s.":                                 "%s is recommended: %s points against %s for %s.",
+	"Больше всего в пользу %s: %s.":                                               "Most in favour of %s: %s.",
+	"В пользу %s: %s.": "In favour of %s: %s.",
+	"Ни один критерий не даёт перевеса %s.":            "No criterion gives %s an advantage.",
+	"Баллы «%s» переопределены вручную: %s вместо %s.": "Scores for «%s» were overridden manually: %s instead of %s.",
+	"Баллы «%s» рассчитаны из оценки стоимости: %s.":   "Scores for «%s» were derived from the cost estimate: %s.",
+	"Значение «%s» критерия «%s» даёт баллы %s.":       "Value «%s» of «%s» gives scores %s.",
+	"Уточнения по «%s» (%s) дают баллы %s.":            "Refinements for «%s» (%s) give scores %s.",
+	"%s исключён правилом «%s»: %s":                    "%s is excluded by the «%s» rule: %s",
+
+	// followups.go
+	"неизвестный вопрос %q":                                 "unknown question %q",
+	"вопрос %q не применим к выбранным критериям и ответам": "question %q does not apply to the selected criteria and answers",
+	"ответ «%s»: %s": "answer «%s»: %s",
+
+	// history.go
+	"Не удалось загрузить историю.":                                     "Could not load the history.",
+	"У вас пока нет пройденных чеклистов. Чтобы начать, введите /start": "You have no completed checklists yet. To start, type /start",
+	"История чеклистов (страница %d из %d):":                            "Checklist history (page %d of %d):",
+	"Рекомендация: %s":  "Recommendation: %s",
+	"%d. Подробнее":     "%d. Details",
+	"%d. Пройти заново": "%d. Run again",
+	"◀️ Назад":          "◀️ Back",
+	"Вперёд ▶️":         "Next ▶️",
+	"Чеклист от %s\nСохранённая рекомендация: %s": "Checklist of %s\nSaved recommendation: %s",
+	"Рекомендация AI:":                            "AI recommendation:",
+	"Что сделать с этим прохождением?":            "What do you want to do with this run?",
+	"🔁 Пройти заново":                             "🔁 Run again",
+	"Ответы предыдущего прохождения подставлены.": "Answers from the previous run have been filled in.",
+	"нет ответа":                                  "no answer",
+	"Запись не найдена.":                          "Record not found.",
+
+	// methods.go
+	"Взвешенная сумма":                                   "Weighted sum",
+	"Взвешенное произведение":                            "Weighted product",
+	"Сравнение методов принятия решений:":                "Decision method comparison:",
+	"Все методы дают одинаковую рекомендацию.":           "All methods give the same recommendation.",
+	"Методы расходятся — результат стоит перепроверить.": "The methods disagree — the result is worth double-checking.",
+
+	// profiles.go
+	"профиль не найден":             "profile not found",
+	"Не удалось загрузить профили.": "Could not load profiles.",
+	"У вас пока нет сохранённых профилей. Профиль можно сохранить после прохождения чеклиста или из /history.": "You have no saved profiles yet. You can save a profile after completing a checklist or from /history.",
+	"Ваши профили. Выберите профиль, чтобы начать чеклист с его ответами:":                                     "Your profiles. Choose a profile to start the checklist with its answers:",
+	"Профиль не найден.":                  "Profile not found.",
+	"Ответы из профиля «%s» подставлены.": "Answers from profile «%s» have been filled in.",
+	"Не удалось удалить профиль.":         "Could not delete the profile.",
+	"Профиль удалён.":                     "Profile deleted.",
+	"Введите название профиля, например «Платёжный сервис» или «Аналитика»:": "Enter a profile name, for example «Payments service» or «Analytics»:",
+	"Некорректное название: %s. Попробуйте ещё раз.":                         "Invalid name: %s. Please try again.",
+	"Не удалось сохранить профиль.":                                          "Could not save the profile.",
+	"Профиль «%s» сохранён. Список профилей: /profiles":                      "Profile «%s» saved. List of profiles: /profiles",
+	"название профиля не может быть пустым":                                  "profile name cannot be empty",
+	"название профиля длиннее %d символов":                                   "profile name is longer than %d characters",
+	"Ошибка загрузки профиля":                                                "Error loading the profile",
+	"Ошибка загрузки профилей":                                               "Error loading profiles",
+	"Запись ответа не найдена":                                               "Answer record not found",
+	"Необходимо указать input или answer_id":                                 "Either input or answer_id must be given",
+	"Ошибка сохранения профиля":                                              "Error saving the profile",
+	"Некорректный параметр id":                                               "Invalid id parameter",
+	"Ошибка удаления профиля":                                                "Error deleting the profile",
+
+	// sensitivity.go
+	"Устойчивость рекомендации:": "Recommendation stability:",
+	"Лидеры набрали одинаковое количество баллов, рекомендация неустойчива.": "The leaders have equal scores, the recommendation is unstable.",
+	"Остальные варианты исключены ограничениями, %s не зависит от баллов.":   "The other options are excluded by constraints, %s does not depend on the scores.",
+	"Отрыв %s от %s: %s (%.1f%%)": "Lead of %s over %s: %s (%.1f%%)",
+	"Минимальное изменение приоритета: «%s» %s → %s (результат: %s)": "Smallest priority change: «%s» %s → %s (result: %s)",
+	"Никакое одиночное изменение приоритета не меняет результат.":    "No single priority change alters the result.",
+	"Минимальное изменение балла: «%s», %s %s → %s (результат: %s)":  "Smallest score change: «%s», %s %s → %s (result: %s)",
+	"Никакое одиночное изменение балла не меняет результат.":         "No single score change alters the result.",
+	"Вклад критериев в отрыв %s:":                                    "Criterion contributions to the lead of %s:",
+
+	// tiebreak.go
+	"без разрешения": "no resolution",
+	"победы в критериях с наивысшим приоритетом": "wins in the highest-priority criteria",
+	"меньшие начальные инвестиции":               "lower initial investment",
+	"уточняющий вопрос":                          "follow-up question",
+	"вердикт AI":                                 "AI verdict",
+	"ожидается вердикт AI":                       "waiting for the AI verdict",
+	"правило разрешения ничьей не задано":        "no tie-breaking rule is configured",
+	"критерии с наивысшим приоритетом не различают равные варианты": "the highest-priority criteria do not distinguish the tied options",
+	"%s лидирует в %d из %d критериев с наивысшим приоритетом":      "%s leads in %d of %d highest-priority criteria",
+	"у %s наименьшие разовые затраты: %s %s":                        "%s has the lowest upfront cost: %s %s",
+	"разовые затраты равных вариантов совпадают":                    "the tied options have the same upfront cost",
+	"критерий начальных инвестиций не настроен":                     "the initial investment criterion is not configured",
+	"у %s лучший балл по критерию «%s»":                             "%s has the best score for «%s»",
+	"баллы равных вариантов по критерию «%s» совпадают":             "the tied options have the same score for «%s»",
+	"в каталоге нет критериев, различающих равные варианты":         "the catalog has no criteria that distinguish the tied options",
+	"Варианты %s равны по баллам. Что для вас важнее?":              "Options %s have equal scores. What matters more to you?",
+	"ожидается ответ на уточняющий вопрос":                          "waiting for an answer to the follow-up question",
+	"пользователь выбрал критерий «%s»":                             "the user chose «%s»",
+	"ответ %q не соответствует вопросу":                             "answer %q does not match the question",
+	"рекомендация AI недоступна":                                    "the AI recommendation is unavailable",
+	"AI рекомендует %s": "the AI recommends %s",
+	"вердикт AI не называет ни один из равных вариантов":             "the AI verdict names none of the tied options",
+	"Варианты %s равны по баллам, выбор сделан по правилу «%s»: %s.": "Options %s have equal scores, the choice was made by the «%s» rule: %s.",
+	"Ничья между %s не разрешена правилом «%s»: %s.":                 "The tie between %s was not resolved by the «%s» rule: %s.",
+
+	// uncertainty.go
+	"Уверенность в рекомендации (%d случайных прогонов, seed %d):": "Recommendation confidence (%d random runs, seed %d):",
+	"• %s побеждает в %.1f%% случаев":                              "• %s wins in %.1f%% of runs",
+	"• Ничья в %.1f%% случаев":                                     "• Tie in %.1f%% of runs",
+	"min %s больше max %s":                                         "min %s is greater than max %s",
+	"диапазон [%s, %s] выходит за пределы [%s, %s]":                "range [%s, %s] is outside [%s, %s]",
+	"min %d больше max %d":                                         "min %d is greater than max %d",
+	"диапазон [%d, %d] выходит за пределы [%d, %d]":                "range [%d, %d] is outside [%d, %d]",
+	"iterations должно быть от 0 (по умолчанию %d) до %d":          "iterations must be between 0 (default %d) and %d",
+	"priority_spread не может быть отрицательным":                  "priority_spread cannot be negative",
+	"score_spread не может быть отрицательным":                     "score_spread cannot be negative",
+
+	// validation.go
+	"Некорректный запрос":                                                "Invalid request",
+	"Ошибка парсинга JSON: %v":                                           "JSON parse error: %v",
+	"неизвестное поле":                                                   "unknown field",
+	"ожидается %s, получено %s":                                          "expected %s, got %s",
+	"неизвестный критерий %q, допустимые значения: %s":                   "unknown criterion %q, valid values: %s",
+	"критерий %q не выбран в selected_criteria":                          "criterion %q is not selected in selected_criteria",
+	"критерий %q указан повторно":                                        "criterion %q is specified more than once",
+	"приоритет должен быть от %d до %d":                                  "priority must be between %d and %d",
+	"вес не может быть отрицательным":                                    "weight cannot be negative",
+	"балл должен быть от %d до %d":                                       "score must be between %d and %d",
+	"критерий %q не специальный":                                         "criterion %q has no special values",
+	"недопустимое значение %q, допустимые значения: %s":                  "invalid value %q, valid values: %s",
+	"не указано значение специального критерия, допустимые значения: %s": "special criterion value is missing, valid values: %s",
+	"значение сравнения должно быть от 1/9 до 9":                         "comparison value must be between 1/9 and 9",
+	"недопустимый ответ %q, допустимые значения: %s":                     "invalid answer %q, valid values: %s",
+	"неизвестный критерий поставщиков %q, допустимые значения: %s":       "unknown vendor criterion %q, valid values: %s",
+
+	// vendors.go
+	"Поставщики для %s:": "Vendors for %s:",
+	"%d. %s — %s баллов": "%d. %s — %s points",
+	"Для этого результата нет каталога поставщиков.":                      "There is no vendor catalog for this result.",
+	"Сначала завершите текущий чеклист или сбросьте его командой /reset.": "Finish the current checklist first or reset it with /reset.",
+	"Установите приоритет для критерия выбора поставщика:\n\n*%s*\n%s":    "Set the priority for the vendor selection criterion:\n\n*%s*\n%s",
+
+	// veto.go
+	"Исключены жёсткими ограничениями:": "Excluded by hard constraints:",
+	"значение «%s»": "value «%s»",
+	"вес %.1f%%":    "weight %.1f%%",
+	"приоритет %d":  "priority %d",
+
+	// wizard.go
+	"Эта кнопка относится к другому шагу чеклиста. Ответьте на текущий вопрос или начните заново с /start.": "This button belongs to another checklist step. Answer the current question or start over with /start.",
+	"Пожалуйста, используйте кнопки для переопределения весов.":                                             "Please use the buttons to override the weights.",
+
+	// wizard_api.go
+	"Сессия не найдена":   "Session not found",
+	"Возвращаться некуда": "There is no previous question to go back to",
+	"Расчет недоступен: сначала ответьте на вопросы чеклиста": "The result is not available yet: answer the checklist questions first",
+	"Чеклист завершен": "The checklist is finished",
+	"код недействителен или просрочен, получите новый командой бота /web":                                               "the code is invalid or expired, get a new one with the bot command /web",
+	"Не удалось выдать код, попробуйте еще раз.":                                                                        "Could not issue a code, please try again.",
+	"Код для продолжения чеклиста на сайте: `%s`\nКод действует %d минут и подходит один раз. Никому его не сообщайте.": "Code to continue the checklist on the website: `%s`\nThe code is valid for %d minutes and works once. Do not share it with anyone.",
+	"укажите action или text":                     "specify either action or text",
+	"ответ не относится к текущему вопросу":       "the answer does not belong to the current question",
+	"текущий вопрос не принимает текстовый ответ": "the current question does not accept a text answer",
+}
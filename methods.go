@@ -0,0 +1,170 @@
+package main
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+)
+
+const (
+	methodWeightedSum     = "weighted_sum"
+	methodTOPSIS          = "topsis"
+	methodWeightedProduct = "weighted_product"
+)
+
+var decisionMethods = []string{methodWeightedSum, methodTOPSIS, methodWeightedProduct}
+
+var decisionMethodNames = map[string]string{
+	methodWeightedSum:     "Взвешенная сумма",
+	methodTOPSIS:          "TOPSIS",
+	methodWeightedProduct: "Взвешенное произведение",
+}
+
+func isKnownMethod(method string) bool {
+	return method == "" || contains(decisionMethods, method)
+}
+
+// normalizedWeights возвращает нормированные веса критериев (в сумме 1).
+func normalizedWeights(details []CriterionDetail) []float64 {
+	weights := make([]float64, len(details))
+	for i, detail := range details {
+		weights[i] = detail.Weight
+	}
+	return weights
+}
+
+func evaluateMethod(method string, details []CriterionDetail, excluded []bool) MethodResult {
+	weights := normalizedWeights(details)
+	result := MethodResult{Method: method, Criteria: make([]MethodCriterion, 0, len(details))}
+
+	scores := make([]float64, len(deploymentOptions))
+	contributions := make([][]float64, len(details))
+	for i := range contributions {
+		contributions[i] = make([]float64, len(deploymentOptions))
+	}
+
+	switch method {
+	case methodTOPSIS:
+		dBest := make([]float64, len(deploymentOptions))
+		dWorst := make([]float64, len(deploymentOptions))
+		for i, detail := range details {
+			values := detailScores(detail)
+			norm := 0.0
+			for _, v := range values {
+				norm += v * v
+			}
+			norm = math.Sqrt(norm)
+
+			for option, v := range values {
+				if norm > 0 {
+					contributions[i][option] = weights[i] * v / norm
+				}
+			}
+
+			best, worst := contributions[i][0], contributions[i][0]
+			for _, v := range contributions[i] {
+				best = math.Max(best, v)
+				worst = math.Min(worst, v)
+			}
+			for option, v := range contributions[i] {
+				dBest[option] += (v - best) * (v - best)
+				dWorst[option] += (v - worst) * (v - worst)
+			}
+		}
+		for option := range scores {
+			plus, minus := math.Sqrt(dBest[option]), math.Sqrt(dWorst[option])
+			if plus+minus > 0 {
+				scores[option] = minus / (plus + minus)
+			} else {
+				scores[option] = 0.5
+			}
+		}
+	case methodWeightedProduct:
+		for option := range scores {
+			scores[option] = 1
+		}
+		for i, detail := range details {
+			for option, v := range detailScores(detail) {
+				scores[option] *= math.Pow(v, weights[i])
+				if v > 0 {
+					contributions[i][option] = weights[i] * math.Log(v)
+				} else {
+					contributions[i][option] = math.Inf(-1)
+				}
+			}
+		}
+	default:
+		result.Method = methodWeightedSum
+		for i, detail := range details {
+			for option, v := range detailScores(detail) {
+				contributions[i][option] = weights[i] * v
+				scores[option] += contributions[i][option]
+			}
+		}
+	}
+
+	for i, detail := range details {
+		criterion := MethodCriterion{Name: detail.Name, Weight: weights[i]}
+		for option, v := range contributions[i] {
+			if math.IsInf(v, 0) {
+				v = 0
+			}
+			criterion.Contributions = append(criterion.Contributions, OptionScore{Option: deploymentOptions[option].Name, Score: v})
+		}
+		result.Criteria = append(result.Criteria, criterion)
+	}
+
+	for option, score := range scores {
+		result.Scores = append(result.Scores, OptionScore{Option: deploymentOptions[option].Name, Score: score})
+	}
+
+	order := make([]int, len(scores))
+	for i := range order {
+		order[i] = i
+	}
+	sort.SliceStable(order, func(i, j int) bool {
+		if excluded[order[i]] != excluded[order[j]] {
+			return !excluded[order[i]]
+		}
+		return scores[order[i]] > scores[order[j]]+scoreEpsilon
+	})
+	for _, option := range order {
+		result.Ranking = append(result.Ranking, deploymentOptions[option].Name)
+	}
+	result.Outcome = pickRecommendation(scores, excluded)
+	result.Recommendation = recommendationLabel(langRU, result.Outcome)
+
+	return result
+}
+
+func methodsAgree(results []MethodResult) bool {
+	for _, result := range results[1:] {
+		if !sameOutcome(result.Outcome, results[0].Outcome) {
+			return false
+		}
+	}
+	return true
+}
+
+func formatMethodsMessage(lang Lang, results []MethodResult, agree bool) string {
+	var text strings.Builder
+	text.WriteString(tr(lang, "Сравнение методов принятия решений:") + "\n")
+
+	for _, result := range results {
+		var scores []string
+		for _, score := range result.Scores {
+			scores = append(scores, fmt.Sprintf("%s=%.3f", score.Option, score.Score))
+		}
+		text.WriteString(fmt.Sprintf("• %s: %s (%s)\n",
+			tr(lang, decisionMethodNames[result.Method]), recommendationLabel(lang, result.Outcome), strings.Join(scores, ", ")))
+	}
+
+	if agree {
+		text.WriteString("\n" + tr(lang, "Все методы дают одинаковую рекомендацию."))
+	} else {
+		text.WriteString("\n" + tr(lang, "Методы расходятся — результат стоит перепроверить."))
+	}
+
+	return text.String()
+}
@@ -0,0 +1,387 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/jackc/pgx/v5"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+const maxProfileNameLength = 64
+
+var errProfileNotFound = errors.New("профиль не найден")
+
+// profileForAPI возвращает профиль с идентификаторами критериев вместо имен.
+func profileForAPI(p Profile) Profile {
+	p.Input = inputWithCriterionIDs(p.Input)
+	return p
+}
+
+func validateProfileName(lang Lang, name string) (string, error) {
+	name = strings.TrimSpace(name)
+	if name == "" {
+		return "", errors.New(tr(lang, "название профиля не может быть пустым"))
+	}
+	if utf8.RuneCountInString(name) > maxProfileNameLength {
+		return "", errors.New(tr(lang, "название профиля длиннее %d символов", maxProfileNameLength))
+	}
+	return name, nil
+}
+
+// saveProfile сохраняет профиль владельца. Профиль с тем же названием перезаписывается.
+func saveProfile(owner recordOwner, name string, input UserInputData) (*Profile, error) {
+	inputJSON, err := json.Marshal(input)
+	if err != nil {
+		return nil, fmt.Errorf("ошибка сериализации профиля: %w", err)
+	}
+
+	profile := &Profile{Name: name, Input: input}
+	err = pool.QueryRow(context.Background(),
+		`INSERT INTO profiles (`+owner.column+`, name, user_input) VALUES ($1, $2, $3)
+		 ON CONFLICT (`+owner.column+`, name) DO UPDATE SET user_input = EXCLUDED.user_input, created_at = CURRENT_TIMESTAMP
+		 RETURNING id, COALESCE(user_id, 0), created_at`, owner.id, name, inputJSON).Scan(&profile.ID, &profile.UserID, &profile.CreatedAt)
+	if err != nil {
+		return nil, fmt.Errorf("ошибка сохранения профиля: %w", err)
+	}
+
+	return profile, nil
+}
+
+func listProfiles(owner recordOwner) ([]Profile, error) {
+	rows, err := pool.Query(context.Background(),
+		`SELECT id, COALESCE(user_id, 0), name, user_input, created_at FROM profiles
+		 WHERE `+owner.column+` = $1 ORDER BY name`, owner.id)
+	if err != nil {
+		return nil, fmt.Errorf("ошибка чтения профилей: %w", err)
+	}
+	defer rows.Close()
+
+	profiles := []Profile{}
+	for rows.Next() {
+		profile, err := scanProfile(rows)
+		if err != nil {
+			return nil, err
+		}
+		profiles = append(profiles, profile)
+	}
+
+	return profiles, rows.Err()
+}
+
+func loadProfile(owner recordOwner, profileID int64) (*Profile, error) {
+	row := pool.QueryRow(context.Background(),
+		`SELECT id, COALESCE(user_id, 0), name, user_input, created_at FROM profiles
+		 WHERE `+owner.column+` = $1 AND id = $2`, owner.id, profileID)
+
+	profile, err := scanProfile(row)
+	if err != nil {
+		return nil, err
+	}
+	return &profile, nil
+}
+
+func loadProfileByName(owner recordOwner, name string) (*Profile, error) {
+	row := pool.QueryRow(context.Background(),
+		`SELECT id, COALESCE(user_id, 0), name, user_input, created_at FROM profiles
+		 WHERE `+owner.column+` = $1 AND name = $2`, owner.id, name)
+
+	profile, err := scanProfile(row)
+	if err != nil {
+		return nil, err
+	}
+	return &profile, nil
+}
+
+func deleteProfile(owner recordOwner, profileID int64) error {
+	tag, err := pool.Exec(context.Background(),
+		`DELETE FROM profiles WHERE `+owner.column+` = $1 AND id = $2`, owner.id, profileID)
+	if err != nil {
+		return fmt.Errorf("ошибка удаления профиля: %w", err)
+	}
+	if tag.RowsAffected() == 0 {
+		return errProfileNotFound
+	}
+	return nil
+}
+
+func scanProfile(row answerScanner) (Profile, error) {
+	var profile Profile
+	var inputJSON []byte
+
+	err := row.Scan(&profile.ID, &profile.UserID, &profile.Name, &inputJSON, &profile.CreatedAt)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return profile, errProfileNotFound
+	}
+	if err != nil {
+		return profile, fmt.Errorf("ошибка чтения профиля: %w", err)
+	}
+
+	if err := json.Unmarshal(inputJSON, &profile.Input); err != nil {
+		return profile, fmt.Errorf("ошибка разбора профиля %d: %w", profile.ID, err)
+	}
+
+	return profile, nil
+}
+
+func showProfiles(bot *tgbotapi.BotAPI, chatID int64) {
+	lang := userLang(chatID)
+	profiles, err := listProfiles(telegramOwner(chatID))
+	if err != nil {
+		logger.Printf("Ошибка загрузки профилей для chatID %d: %v", chatID, err)
+		sendMessage(bot, tgbotapi.NewMessage(chatID, tr(lang, "Не удалось загрузить профили.")))
+		return
+	}
+
+	if len(profiles) == 0 {
+		sendMessage(bot, tgbotapi.NewMessage(chatID,
+			tr(lang, "У вас пока нет сохранённых профилей. Профиль можно сохранить после прохождения чеклиста или из /history.")))
+		return
+	}
+
+	var keyboardRows [][]tgbotapi.InlineKeyboardButton
+	for _, profile := range profiles {
+		keyboardRows = append(keyboardRows, tgbotapi.NewInlineKeyboardRow(
+			tgbotapi.NewInlineKeyboardButtonData("▶️ "+profile.Name, fmt.Sprintf("prof_start_%d", profile.ID)),
+			tgbotapi.NewInlineKeyboardButtonData("🗑", fmt.Sprintf("prof_del_%d", profile.ID)),
+		))
+	}
+
+	msg := tgbotapi.NewMessage(chatID, tr(lang, "Ваши профили. Выберите профиль, чтобы начать чеклист с его ответами:"))
+	msg.ReplyMarkup = tgbotapi.NewInlineKeyboardMarkup(keyboardRows...)
+	sendMessage(bot, msg)
+}
+
+func handleProfileCallback(bot *tgbotapi.BotAPI, query *tgbotapi.CallbackQuery, chatID int64) {
+	parts := strings.Split(query.Data, "_")
+	if len(parts) != 3 {
+		return
+	}
+
+	id, err := strconv.ParseInt(parts[2], 10, 64)
+	if err != nil {
+		logger.Printf("Некорректный callback профиля: %s", query.Data)
+		return
+	}
+	lang := userLang(chatID)
+
+	switch parts[1] {
+	case "save":
+		if _, err := loadUserAnswer(chatID, id); err != nil {
+			logger.Printf("Ошибка загрузки записи %d для chatID %d: %v", id, chatID, err)
+			sendMessage(bot, tgbotapi.NewMessage(chatID, tr(lang, "Запись не найдена.")))
+			return
+		}
+		state := newUserState()
+		state.PendingAnswerID = id
+		userStates[chatID] = state
+		transition(bot, chatID, stateProfileName)
+	case "start":
+		profile, err := loadProfile(telegramOwner(chatID), id)
+		if err != nil {
+			logger.Printf("Ошибка загрузки профиля %d для chatID %d: %v", id, chatID, err)
+			sendMessage(bot, tgbotapi.NewMessage(chatID, tr(lang, "Профиль не найден.")))
+			return
+		}
+		userStates[chatID] = newStateFromInput(profile.Input)
+
+		logger.LogTelegramAction("Чеклист начат из профиля", map[string]interface{}{
+			"ChatID":  chatID,
+			"Профиль": profile.Name,
+		})
+
+		sendMessage(bot, tgbotapi.NewMessage(chatID, tr(lang, "Ответы из профиля «%s» подставлены.", profile.Name)))
+		transition(bot, chatID, stateReview)
+	case "del":
+		if err := deleteProfile(telegramOwner(chatID), id); err != nil {
+			logger.Printf("Ошибка удаления профиля %d для chatID %d: %v", id, chatID, err)
+			sendMessage(bot, tgbotapi.NewMessage(chatID, tr(lang, "Не удалось удалить профиль.")))
+			return
+		}
+		sendMessage(bot, tgbotapi.NewMessage(chatID, tr(lang, "Профиль удалён.")))
+		showProfiles(bot, chatID)
+	}
+}
+
+func askProfileName(bot *tgbotapi.BotAPI, chatID int64) {
+	sendMessage(bot, tgbotapi.NewMessage(chatID,
+		tr(userLang(chatID), "Введите название профиля, например «Платёжный сервис» или «Аналитика»:")))
+}
+
+func saveProfileFromMessage(bot *tgbotapi.BotAPI, chatID int64, text string) {
+	state := userStates[chatID]
+	lang := userLang(chatID)
+
+	name, err := validateProfileName(lang, text)
+	if err != nil {
+		sendMessage(bot, tgbotapi.NewMessage(chatID, tr(lang, "Некорректное название: %s. Попробуйте ещё раз.", err)))
+		return
+	}
+
+	record, err := loadUserAnswer(chatID, state.PendingAnswerID)
+	if err != nil {
+		logger.Printf("Ошибка загрузки записи %d для chatID %d: %v", state.PendingAnswerID, chatID, err)
+		sendMessage(bot, tgbotapi.NewMessage(chatID, tr(lang, "Запись не найдена.")))
+		delete(userStates, chatID)
+		return
+	}
+
+	input := inputToRequest(record.UserInput)
+	profile, err := saveProfile(telegramOwner(chatID), name, UserInputData{
+		SelectedCriteria:   input.SelectedCriteria,
+		CriteriaPriorities: input.CriteriaPriorities,
+		OverriddenScores:   input.OverriddenScores,
+		SpecialValues:      input.SpecialValues,
+	})
+	if err != nil {
+		logger.Printf("Ошибка сохранения профиля для chatID %d: %v", chatID, err)
+		sendMessage(bot, tgbotapi.NewMessage(chatID, tr(lang, "Не удалось сохранить профиль.")))
+		return
+	}
+
+	logger.LogTelegramAction("Профиль сохранен", map[string]interface{}{
+		"ChatID":    chatID,
+		"ProfileID": profile.ID,
+		"Название":  profile.Name,
+	})
+
+	delete(userStates, chatID)
+	sendMessage(bot, tgbotapi.NewMessage(chatID,
+		tr(lang, "Профиль «%s» сохранён. Список профилей: /profiles", profile.Name)))
+}
+
+// profilesHandler обслуживает /profiles: GET возвращает профили клиента API (или один
+// профиль по name), POST сохраняет профиль, DELETE удаляет его по id. Профили принадлежат
+// ключу API запроса; профили пользователей Telegram через API недоступны.
+func profilesHandler(w http.ResponseWriter, r *http.Request) {
+	lang := requestLang(r)
+	w.Header().Set("Content-Language", string(lang))
+
+	client := apiClientFrom(r)
+	if client == nil {
+		w.Header().Set("WWW-Authenticate", "Bearer")
+		http.Error(w, tr(lang, "Требуется ключ API: заголовок Authorization: Bearer <ключ>"), http.StatusUnauthorized)
+		return
+	}
+	if pool == nil {
+		http.Error(w, tr(lang, "База данных недоступна"), http.StatusServiceUnavailable)
+		return
+	}
+	owner := apiKeyOwner(client)
+
+	switch r.Method {
+	case "GET":
+		if name := r.URL.Query().Get("name"); name != "" {
+			profile, err := loadProfileByName(owner, name)
+			if errors.Is(err, errProfileNotFound) {
+				http.Error(w, tr(lang, err.Error()), http.StatusNotFound)
+				return
+			}
+			if err != nil {
+				logger.Printf("Ошибка загрузки профиля через API: %v", err)
+				http.Error(w, tr(lang, "Ошибка загрузки профиля"), http.StatusInternalServerError)
+				return
+			}
+			writeJSON(w, http.StatusOK, profileForAPI(*profile))
+			return
+		}
+
+		profiles, err := listProfiles(owner)
+		if err != nil {
+			logger.Printf("Ошибка загрузки профилей через API: %v", err)
+			http.Error(w, tr(lang, "Ошибка загрузки профилей"), http.StatusInternalServerError)
+			return
+		}
+		for i := range profiles {
+			profiles[i] = profileForAPI(profiles[i])
+		}
+		writeJSON(w, http.StatusOK, profiles)
+	case "POST":
+		var req ProfileRequest
+		decoder := json.NewDecoder(r.Body)
+		decoder.DisallowUnknownFields()
+		if err := decoder.Decode(&req); err != nil {
+			http.Error(w, tr(lang, "Ошибка парсинга JSON: ")+err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		name, err := validateProfileName(lang, req.Name)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		var input UserInputData
+		switch {
+		case req.Input != nil:
+			input = *req.Input
+			err := legacyCriterionNames(w, r, lang, func(m, _ *keyMapper) { mapInputCriteria(&input, m) })
+			if err == nil {
+				err = resolveInputIDs(lang, &input)
+			}
+			if err != nil {
+				http.Error(w, tr(lang, "Некорректный запрос: ")+err.Error(), http.StatusBadRequest)
+				return
+			}
+		case req.AnswerID != 0:
+			record, err := loadAnswer(owner, req.AnswerID)
+			if err != nil {
+				http.Error(w, tr(lang, "Запись ответа не найдена"), http.StatusNotFound)
+				return
+			}
+			input = record.UserInput
+		default:
+			http.Error(w, tr(lang, "Необходимо указать input или answer_id"), http.StatusBadRequest)
+			return
+		}
+
+		input.SelectedCriteria = inputToRequest(input).SelectedCriteria
+		if len(input.SelectedCriteria) == 0 {
+			http.Error(w, tr(lang, "Необходимо выбрать хотя бы один критерий"), http.StatusBadRequest)
+			return
+		}
+
+		profile, err := saveProfile(owner, name, input)
+		if err != nil {
+			logger.Printf("Ошибка сохранения профиля через API: %v", err)
+			http.Error(w, tr(lang, "Ошибка сохранения профиля"), http.StatusInternalServerError)
+			return
+		}
+		writeJSON(w, http.StatusOK, profileForAPI(*profile))
+	case "DELETE":
+		profileID, err := strconv.ParseInt(r.URL.Query().Get("id"), 10, 64)
+		if err != nil {
+			http.Error(w, tr(lang, "Некорректный параметр id"), http.StatusBadRequest)
+			return
+		}
+
+		err = deleteProfile(owner, profileID)
+		if errors.Is(err, errProfileNotFound) {
+			http.Error(w, tr(lang, err.Error()), http.StatusNotFound)
+			return
+		}
+		if err != nil {
+			logger.Printf("Ошибка удаления профиля через API: %v", err)
+			http.Error(w, tr(lang, "Ошибка удаления профиля"), http.StatusInternalServerError)
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+	default:
+		http.Error(w, tr(lang, "Метод не поддерживается"), http.StatusMethodNotAllowed)
+	}
+}
+
+func writeJSON(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	if err := json.NewEncoder(w).Encode(v); err != nil {
+		logger.Printf("Ошибка записи JSON ответа: %v", err)
+	}
+}
@@ -0,0 +1,214 @@
+package main
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+)
+
+const (
+	minScore = 1
+	maxScore = 10
+
+	// scoreEpsilon — допуск при сравнении дробных итогов.
+	scoreEpsilon = 1e-9
+)
+
+func responseTotals(response *RecommendationResponse) []float64 {
+	return scoreVector(response.Totals)
+}
+
+func detailScores(detail CriterionDetail) []float64 {
+	return scoreVector(detail.Scores)
+}
+
+func detailWeighted(detail CriterionDetail) []float64 {
+	return scoreVector(detail.Weighted)
+}
+
+// rankOptions возвращает индексы вариантов по убыванию итога; исключенные варианты
+// идут последними, при равенстве сохраняется порядок deploymentOptions.
+func rankOptions(totals []float64, excluded []bool) []int {
+	order := make([]int, len(totals))
+	for i := range order {
+		order[i] = i
+	}
+	sort.SliceStable(order, func(i, j int) bool {
+		if excluded[order[i]] != excluded[order[j]] {
+			return !excluded[order[i]]
+		}
+		return totals[order[i]] > totals[order[j]]+scoreEpsilon
+	})
+	return order
+}
+
+// totalsWithPriorities пересчитывает итоги, если критерию index назначить приоритет prio.
+// Веса остальных критериев перенормируются.
+func totalsWithPriorities(details []CriterionDetail, index, prio int) []float64 {
+	totals := make([]float64, len(deploymentOptions))
+	sum := 0
+	for i, detail := range details {
+		p := detail.Priority
+		if i == index {
+			p = prio
+		}
+		sum += p
+		for option, score := range detailScores(detail) {
+			totals[option] += float64(p) * score
+		}
+	}
+	for option := range totals {
+		totals[option] /= float64(sum)
+	}
+	return totals
+}
+
+// analyzeSensitivity оценивает устойчивость рекомендации: отрыв лидера, минимальные
+// одиночные изменения приоритета и балла, меняющие результат, и вклад критериев в отрыв.
+func analyzeSensitivity(response *RecommendationResponse) *SensitivityReport {
+	totals := responseTotals(response)
+	excluded := excludedMask(response.Excluded)
+	order := rankOptions(totals, excluded)
+	winner, runnerUp := order[0], order[1]
+
+	report := &SensitivityReport{Contributions: []CriterionContribution{}}
+
+	if excluded[winner] {
+		return report
+	}
+	if excluded[runnerUp] {
+		report.Winner = deploymentOptions[winner].Name
+		return report
+	}
+
+	report.Margin = totals[winner] - totals[runnerUp]
+	if report.Margin <= scoreEpsilon {
+		report.Margin = 0
+		return report
+	}
+
+	report.Winner = deploymentOptions[winner].Name
+	report.RunnerUp = deploymentOptions[runnerUp].Name
+	report.MarginPercent = report.Margin * 100 / totals[winner]
+
+	for _, detail := range response.Details {
+		weighted := detailWeighted(detail)
+		report.Contributions = append(report.Contributions, CriterionContribution{
+			ID:   detail.ID,
+			Name: detail.Name,
+			Lead: weighted[winner] - weighted[runnerUp],
+		})
+	}
+	sort.SliceStable(report.Contributions, func(i, j int) bool {
+		return report.Contributions[i].Lead > report.Contributions[j].Lead
+	})
+
+	flips := func(newTotals []float64) bool {
+		for i, total := range newTotals {
+			if i != winner && !excluded[i] && total >= newTotals[winner]-scoreEpsilon {
+				return true
+			}
+		}
+		return false
+	}
+
+	for index, detail := range response.Details {
+		scores := detailScores(detail)
+
+		// Для весов AHP приоритета нет (Priority = 0), шкала приоритетов к ним неприменима.
+		for prio := priorityScale.Min; prio <= priorityScale.Max && detail.Priority > 0; prio++ {
+			delta := float64(prio - detail.Priority)
+			if delta == 0 || (report.PriorityFlip != nil && math.Abs(delta) >= math.Abs(report.PriorityFlip.To-report.PriorityFlip.From)) {
+				continue
+			}
+
+			newTotals := totalsWithPriorities(response.Details, index, prio)
+			if flips(newTotals) {
+				outcome := pickRecommendation(newTotals, excluded)
+				report.PriorityFlip = &FlipChange{
+					CriterionID:       detail.ID,
+					Criterion:         detail.Name,
+					Kind:              "priority",
+					From:              float64(detail.Priority),
+					To:                float64(prio),
+					NewRecommendation: recommendationLabel(langRU, outcome),
+					NewOutcome:        outcome,
+				}
+			}
+		}
+
+		for option := range scores {
+			for score := float64(minScore); score <= maxScore; score++ {
+				delta := score - scores[option]
+				if delta == 0 || (report.ScoreFlip != nil && math.Abs(delta) >= math.Abs(report.ScoreFlip.To-report.ScoreFlip.From)) {
+					continue
+				}
+
+				newTotals := append([]float64(nil), totals...)
+				newTotals[option] += delta * detail.Weight
+				if flips(newTotals) {
+					outcome := pickRecommendation(newTotals, excluded)
+					report.ScoreFlip = &FlipChange{
+						CriterionID:       detail.ID,
+						Criterion:         detail.Name,
+						Kind:              "score",
+						Option:            deploymentOptions[option].Name,
+						From:              scores[option],
+						To:                score,
+						NewRecommendation: recommendationLabel(langRU, outcome),
+						NewOutcome:        outcome,
+					}
+				}
+			}
+		}
+	}
+
+	return report
+}
+
+func formatSensitivityMessage(lang Lang, report *SensitivityReport) string {
+	if report == nil {
+		return ""
+	}
+
+	var text strings.Builder
+	text.WriteString(tr(lang, "Устойчивость рекомендации:") + "\n")
+
+	if report.Winner == "" {
+		text.WriteString(tr(lang, "Лидеры набрали одинаковое количество баллов, рекомендация неустойчива."))
+		return text.String()
+	}
+	if report.RunnerUp == "" {
+		text.WriteString(tr(lang, "Остальные варианты исключены ограничениями, %s не зависит от баллов.", report.Winner))
+		return text.String()
+	}
+
+	text.WriteString(tr(lang, "Отрыв %s от %s: %s (%.1f%%)",
+		report.Winner, report.RunnerUp, formatNumber(report.Margin), report.MarginPercent) + "\n")
+
+	if flip := report.PriorityFlip; flip != nil {
+		text.WriteString(tr(lang, "Минимальное изменение приоритета: «%s» %s → %s (результат: %s)",
+			criterionLabel(lang, flip.Criterion), formatNumber(flip.From), formatNumber(flip.To),
+			recommendationLabel(lang, flip.NewOutcome)) + "\n")
+	} else {
+		text.WriteString(tr(lang, "Никакое одиночное изменение приоритета не меняет результат.") + "\n")
+	}
+
+	if flip := report.ScoreFlip; flip != nil {
+		text.WriteString(tr(lang, "Минимальное изменение балла: «%s», %s %s → %s (результат: %s)",
+			criterionLabel(lang, flip.Criterion), flip.Option, formatNumber(flip.From), formatNumber(flip.To),
+			recommendationLabel(lang, flip.NewOutcome)) + "\n")
+	} else {
+		text.WriteString(tr(lang, "Никакое одиночное изменение балла не меняет результат.") + "\n")
+	}
+
+	if len(report.Contributions) > 0 {
+		text.WriteString("\n" + tr(lang, "Вклад критериев в отрыв %s:", report.Winner) + "\n")
+		for _, contribution := range report.Contributions {
+			text.WriteString(fmt.Sprintf("• %s: %s\n", criterionLabel(lang, contribution.Name), formatDelta(contribution.Lead)))
+		}
+	}
+
+	return text.String()
+}
@@ -0,0 +1,342 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+
+	"tg-bot-checklist/api"
+	"tg-bot-checklist/client"
+)
+
+// Контрактная проверка: запросы к работающему серверу, ответы которых сверяются со
+// спецификацией /api/v1/openapi.json — код ответа должен быть описан у операции, тело —
+// соответствовать схеме.
+
+type contractChecker struct {
+	base     string
+	spec     map[string]interface{}
+	failures []string
+	checked  map[string]bool
+}
+
+func runContractTests(base string) bool {
+	spec, err := client.New(base).OpenAPI(context.Background())
+	if err != nil {
+		fmt.Printf("Не удалось загрузить спецификацию: %v\n", err)
+		return false
+	}
+	server, _ := spec["servers"].([]interface{})[0].(map[string]interface{})["url"].(string)
+	c := &contractChecker{base: strings.TrimRight(base, "/") + server, spec: spec, checked: map[string]bool{}}
+
+	c.check("GET", "/openapi.json", "/openapi.json", nil, nil)
+
+	etag := c.check("GET", "/criteria", "/criteria", nil, nil).Header.Get("ETag")
+	c.check("GET", "/criteria", "/criteria", nil, map[string]string{"If-None-Match": etag})
+
+	requests := generateRequests(3)
+	full := requests[0]
+	full.CompareMethods = true
+	full.Uncertainty = &api.UncertaintyOptions{Iterations: 50, Seed: 1}
+	full.Cost = &api.CostInput{DataGB: 500, Instances: 2, TeamSize: 2, HorizonYears: 5}
+	full.Vendors = &api.VendorRequest{}
+	c.check("POST", "/recommend", "/recommend", full, nil)
+	c.check("POST", "/recommend", "/recommend", requests[1], nil)
+	c.check("POST", "/recommend", "/recommend",
+		map[string]interface{}{"selected_criteria": []string{"no_such_criterion"}, "unknown": 1}, nil)
+	c.check("POST", "/compare", "/compare", api.CompareRequest{A: &requests[1], B: &requests[2]}, nil)
+
+	c.check("GET", "/profiles", "/profiles", nil, nil)
+
+	var view api.WizardView
+	resp := c.check("POST", "/wizard/sessions", "/wizard/sessions", nil, nil)
+	if json.Unmarshal(resp.Body, &view) == nil && view.SessionID != "" {
+		path := "/wizard/sessions/" + view.SessionID
+		c.check("GET", "/wizard/sessions/{id}", path, nil, nil)
+		c.check("POST", "/wizard/sessions/{id}/answer", path+"/answer", api.WizardAnswerRequest{Action: "bogus"}, nil)
+		c.check("POST", "/wizard/sessions/{id}/back", path+"/back", nil, nil)
+		c.check("POST", "/wizard/sessions/{id}/finalize", path+"/finalize", nil, nil)
+		if len(view.Answers) > 0 {
+			c.check("POST", "/wizard/sessions/{id}/answer", path+"/answer",
+				api.WizardAnswerRequest{Action: view.Answers[0].Action}, nil)
+		}
+		c.check("DELETE", "/wizard/sessions/{id}", path, nil, nil)
+		c.check("GET", "/wizard/sessions/{id}", path, nil, nil)
+	}
+
+	c.checkLegacy(base)
+
+	var uncovered []string
+	for path, item := range c.spec["paths"].(map[string]interface{}) {
+		for method := range item.(map[string]interface{}) {
+			if op := strings.ToUpper(method) + " " + path; !c.checked[op] {
+				uncovered = append(uncovered, op)
+			}
+		}
+	}
+	sort.Strings(uncovered)
+	if len(uncovered) > 0 {
+		fmt.Printf("Не проверены: %s\n", strings.Join(uncovered, ", "))
+	}
+
+	if len(c.failures) > 0 {
+		fmt.Printf("\nКонтракт нарушен (%d):\n", len(c.failures))
+		for _, failure := range c.failures {
+			fmt.Println("  " + failure)
+		}
+		return false
+	}
+	fmt.Println("\nОтветы соответствуют спецификации")
+	return true
+}
+
+type contractResponse struct {
+	Status int
+	Header http.Header
+	Body   []byte
+}
+
+// check выполняет запрос и сверяет ответ с операцией specPath спецификации.
+func (c *contractChecker) check(method, specPath, path string, body interface{}, headers map[string]string) contractResponse {
+	name := method + " " + specPath
+	c.checked[name] = true
+
+	var reader io.Reader
+	if body != nil {
+		data, _ := json.Marshal(body)
+		reader = bytes.NewReader(data)
+	}
+	req, err := http.NewRequest(method, c.base+path, reader)
+	if err != nil {
+		c.fail(name, "%v", err)
+		return contractResponse{}
+	}
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	if apiKey != "" {
+		req.Header.Set("Authorization", "Bearer "+apiKey)
+	}
+	for k, v := range headers {
+		req.Header.Set(k, v)
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		c.fail(name, "%v", err)
+		return contractResponse{}
+	}
+	defer resp.Body.Close()
+	data, _ := io.ReadAll(resp.Body)
+	result := contractResponse{Status: resp.StatusCode, Header: resp.Header, Body: data}
+	fmt.Printf("%-45s %s → %d\n", name, path, resp.StatusCode)
+
+	op, _ := c.lookup("paths", specPath, strings.ToLower(method)).(map[string]interface{})
+	if op == nil {
+		c.fail(name, "операции нет в спецификации")
+		return result
+	}
+	response, _ := op["responses"].(map[string]interface{})[strconv.Itoa(resp.StatusCode)].(map[string]interface{})
+	if response == nil {
+		c.fail(name, "код %d не описан", resp.StatusCode)
+		return result
+	}
+
+	content, _ := response["content"].(map[string]interface{})
+	if len(content) == 0 {
+		if len(bytes.TrimSpace(data)) > 0 {
+			c.fail(name, "код %d описан без тела, получено тело", resp.StatusCode)
+		}
+		return result
+	}
+	contentType, _, _ := strings.Cut(resp.Header.Get("Content-Type"), ";")
+	media, _ := content[contentType].(map[string]interface{})
+	if media == nil {
+		c.fail(name, "тип %q для кода %d не описан", contentType, resp.StatusCode)
+		return result
+	}
+	if contentType != "application/json" {
+		return result
+	}
+
+	var value interface{}
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber()
+	if err := decoder.Decode(&value); err != nil {
+		c.fail(name, "невалидный JSON: %v", err)
+		return result
+	}
+	for _, problem := range c.validate(media["schema"].(map[string]interface{}), value, "$") {
+		c.fail(name, "%s", problem)
+	}
+	return result
+}
+
+// checkLegacy проверяет, что маршруты без версии отвечают с заголовками устаревания.
+func (c *contractChecker) checkLegacy(base string) {
+	name := "GET /api/criteria (legacy)"
+	resp, err := http.Get(strings.TrimRight(base, "/") + "/api/criteria")
+	if err != nil {
+		c.fail(name, "%v", err)
+		return
+	}
+	resp.Body.Close()
+	fmt.Printf("%-45s %s → %d\n", name, "/api/criteria", resp.StatusCode)
+	for _, header := range []string{"Deprecation", "Sunset", "Link"} {
+		if resp.Header.Get(header) == "" {
+			c.fail(name, "нет заголовка %s", header)
+		}
+	}
+}
+
+func (c *contractChecker) fail(name, format string, args ...interface{}) {
+	c.failures = append(c.failures, name+": "+fmt.Sprintf(format, args...))
+}
+
+// lookup достает узел спецификации по цепочке ключей.
+func (c *contractChecker) lookup(keys ...string) interface{} {
+	var node interface{} = c.spec
+	for _, key := range keys {
+		m, ok := node.(map[string]interface{})
+		if !ok {
+			return nil
+		}
+		node = m[key]
+	}
+	return node
+}
+
+func (c *contractChecker) resolve(schema map[string]interface{}) map[string]interface{} {
+	for {
+		ref, ok := schema["$ref"].(string)
+		if !ok {
+			return schema
+		}
+		name := strings.TrimPrefix(ref, "#/components/schemas/")
+		schema, _ = c.lookup("components", "schemas", name).(map[string]interface{})
+		if schema == nil {
+			return map[string]interface{}{}
+		}
+	}
+}
+
+// validate проверяет значение по подмножеству JSON Schema, которое использует спецификация.
+func (c *contractChecker) validate(schema map[string]interface{}, value interface{}, path string) []string {
+	schema = c.resolve(schema)
+	if value == nil {
+		if nullable, _ := schema["nullable"].(bool); nullable {
+			return nil
+		}
+	}
+	if allOf, ok := schema["allOf"].([]interface{}); ok {
+		var problems []string
+		for _, sub := range allOf {
+			problems = append(problems, c.validate(sub.(map[string]interface{}), value, path)...)
+		}
+		return problems
+	}
+	if oneOf, ok := schema["oneOf"].([]interface{}); ok {
+		matched := 0
+		for _, sub := range oneOf {
+			if len(c.validate(sub.(map[string]interface{}), value, path)) == 0 {
+				matched++
+			}
+		}
+		if matched != 1 {
+			return []string{fmt.Sprintf("%s: подходит схем oneOf: %d", path, matched)}
+		}
+		return nil
+	}
+
+	typ, _ := schema["type"].(string)
+	switch typ {
+	case "":
+		return nil
+	case "object":
+		obj, ok := value.(map[string]interface{})
+		if !ok {
+			return []string{fmt.Sprintf("%s: ожидается object, получено %s", path, jsonKind(value))}
+		}
+		properties, _ := schema["properties"].(map[string]interface{})
+		var problems []string
+		keys := make([]string, 0, len(obj))
+		for key := range obj {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+		for _, key := range keys {
+			if properties != nil {
+				if prop, ok := properties[key].(map[string]interface{}); ok {
+					problems = append(problems, c.validate(prop, obj[key], path+"."+key)...)
+					continue
+				}
+			}
+			switch additional := schema["additionalProperties"].(type) {
+			case bool:
+				if !additional {
+					problems = append(problems, fmt.Sprintf("%s: поле %q не описано", path, key))
+				}
+			case map[string]interface{}:
+				problems = append(problems, c.validate(additional, obj[key], path+"."+key)...)
+			}
+		}
+		return problems
+	case "array":
+		arr, ok := value.([]interface{})
+		if !ok {
+			return []string{fmt.Sprintf("%s: ожидается array, получено %s", path, jsonKind(value))}
+		}
+		items, _ := schema["items"].(map[string]interface{})
+		var problems []string
+		for i, item := range arr {
+			problems = append(problems, c.validate(items, item, fmt.Sprintf("%s[%d]", path, i))...)
+		}
+		return problems
+	case "integer":
+		n, ok := value.(json.Number)
+		if f, err := n.Float64(); !ok || err != nil || f != math.Trunc(f) {
+			return []string{fmt.Sprintf("%s: ожидается integer, получено %s", path, jsonKind(value))}
+		}
+	case "number":
+		if _, ok := value.(json.Number); !ok {
+			return []string{fmt.Sprintf("%s: ожидается number, получено %s", path, jsonKind(value))}
+		}
+	case "string":
+		if _, ok := value.(string); !ok {
+			return []string{fmt.Sprintf("%s: ожидается string, получено %s", path, jsonKind(value))}
+		}
+	case "boolean":
+		if _, ok := value.(bool); !ok {
+			return []string{fmt.Sprintf("%s: ожидается boolean, получено %s", path, jsonKind(value))}
+		}
+	}
+	return nil
+}
+
+func jsonKind(value interface{}) string {
+	switch v := value.(type) {
+	case nil:
+		return "null"
+	case map[string]interface{}:
+		return "object"
+	case []interface{}:
+		return "array"
+	case string:
+		return "string"
+	case bool:
+		return "boolean"
+	case json.Number:
+		if strings.ContainsAny(v.String(), ".eE") {
+			return "number"
+		}
+		return "integer"
+	default:
+		return fmt.Sprintf("%T", v)
+	}
+}
@@ -1,3 +1,7 @@
 module tests
 
 go 1.24.1
+
+require tg-bot-checklist v0.0.0
+
+replace tg-bot-checklist => ../
@@ -11,59 +11,52 @@ import (
 	"os"
 	"sync"
 	"time"
-)
 
-type Scores struct {
-	OnPrem  int `json:"on_prem"`
-	Private int `json:"private"`
-	Public  int `json:"public"`
-}
+	"tg-bot-checklist/api"
+)
 
-type RecommendationRequest struct {
-	SelectedCriteria   []string          `json:"selected_criteria"`
-	CriteriaPriorities map[string]int    `json:"criteria_priorities"`
-	SpecialValues      map[string]string `json:"special_values"`
-}
+// Критерии, их специальные значения и шкала приоритетов заполняются из /api/v1/criteria.
+var (
+	allCriteria        []string
+	specialCriteria    = map[string][]string{}
+	specialCriteriaIDs []string
+	minPriority        = 1
+	maxPriority        = 5
+)
 
-type CriterionDetail struct {
-	Name            string `json:"name"`
-	Priority        int    `json:"priority"`
-	Source          string `json:"source"`
-	OnPremScore     int    `json:"on_prem_score"`
-	PrivateScore    int    `json:"private_score"`
-	PublicScore     int    `json:"public_score"`
-	OnPremWeighted  int    `json:"on_prem_weighted"`
-	PrivateWeighted int    `json:"private_weighted"`
-	PublicWeighted  int    `json:"public_weighted"`
-}
+// apiKey передается в Authorization всех запросов, кроме каталога.
+var apiKey string
 
-type RecommendationResponse struct {
-	OnPremTotal    int               `json:"on_prem_total"`
-	PrivateTotal   int               `json:"private_total"`
-	PublicTotal    int               `json:"public_total"`
-	Recommendation string            `json:"recommendation"`
-	Details        []CriterionDetail `json:"details"`
-	AIAnalysis     string            `json:"ai_analysis,omitempty"`
-}
-
-var allCriteria = []string{
-	"Юрисдикция данных",
-	"Отраслевые стандарты",
-	"Физическая безопасность",
-	"Объём данных",
-	"Латентность",
-	"Вариативность нагрузки",
-	"Начальные инвестиции",
-	"Постоянные затраты",
-	"Срок использования",
-	"Квалификация персонала",
-	"Время до запуска",
-	"Масштабируемость",
-}
+func loadCatalog(url string) error {
+	resp, err := http.Get(url)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("статус %d", resp.StatusCode)
+	}
 
-var specialCriteria = map[string][]string{
-	"Объём данных":       {"Малый", "Средний", "Большой"},
-	"Срок использования": {"Краткосрочный", "Долгосрочный"},
+	var catalog api.CatalogResponse
+	if err := json.NewDecoder(resp.Body).Decode(&catalog); err != nil {
+		return err
+	}
+	minPriority, maxPriority = catalog.PriorityScale.Min, catalog.PriorityScale.Max
+	for _, crit := range catalog.Criteria {
+		allCriteria = append(allCriteria, crit.ID)
+		if !crit.IsSpecial {
+			continue
+		}
+		specialCriteriaIDs = append(specialCriteriaIDs, crit.ID)
+		for _, option := range crit.SpecialOptions {
+			specialCriteria[crit.ID] = append(specialCriteria[crit.ID], option.Value)
+		}
+	}
+	if len(allCriteria) < 2 {
+		return fmt.Errorf("в каталоге меньше двух критериев")
+	}
+	fmt.Printf("Каталог версии %s: критериев %d\n", catalog.Version, len(allCriteria))
+	return nil
 }
 
 type RequestStats struct {
@@ -71,20 +64,36 @@ type RequestStats struct {
 	StatusCode     int
 	Error          error
 	Recommendation string
-	OnPremTotal    int
-	PrivateTotal   int
-	PublicTotal    int
+	OnPremTotal    float64
+	PrivateTotal   float64
+	PublicTotal    float64
 }
 
 func main() {
-	url := flag.String("url", "http://localhost:8080/api/recommend", "URL API рекомендаций")
+	url := flag.String("url", "http://localhost:8080/api/v1/recommend", "URL API рекомендаций")
+	catalogURL := flag.String("catalog", "http://localhost:8080/api/v1/criteria", "URL каталога критериев")
 	concurrency := flag.Int("c", 10, "Количество параллельных запросов")
 	total := flag.Int("n", 100, "Общее количество запросов")
 	delay := flag.Int("delay", 0, "Задержка между запросами в мс")
 	outputFile := flag.String("o", "", "Файл для записи результатов (JSON)")
 	verbose := flag.Bool("v", false, "Подробный вывод")
+	contract := flag.Bool("contract", false, "Проверить ответы API по спецификации /api/v1/openapi.json вместо нагрузочного теста")
+	base := flag.String("base", "http://localhost:8080", "Адрес сервера для контрактной проверки")
+	flag.StringVar(&apiKey, "key", os.Getenv("API_KEY"), "Ключ API (по умолчанию из API_KEY)")
 	flag.Parse()
 
+	if err := loadCatalog(*catalogURL); err != nil {
+		fmt.Printf("Не удалось загрузить каталог %s: %v\n", *catalogURL, err)
+		os.Exit(1)
+	}
+
+	if *contract {
+		if !runContractTests(*base) {
+			os.Exit(1)
+		}
+		return
+	}
+
 	fmt.Printf("Начинаем нагрузочное тестирование API %s\n", *url)
 	fmt.Printf("Параметры: %d запросов, %d параллельных потоков\n", *total, *concurrency)
 
@@ -103,12 +112,12 @@ func main() {
 	}
 }
 
-func generateRequests(count int) []RecommendationRequest {
+func generateRequests(count int) []api.RecommendationRequest {
 	rand.Seed(time.Now().UnixNano())
-	requests := make([]RecommendationRequest, count)
+	requests := make([]api.RecommendationRequest, count)
 
 	for i := 0; i < count; i++ {
-		req := RecommendationRequest{
+		req := api.RecommendationRequest{
 			CriteriaPriorities: make(map[string]int),
 			SpecialValues:      make(map[string]string),
 		}
@@ -123,7 +132,7 @@ func generateRequests(count int) []RecommendationRequest {
 		req.SelectedCriteria = shuffledCriteria[:numCriteria]
 
 		for _, criterion := range req.SelectedCriteria {
-			req.CriteriaPriorities[criterion] = rand.Intn(5) + 1
+			req.CriteriaPriorities[criterion] = minPriority + rand.Intn(maxPriority-minPriority+1)
 		}
 
 		for criterion, values := range specialCriteria {
@@ -136,33 +145,33 @@ func generateRequests(count int) []RecommendationRequest {
 	}
 
 	if count > 5 {
-		allCriteriaReq := RecommendationRequest{
+		allCriteriaReq := api.RecommendationRequest{
 			SelectedCriteria:   allCriteria,
 			CriteriaPriorities: make(map[string]int),
 			SpecialValues:      make(map[string]string),
 		}
 		for _, criterion := range allCriteria {
-			allCriteriaReq.CriteriaPriorities[criterion] = 3
+			allCriteriaReq.CriteriaPriorities[criterion] = (minPriority + maxPriority) / 2
 		}
 		for criterion, values := range specialCriteria {
 			allCriteriaReq.SpecialValues[criterion] = values[0]
 		}
 		requests[0] = allCriteriaReq
 
-		singleCriterionReq := RecommendationRequest{
-			SelectedCriteria:   []string{"Юрисдикция данных"},
-			CriteriaPriorities: map[string]int{"Юрисдикция данных": 5},
+		singleCriterionReq := api.RecommendationRequest{
+			SelectedCriteria:   allCriteria[:1],
+			CriteriaPriorities: map[string]int{allCriteria[0]: maxPriority},
 			SpecialValues:      make(map[string]string),
 		}
 		requests[1] = singleCriterionReq
 
-		specialCriteriaReq := RecommendationRequest{
-			SelectedCriteria:   []string{"Объём данных", "Срок использования"},
+		specialCriteriaReq := api.RecommendationRequest{
+			SelectedCriteria:   specialCriteriaIDs,
 			CriteriaPriorities: make(map[string]int),
 			SpecialValues:      make(map[string]string),
 		}
 		for _, criterion := range specialCriteriaReq.SelectedCriteria {
-			specialCriteriaReq.CriteriaPriorities[criterion] = 4
+			specialCriteriaReq.CriteriaPriorities[criterion] = maxPriority - 1
 			if values, ok := specialCriteria[criterion]; ok {
 				specialCriteriaReq.SpecialValues[criterion] = values[len(values)-1]
 			}
@@ -173,7 +182,7 @@ func generateRequests(count int) []RecommendationRequest {
 	return requests
 }
 
-func runLoadTest(requests []RecommendationRequest, url string, concurrency, delay int, verbose bool) []RequestStats {
+func runLoadTest(requests []api.RecommendationRequest, url string, concurrency, delay int, verbose bool) []RequestStats {
 	totalRequests := len(requests)
 	stats := make([]RequestStats, totalRequests)
 
@@ -187,7 +196,7 @@ func runLoadTest(requests []RecommendationRequest, url string, concurrency, dela
 		wg.Add(1)
 		sem <- true
 
-		go func(reqIndex int, request RecommendationRequest) {
+		go func(reqIndex int, request api.RecommendationRequest) {
 			defer func() {
 				<-sem
 				wg.Done()
@@ -225,7 +234,7 @@ func runLoadTest(requests []RecommendationRequest, url string, concurrency, dela
 	return stats
 }
 
-func sendRequest(req RecommendationRequest, url string, verbose bool) RequestStats {
+func sendRequest(req api.RecommendationRequest, url string, verbose bool) RequestStats {
 	stat := RequestStats{}
 
 	jsonData, err := json.Marshal(req)
@@ -241,6 +250,9 @@ func sendRequest(req RecommendationRequest, url string, verbose bool) RequestSta
 	}
 
 	httpReq.Header.Set("Content-Type", "application/json")
+	if apiKey != "" {
+		httpReq.Header.Set("Authorization", "Bearer "+apiKey)
+	}
 
 	startTime := time.Now()
 	client := &http.Client{}
@@ -262,7 +274,7 @@ func sendRequest(req RecommendationRequest, url string, verbose bool) RequestSta
 	}
 
 	if resp.StatusCode == http.StatusOK {
-		var response RecommendationResponse
+		var response api.RecommendationResponse
 		err = json.Unmarshal(body, &response)
 		if err != nil {
 			stat.Error = err
@@ -0,0 +1,299 @@
+package main
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+const (
+	tieBreakNone            = "none"
+	tieBreakTopPriority     = "top_priority"
+	tieBreakLowerInvestment = "lower_investment"
+	tieBreakAskUser         = "ask_user"
+	tieBreakAI              = "ai"
+)
+
+var tieBreakPolicies = []string{tieBreakNone, tieBreakTopPriority, tieBreakLowerInvestment, tieBreakAskUser, tieBreakAI}
+
+var tieBreakPolicyNames = map[string]string{
+	tieBreakNone:            "без разрешения",
+	tieBreakTopPriority:     "победы в критериях с наивысшим приоритетом",
+	tieBreakLowerInvestment: "меньшие начальные инвестиции",
+	tieBreakAskUser:         "уточняющий вопрос",
+	tieBreakAI:              "вердикт AI",
+}
+
+// tieBreakPolicy — правило разрешения ничьей, задается переменной TIE_BREAK_POLICY.
+var tieBreakPolicy = tieBreakNone
+
+func isKnownTieBreakPolicy(policy string) bool {
+	return contains(tieBreakPolicies, policy)
+}
+
+// breakTie применяет настроенное правило к ничьей и записывает результат в response.TieBreak.
+// Вердикт AI известен только после запроса к LLM, поэтому он применяется в resolveTieWithAI.
+func breakTie(response *RecommendationResponse, answer string) {
+	tied := response.Outcome.Tied
+	if len(tied) < 2 {
+		return
+	}
+
+	var candidates []int
+	for _, id := range tied {
+		for i, option := range deploymentOptions {
+			if option.ID == id {
+				candidates = append(candidates, i)
+			}
+		}
+	}
+	names := optionNamesByID(tied)
+
+	lang := response.Language
+	tie := &TieBreakResult{Policy: tieBreakPolicy, Candidates: names}
+	response.TieBreak = tie
+
+	winner := -1
+	switch tieBreakPolicy {
+	case tieBreakTopPriority:
+		winner, tie.Reason = breakTieByTopPriority(lang, response.Details, candidates)
+	case tieBreakLowerInvestment:
+		winner, tie.Reason = breakTieByInvestment(response, candidates)
+	case tieBreakAskUser:
+		winner, tie.Question, tie.Reason = breakTieByQuestion(lang, candidates, answer)
+	case tieBreakAI:
+		tie.Reason = tr(lang, "ожидается вердикт AI")
+	default:
+		tie.Reason = tr(lang, "правило разрешения ничьей не задано")
+	}
+
+	if winner >= 0 {
+		tie.Applied = true
+		tie.Winner = deploymentOptions[winner].Name
+		setOutcome(response, Outcome{OptionID: deploymentOptions[winner].ID})
+	}
+}
+
+// breakTieByTopPriority выбирает вариант, который чаще других лидирует в критериях
+// с наибольшим весом.
+func breakTieByTopPriority(lang Lang, details []CriterionDetail, candidates []int) (int, string) {
+	topWeight := 0.0
+	for _, detail := range details {
+		topWeight = math.Max(topWeight, detail.Weight)
+	}
+
+	wins := make(map[int]int, len(candidates))
+	top := 0
+	for _, detail := range details {
+		if topWeight-detail.Weight > scoreEpsilon {
+			continue
+		}
+		top++
+		if leader := uniqueBest(candidates, scoreVector(detail.Scores)); leader >= 0 {
+			wins[leader]++
+		}
+	}
+
+	counts := make([]float64, len(deploymentOptions))
+	for option, n := range wins {
+		counts[option] = float64(n)
+	}
+	winner := uniqueBest(candidates, counts)
+	if winner < 0 || wins[winner] == 0 {
+		return -1, tr(lang, "критерии с наивысшим приоритетом не различают равные варианты")
+	}
+	return winner, tr(lang, "%s лидирует в %d из %d критериев с наивысшим приоритетом",
+		deploymentOptions[winner].Name, wins[winner], top)
+}
+
+// breakTieByInvestment выбирает вариант с меньшими начальными затратами: по оценке
+// стоимости, если она есть в ответе, иначе по баллам критерия начальных инвестиций.
+func breakTieByInvestment(response *RecommendationResponse, candidates []int) (int, string) {
+	lang := response.Language
+	if response.Cost != nil {
+		upfront := make([]float64, len(deploymentOptions))
+		for i, option := range deploymentOptions {
+			for _, cost := range response.Cost.Options {
+				if cost.Option == option.ID {
+					upfront[i] = -cost.Upfront
+				}
+			}
+		}
+		if winner := uniqueBest(candidates, upfront); winner >= 0 {
+			return winner, tr(lang, "у %s наименьшие разовые затраты: %s %s",
+				deploymentOptions[winner].Name, formatMoney(-upfront[winner]), response.Cost.Currency)
+		}
+		return -1, tr(lang, "разовые затраты равных вариантов совпадают")
+	}
+
+	if priceSheet == nil || priceSheet.UpfrontCriterion == "" {
+		return -1, tr(lang, "критерий начальных инвестиций не настроен")
+	}
+	name := priceSheet.UpfrontCriterion
+	scores := findCriterionByName(name).BaseScores
+	for _, detail := range response.Details {
+		if detail.Name == name {
+			scores = detail.Scores
+		}
+	}
+	if winner := uniqueBest(candidates, scoreVector(scores)); winner >= 0 {
+		return winner, tr(lang, "у %s лучший балл по критерию «%s»", deploymentOptions[winner].Name, criterionLabel(lang, name))
+	}
+	return -1, tr(lang, "баллы равных вариантов по критерию «%s» совпадают", criterionLabel(lang, name))
+}
+
+// breakTieByQuestion строит вопрос «что для вас важнее?»: для каждого равного варианта —
+// критерий каталога, по которому его преимущество над остальными наибольшее.
+// Если ответ уже передан, возвращает соответствующий вариант.
+func breakTieByQuestion(lang Lang, candidates []int, answer string) (int, *TieBreakQuestion, string) {
+	question := &TieBreakQuestion{}
+	used := make(map[string]bool)
+	var names []string
+	var winners []int
+
+	for _, option := range candidates {
+		best, bestLead := "", 0.0
+		for _, crit := range defaultCriteria {
+			if crit.IsSpecial || used[crit.Name] {
+				continue
+			}
+			lead := math.Inf(1)
+			for _, other := range candidates {
+				if other != option {
+					id, otherID := deploymentOptions[option].ID, deploymentOptions[other].ID
+					lead = math.Min(lead, crit.BaseScores[id]-crit.BaseScores[otherID])
+				}
+			}
+			if lead > bestLead {
+				best, bestLead = crit.Name, lead
+			}
+		}
+		if best == "" {
+			continue
+		}
+		used[best] = true
+		names = append(names, deploymentOptions[option].Name)
+		winners = append(winners, option)
+		question.Answers = append(question.Answers, TieBreakAnswer{CriterionID: criterionID(best), Criterion: best, Option: deploymentOptions[option].Name})
+	}
+
+	if len(question.Answers) < 2 {
+		return -1, nil, tr(lang, "в каталоге нет критериев, различающих равные варианты")
+	}
+	question.Text = tr(lang, "Варианты %s равны по баллам. Что для вас важнее?", strings.Join(names, ", "))
+
+	if answer == "" {
+		return -1, question, tr(lang, "ожидается ответ на уточняющий вопрос")
+	}
+	for i, a := range question.Answers {
+		if a.Criterion == answer {
+			return winners[i], question, tr(lang, "пользователь выбрал критерий «%s»", criterionLabel(lang, answer))
+		}
+	}
+	return -1, question, tr(lang, "ответ %q не соответствует вопросу", answer)
+}
+
+// uniqueBest возвращает кандидата с наибольшим значением или -1, если лидеров несколько.
+func uniqueBest(candidates []int, values []float64) int {
+	best := -1
+	tie := false
+	for _, option := range candidates {
+		switch {
+		case best < 0 || values[option]-values[best] > scoreEpsilon:
+			best, tie = option, false
+		case values[best]-values[option] <= scoreEpsilon:
+			tie = true
+		}
+	}
+	if tie {
+		return -1
+	}
+	return best
+}
+
+// resolveTieWithAI применяет правило ai: выбирает равный вариант, названный в первой строке
+// ответа LLM. Возвращает true, если рекомендация изменилась.
+func resolveTieWithAI(response *RecommendationResponse, aiAnalysis string) bool {
+	tie := response.TieBreak
+	if tie == nil || tie.Policy != tieBreakAI || tie.Applied {
+		return false
+	}
+	lang := response.Language
+	if aiAnalysis == "" {
+		tie.Reason = tr(lang, "рекомендация AI недоступна")
+		return false
+	}
+
+	verdict := strings.ToLower(strings.Trim(strings.SplitN(strings.TrimSpace(aiAnalysis), "\n", 2)[0], " *<>"))
+	for _, id := range response.Outcome.Tied {
+		option, ok := findDeploymentOption(id)
+		if ok && strings.Contains(verdict, strings.ToLower(option.Name)) {
+			tie.Applied = true
+			tie.Winner = option.Name
+			tie.Reason = tr(lang, "AI рекомендует %s", option.Name)
+			setOutcome(response, Outcome{OptionID: option.ID})
+			return true
+		}
+	}
+	tie.Reason = tr(lang, "вердикт AI не называет ни один из равных вариантов")
+	return false
+}
+
+func formatTieBreak(lang Lang, tie *TieBreakResult) string {
+	if tie.Applied {
+		return tr(lang, "Варианты %s равны по баллам, выбор сделан по правилу «%s»: %s.",
+			strings.Join(tie.Candidates, ", "), tr(lang, tieBreakPolicyNames[tie.Policy]), tie.Reason)
+	}
+	return tr(lang, "Ничья между %s не разрешена правилом «%s»: %s.",
+		strings.Join(tie.Candidates, ", "), tr(lang, tieBreakPolicyNames[tie.Policy]), tie.Reason)
+}
+
+// askTieBreakQuestion задает уточняющий вопрос state.TieBreakQuestion перед показом результата.
+func askTieBreakQuestion(bot *tgbotapi.BotAPI, chatID int64) {
+	question := userStates[chatID].TieBreakQuestion
+	lang := userLang(chatID)
+
+	var rows [][]tgbotapi.InlineKeyboardButton
+	for i, answer := range question.Answers {
+		rows = append(rows, tgbotapi.NewInlineKeyboardRow(
+			tgbotapi.NewInlineKeyboardButtonData(criterionLabel(lang, answer.Criterion), fmt.Sprintf("tieb_%d", i)),
+		))
+	}
+
+	text := question.Text
+	for _, answer := range question.Answers {
+		if critText := findCriterionByName(answer.Criterion).text(lang); critText.Description != "" {
+			text += fmt.Sprintf("\n\n*%s*\n%s", critText.Name, critText.Description)
+		}
+	}
+
+	msg := tgbotapi.NewMessage(chatID, text)
+	msg.ParseMode = "Markdown"
+	msg.ReplyMarkup = tgbotapi.NewInlineKeyboardMarkup(rows...)
+	sendMessage(bot, msg)
+
+	logger.LogTelegramAction("Уточняющий вопрос при ничьей", map[string]interface{}{
+		"ChatID":   chatID,
+		"Варианты": len(question.Answers),
+	})
+}
+
+func handleTieBreakCallback(bot *tgbotapi.BotAPI, chatID int64, callbackData string) {
+	state := userStates[chatID]
+	if state.TieBreakQuestion == nil {
+		return
+	}
+
+	index, err := strconv.Atoi(strings.TrimPrefix(callbackData, "tieb_"))
+	if err != nil || index < 0 || index >= len(state.TieBreakQuestion.Answers) {
+		logger.Printf("Некорректный ответ на уточняющий вопрос: %s", callbackData)
+		return
+	}
+
+	state.TieBreakAnswer = state.TieBreakQuestion.Answers[index].Criterion
+	state.TieBreakQuestion = nil
+	transition(bot, chatID, stateResult)
+}
@@ -0,0 +1,236 @@
+package main
+
+import (
+	"errors"
+	"math"
+	"math/rand"
+	"strconv"
+	"strings"
+	"time"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+const (
+	defaultUncertaintyIterations = 2000
+	maxUncertaintyIterations     = 20000
+	defaultUncertaintySpread     = 1
+)
+
+func validateFloatRange(lang Lang, r FloatRange, lower, upper float64) error {
+	if r.Min > r.Max {
+		return errors.New(tr(lang, "min %s больше max %s", formatNumber(r.Min), formatNumber(r.Max)))
+	}
+	if r.Min < lower || r.Max > upper {
+		return errors.New(tr(lang, "диапазон [%s, %s] выходит за пределы [%s, %s]",
+			formatNumber(r.Min), formatNumber(r.Max), formatNumber(lower), formatNumber(upper)))
+	}
+	return nil
+}
+
+func validateIntRange(lang Lang, r IntRange, lower, upper int) error {
+	if r.Min > r.Max {
+		return errors.New(tr(lang, "min %d больше max %d", r.Min, r.Max))
+	}
+	if r.Min < lower || r.Max > upper {
+		return errors.New(tr(lang, "диапазон [%d, %d] выходит за пределы [%d, %d]", r.Min, r.Max, lower, upper))
+	}
+	return nil
+}
+
+// validateUncertaintyOptions проверяет параметры прогонов; диапазоны по критериям
+// проверяются вместе с остальными ключами критериев запроса.
+func validateUncertaintyOptions(errs *fieldErrors, path string, opts *UncertaintyOptions) {
+	if opts.Iterations < 0 || opts.Iterations > maxUncertaintyIterations {
+		errs.add(fieldPath(path, "iterations"), "iterations должно быть от 0 (по умолчанию %d) до %d", defaultUncertaintyIterations, maxUncertaintyIterations)
+	}
+	if opts.PrioritySpread != nil && *opts.PrioritySpread < 0 {
+		errs.add(fieldPath(path, "priority_spread"), "priority_spread не может быть отрицательным")
+	}
+	if opts.ScoreSpread != nil && *opts.ScoreSpread < 0 {
+		errs.add(fieldPath(path, "score_spread"), "score_spread не может быть отрицательным")
+	}
+}
+
+// floatSpreadRange — аналог spreadRange для дробных баллов.
+func floatSpreadRange(value, spread, lower, upper float64) FloatRange {
+	if value < lower || value > upper {
+		return FloatRange{Min: value, Max: value}
+	}
+	return FloatRange{Min: math.Max(value-spread, lower), Max: math.Min(value+spread, upper)}
+}
+
+func spreadRange(value, spread, lower, upper int) IntRange {
+	if value < lower || value > upper {
+		return IntRange{Min: value, Max: value}
+	}
+
+	r := IntRange{Min: value - spread, Max: value + spread}
+	if r.Min < lower {
+		r.Min = lower
+	}
+	if r.Max > upper {
+		r.Max = upper
+	}
+	return r
+}
+
+func sampleInt(rng *rand.Rand, r IntRange) int {
+	return r.Min + rng.Intn(r.Max-r.Min+1)
+}
+
+func sampleFloat(rng *rand.Rand, r FloatRange) float64 {
+	return r.Min + rng.Float64()*(r.Max-r.Min)
+}
+
+// simulateUncertainty многократно пересчитывает итоги со случайными приоритетами и баллами
+// и возвращает долю итераций, в которых победил каждый вариант развертывания.
+// Исключенные ограничениями варианты не побеждают.
+func simulateUncertainty(details []CriterionDetail, opts UncertaintyOptions, excluded []bool) *UncertaintyReport {
+	iterations := opts.Iterations
+	if iterations == 0 {
+		iterations = defaultUncertaintyIterations
+	}
+	seed := opts.Seed
+	if seed == 0 {
+		seed = time.Now().UnixNano()
+	}
+	prioritySpread := defaultUncertaintySpread
+	if opts.PrioritySpread != nil {
+		prioritySpread = *opts.PrioritySpread
+	}
+	scoreSpread := float64(defaultUncertaintySpread)
+	if opts.ScoreSpread != nil {
+		scoreSpread = *opts.ScoreSpread
+	}
+
+	type criterionRanges struct {
+		priority IntRange
+		weight   float64
+		scores   []FloatRange
+	}
+
+	ranges := make([]criterionRanges, len(details))
+	for i, detail := range details {
+		ranges[i].weight = detail.Weight
+		if detail.Priority > 0 {
+			ranges[i].priority = spreadRange(detail.Priority, prioritySpread, priorityScale.Min, priorityScale.Max)
+			if r, ok := opts.PriorityRanges[detail.Name]; ok {
+				ranges[i].priority = r
+			}
+		}
+
+		scores := detailScores(detail)
+		explicit := opts.ScoreRanges[detail.Name]
+		ranges[i].scores = make([]FloatRange, len(scores))
+		for option := range scores {
+			if r, ok := explicit[deploymentOptions[option].ID]; ok {
+				ranges[i].scores[option] = r
+			} else {
+				ranges[i].scores[option] = floatSpreadRange(scores[option], scoreSpread, minScore, maxScore)
+			}
+		}
+	}
+
+	rng := rand.New(rand.NewSource(seed))
+	wins := make([]int, len(deploymentOptions))
+	totals := make([]float64, len(deploymentOptions))
+	weights := make([]float64, len(ranges))
+	ties := 0
+
+	for n := 0; n < iterations; n++ {
+		// Приоритеты разыгрываются заново и перенормируются; прямые веса фиксированы.
+		prioritySum, directSum := 0.0, 0.0
+		for i, r := range ranges {
+			if r.priority.Max > 0 {
+				weights[i] = float64(sampleInt(rng, r.priority))
+				prioritySum += weights[i]
+			} else {
+				directSum += r.weight
+			}
+		}
+		for i, r := range ranges {
+			if r.priority.Max > 0 {
+				weights[i] *= (1 - directSum) / prioritySum
+			} else {
+				weights[i] = r.weight
+			}
+		}
+
+		for option := range totals {
+			totals[option] = 0
+		}
+		for i, r := range ranges {
+			for option := range totals {
+				totals[option] += sampleFloat(rng, r.scores[option]) * weights[i]
+			}
+		}
+
+		order := rankOptions(totals, excluded)
+		switch {
+		case excluded[order[0]]:
+		case !excluded[order[1]] && totals[order[0]]-totals[order[1]] <= scoreEpsilon:
+			ties++
+		default:
+			wins[order[0]]++
+		}
+	}
+
+	report := &UncertaintyReport{
+		Iterations:     iterations,
+		Seed:           seed,
+		TieProbability: float64(ties) / float64(iterations),
+	}
+	for option, count := range wins {
+		report.WinProbabilities = append(report.WinProbabilities, OptionProbability{
+			Option:      deploymentOptions[option].Name,
+			Probability: float64(count) / float64(iterations),
+		})
+	}
+
+	return report
+}
+
+func formatUncertaintyMessage(lang Lang, report *UncertaintyReport) string {
+	var text strings.Builder
+	text.WriteString(tr(lang, "Уверенность в рекомендации (%d случайных прогонов, seed %d):",
+		report.Iterations, report.Seed) + "\n\n")
+
+	for _, p := range report.WinProbabilities {
+		text.WriteString(tr(lang, "• %s побеждает в %.1f%% случаев", p.Option, p.Probability*100) + "\n")
+	}
+	if report.TieProbability > 0 {
+		text.WriteString(tr(lang, "• Ничья в %.1f%% случаев", report.TieProbability*100) + "\n")
+	}
+
+	return text.String()
+}
+
+func showConfidence(bot *tgbotapi.BotAPI, query *tgbotapi.CallbackQuery, chatID int64) {
+	answerID, err := strconv.ParseInt(strings.TrimPrefix(query.Data, "conf_"), 10, 64)
+	if err != nil {
+		logger.Printf("Некорректный callback уверенности: %s", query.Data)
+		return
+	}
+
+	record, err := loadUserAnswer(chatID, answerID)
+	if err != nil {
+		logger.Printf("Ошибка загрузки записи %d для chatID %d: %v", answerID, chatID, err)
+		sendMessage(bot, tgbotapi.NewMessage(chatID, tr(userLang(chatID), "Запись не найдена.")))
+		return
+	}
+
+	req := inputToRequest(record.UserInput)
+	req.Uncertainty = &UncertaintyOptions{}
+	req.Lang = userLang(chatID)
+	response := evaluateRecommendation(req)
+
+	logger.LogTelegramAction("Расчет уверенности", map[string]interface{}{
+		"ChatID":      chatID,
+		"AnswerID":    answerID,
+		"Seed":        response.Uncertainty.Seed,
+		"Вероятности": response.Uncertainty.WinProbabilities,
+	})
+
+	sendMessage(bot, tgbotapi.NewMessage(chatID, formatUncertaintyMessage(req.Lang, response.Uncertainty)))
+}
@@ -0,0 +1,103 @@
+package main
+
+import (
+	"math"
+	"reflect"
+	"testing"
+)
+
+// uncertaintyDetails — два критерия, по которым Private Cloud лидирует с небольшим отрывом,
+// так что при разбросе баллов побеждают разные варианты.
+func uncertaintyDetails() []CriterionDetail {
+	jurisdiction, _ := lookupCriterion(defaultCriteria, "data_jurisdiction")
+	runningCosts, _ := lookupCriterion(defaultCriteria, "running_costs")
+	return []CriterionDetail{
+		{
+			ID:       jurisdiction.ID,
+			Name:     jurisdiction.Name,
+			Priority: 4,
+			Weight:   4.0 / 7,
+			Scores:   Scores{"on_prem": 8, "private": 8.5, "public": 4},
+		},
+		{
+			ID:       runningCosts.ID,
+			Name:     runningCosts.Name,
+			Priority: 3,
+			Weight:   3.0 / 7,
+			Scores:   Scores{"on_prem": 5, "private": 6, "public": 7},
+		},
+	}
+}
+
+func winProbabilities(report *UncertaintyReport) map[string]float64 {
+	probabilities := make(map[string]float64, len(report.WinProbabilities))
+	for _, p := range report.WinProbabilities {
+		probabilities[p.Option] = p.Probability
+	}
+	return probabilities
+}
+
+func TestSimulateUncertaintySeeded(t *testing.T) {
+	for _, detail := range uncertaintyDetails() {
+		if detail.ID == "" || detail.Name == "" {
+			t.Fatalf("критерия нет в каталоге: %+v", detail)
+		}
+	}
+	opts := UncertaintyOptions{Iterations: 1000, Seed: 42}
+	excluded := make([]bool, len(deploymentOptions))
+
+	report := simulateUncertainty(uncertaintyDetails(), opts, excluded)
+	if report.Iterations != 1000 || report.Seed != 42 {
+		t.Fatalf("Iterations/Seed = %d/%d, ожидалось 1000/42", report.Iterations, report.Seed)
+	}
+
+	want := map[string]float64{
+		"On-Premise":    0.126,
+		"Private Cloud": 0.873,
+		"Public Cloud":  0.001,
+	}
+	got := winProbabilities(report)
+	for option, probability := range want {
+		if math.Abs(got[option]-probability) > 1e-9 {
+			t.Errorf("вероятность победы %s = %v, ожидалось %v", option, got[option], probability)
+		}
+	}
+	if report.TieProbability != 0 {
+		t.Errorf("TieProbability = %v, ожидалось 0", report.TieProbability)
+	}
+
+	again := simulateUncertainty(uncertaintyDetails(), opts, excluded)
+	if !reflect.DeepEqual(report, again) {
+		t.Errorf("повторный прогон с тем же seed дал другой результат:\n%+v\n%+v", report, again)
+	}
+
+	opts.Seed = 43
+	if other := simulateUncertainty(uncertaintyDetails(), opts, excluded); reflect.DeepEqual(winProbabilities(report), winProbabilities(other)) {
+		t.Errorf("прогоны с seed 42 и 43 совпали: %v", winProbabilities(other))
+	}
+}
+
+func TestSimulateUncertaintyExcludedNeverWins(t *testing.T) {
+	excluded := make([]bool, len(deploymentOptions))
+	excluded[1] = true // private
+
+	report := simulateUncertainty(uncertaintyDetails(), UncertaintyOptions{Iterations: 500, Seed: 7}, excluded)
+	got := winProbabilities(report)
+	if got["Private Cloud"] != 0 {
+		t.Errorf("исключенный Private Cloud побеждает с вероятностью %v", got["Private Cloud"])
+	}
+	if sum := got["On-Premise"] + got["Public Cloud"] + report.TieProbability; math.Abs(sum-1) > 1e-9 {
+		t.Errorf("сумма вероятностей = %v, ожидалось 1", sum)
+	}
+}
+
+func TestSimulateUncertaintyWithoutSpread(t *testing.T) {
+	zero, zeroScore := 0, 0.0
+	opts := UncertaintyOptions{Iterations: 200, Seed: 1, PrioritySpread: &zero, ScoreSpread: &zeroScore}
+
+	report := simulateUncertainty(uncertaintyDetails(), opts, make([]bool, len(deploymentOptions)))
+	want := map[string]float64{"On-Premise": 0, "Private Cloud": 1, "Public Cloud": 0}
+	if got := winProbabilities(report); !reflect.DeepEqual(got, want) {
+		t.Errorf("без разброса вероятности = %v, ожидалось %v", got, want)
+	}
+}
@@ -0,0 +1,317 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// fieldErrors накапливает ошибки полей, сообщения переводятся на язык запроса.
+type fieldErrors struct {
+	lang Lang
+	list []FieldError
+}
+
+func (e *fieldErrors) add(path, format string, args ...interface{}) {
+	e.list = append(e.list, FieldError{Path: path, Message: tr(e.lang, format, args...)})
+}
+
+func (e *fieldErrors) addError(path string, err error) {
+	e.list = append(e.list, FieldError{Path: path, Message: err.Error()})
+}
+
+// fieldPath добавляет к пути имя поля.
+func fieldPath(base, field string) string {
+	if base == "" {
+		return field
+	}
+	return base + "." + field
+}
+
+// keyPath добавляет к пути ключ объекта; ключи не из латиницы записываются в кавычках.
+func keyPath(base, key string) string {
+	if criterionIDPattern.MatchString(key) {
+		return fieldPath(base, key)
+	}
+	return base + "[" + strconv.Quote(key) + "]"
+}
+
+func indexPath(base string, i int) string {
+	return fmt.Sprintf("%s[%d]", base, i)
+}
+
+// decodeJSONBody строго разбирает тело запроса: неизвестные поля и значения не того типа — ошибка.
+func decodeJSONBody(lang Lang, r *http.Request, v interface{}) []FieldError {
+	decoder := json.NewDecoder(r.Body)
+	decoder.DisallowUnknownFields()
+	err := decoder.Decode(v)
+	if err == nil {
+		return nil
+	}
+
+	errs := &fieldErrors{lang: lang}
+	var typeErr *json.UnmarshalTypeError
+	switch {
+	case errors.As(err, &typeErr):
+		errs.add(typeErr.Field, "ожидается %s, получено %s", jsonTypeName(typeErr.Type), typeErr.Value)
+	case strings.HasPrefix(err.Error(), "json: unknown field "):
+		field, _ := strconv.Unquote(strings.TrimPrefix(err.Error(), "json: unknown field "))
+		errs.add(field, "неизвестное поле")
+	default:
+		errs.add("", "Ошибка парсинга JSON: %v", err)
+	}
+	return errs.list
+}
+
+// jsonTypeName называет тип Go так, как он называется в JSON.
+func jsonTypeName(t reflect.Type) string {
+	switch t.Kind() {
+	case reflect.Bool:
+		return "boolean"
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return "integer"
+	case reflect.Float32, reflect.Float64:
+		return "number"
+	case reflect.String:
+		return "string"
+	case reflect.Slice, reflect.Array:
+		return "array"
+	default:
+		return "object"
+	}
+}
+
+func writeValidationErrors(w http.ResponseWriter, lang Lang, errs []FieldError) {
+	writeJSON(w, http.StatusBadRequest, ValidationError{Error: tr(lang, "Некорректный запрос"), Errors: errs})
+}
+
+// requestValidator проверяет запрос на расчет. Критерии в запросе задаются идентификаторами,
+// selected — выбранные критерии по внутренним именам.
+type requestValidator struct {
+	*fieldErrors
+	selected map[string]bool
+}
+
+// criterion находит критерий по ключу из запроса; неизвестный ключ — ошибка поля path.
+func (v *requestValidator) criterion(list []Criterion, path, key string) (Criterion, bool) {
+	crit, ok := lookupCriterion(list, key)
+	if !ok {
+		v.add(path, "неизвестный критерий %q, допустимые значения: %s", key, strings.Join(criterionIDs(list), ", "))
+	}
+	return crit, ok
+}
+
+// selectedCriterion дополнительно требует, чтобы критерий был выбран в selected_criteria.
+func (v *requestValidator) selectedCriterion(path, key string) (Criterion, bool) {
+	crit, ok := v.criterion(defaultCriteria, path, key)
+	if ok && !v.selected[crit.Name] {
+		v.add(path, "критерий %q не выбран в selected_criteria", key)
+		return crit, false
+	}
+	return crit, ok
+}
+
+// criterionKeys проверяет ключи объекта с критериями и вызывает check для каждого выбранного критерия.
+func criterionKeys[V any](v *requestValidator, base string, values map[string]V, check func(path string, crit Criterion, value V)) {
+	for _, key := range sortedKeys(values) {
+		path := keyPath(base, key)
+		if crit, ok := v.selectedCriterion(path, key); ok {
+			check(path, crit, values[key])
+		}
+	}
+}
+
+// validateRecommendationRequest проверяет запрос целиком и возвращает все найденные ошибки.
+// Пути ошибок начинаются с prefix (для вложенных запросов). Если ошибок нет, критерии запроса
+// переводятся из идентификаторов во внутренние имена.
+func validateRecommendationRequest(lang Lang, prefix string, req *RecommendationRequest) []FieldError {
+	v := &requestValidator{fieldErrors: &fieldErrors{lang: lang}, selected: make(map[string]bool)}
+
+	path := fieldPath(prefix, "selected_criteria")
+	if len(req.SelectedCriteria) == 0 {
+		v.add(path, "Необходимо выбрать хотя бы один критерий")
+	}
+	for i, key := range req.SelectedCriteria {
+		crit, ok := v.criterion(defaultCriteria, indexPath(path, i), key)
+		if !ok {
+			continue
+		}
+		if v.selected[crit.Name] {
+			v.add(indexPath(path, i), "критерий %q указан повторно", key)
+		}
+		v.selected[crit.Name] = true
+	}
+
+	criterionKeys(v, fieldPath(prefix, "criteria_priorities"), req.CriteriaPriorities, func(path string, _ Criterion, prio int) {
+		if !scaleContains(priorityScale, prio) {
+			v.add(path, "приоритет должен быть от %d до %d", priorityScale.Min, priorityScale.Max)
+		}
+	})
+
+	criterionKeys(v, fieldPath(prefix, "criteria_weights"), req.CriteriaWeights, func(path string, _ Criterion, weight float64) {
+		if weight < 0 {
+			v.add(path, "вес не может быть отрицательным")
+		}
+	})
+
+	criterionKeys(v, fieldPath(prefix, "overridden_scores"), req.OverriddenScores, func(path string, _ Criterion, scores Scores) {
+		validateScoreValues(v.fieldErrors, path, scores)
+	})
+
+	specialSet := make(map[string]bool)
+	criterionKeys(v, fieldPath(prefix, "special_values"), req.SpecialValues, func(path string, crit Criterion, value string) {
+		specialSet[crit.Name] = true
+		if !crit.IsSpecial {
+			v.add(path, "критерий %q не специальный", crit.ID)
+			return
+		}
+		if !crit.hasSpecialValue(value) {
+			v.add(path, "недопустимое значение %q, допустимые значения: %s", value, strings.Join(crit.specialValues(), ", "))
+		}
+	})
+	overridden := make(map[string]bool)
+	for key := range req.OverriddenScores {
+		if crit, ok := lookupCriterion(defaultCriteria, key); ok {
+			overridden[crit.Name] = true
+		}
+	}
+	for _, crit := range defaultCriteria {
+		if crit.IsSpecial && v.selected[crit.Name] && !specialSet[crit.Name] && !overridden[crit.Name] {
+			v.add(keyPath(fieldPath(prefix, "special_values"), crit.ID), "не указано значение специального критерия, допустимые значения: %s",
+				strings.Join(crit.specialValues(), ", "))
+		}
+	}
+
+	ahpKeysValid := v.validateAHPJudgments(fieldPath(prefix, "ahp_judgments"), req.AHPJudgments)
+
+	if req.TieBreakAnswer != "" {
+		v.criterion(defaultCriteria, fieldPath(prefix, "tie_break_answer"), req.TieBreakAnswer)
+	}
+
+	if !isKnownMethod(req.Method) {
+		v.add(fieldPath(prefix, "method"), "Неизвестный метод %q, допустимые значения: %s",
+			req.Method, strings.Join(decisionMethods, ", "))
+	}
+
+	if req.Uncertainty != nil {
+		path := fieldPath(prefix, "uncertainty")
+		criterionKeys(v, fieldPath(path, "priority_ranges"), req.Uncertainty.PriorityRanges, func(path string, _ Criterion, r IntRange) {
+			if err := validateIntRange(lang, r, priorityScale.Min, priorityScale.Max); err != nil {
+				v.addError(path, err)
+			}
+		})
+		criterionKeys(v, fieldPath(path, "score_ranges"), req.Uncertainty.ScoreRanges, func(path string, _ Criterion, ranges ScoreRanges) {
+			for _, option := range sortedKeys(ranges) {
+				if _, ok := findDeploymentOption(option); !ok {
+					v.add(keyPath(path, option), "неизвестный вариант развертывания %q, допустимые значения: %s",
+						option, strings.Join(deploymentOptionIDs(), ", "))
+				} else if err := validateFloatRange(lang, ranges[option], minScore, maxScore); err != nil {
+					v.addError(keyPath(path, option), err)
+				}
+			}
+		})
+		validateUncertaintyOptions(v.fieldErrors, path, req.Uncertainty)
+	}
+
+	if req.Cost != nil {
+		validateCostInput(v.fieldErrors, fieldPath(prefix, "cost"), req.Cost)
+	}
+
+	if req.Vendors != nil {
+		validateVendorRequest(v.fieldErrors, fieldPath(prefix, "vendors"), req.Vendors)
+	}
+
+	for _, id := range sortedKeys(req.FollowUpAnswers) {
+		followUp, ok := findFollowUp(id)
+		if !ok {
+			v.add(keyPath(fieldPath(prefix, "follow_up_answers"), id), "неизвестный вопрос %q", id)
+			continue
+		}
+		if _, ok := followUp.answer(req.FollowUpAnswers[id]); !ok {
+			v.add(keyPath(fieldPath(prefix, "follow_up_answers"), id), "недопустимый ответ %q, допустимые значения: %s",
+				req.FollowUpAnswers[id], strings.Join(followUp.answerValues(), ", "))
+		}
+	}
+
+	if len(v.list) > 0 {
+		return v.list
+	}
+
+	// Проверки, которым нужны внутренние имена критериев, выполняются после перевода идентификаторов.
+	if err := resolveRequestIDs(lang, req); err != nil {
+		v.addError(prefix, err)
+		return v.list
+	}
+	if ahpKeysValid && len(req.AHPJudgments) > 0 {
+		if expected := len(req.SelectedCriteria) * (len(req.SelectedCriteria) - 1) / 2; len(req.AHPJudgments) != expected {
+			v.add(fieldPath(prefix, "ahp_judgments"), "нужно %d попарных сравнений, получено %d", expected, len(req.AHPJudgments))
+		}
+	}
+	applicable := make(map[string]bool)
+	for _, followUp := range applicableFollowUps(*req) {
+		applicable[followUp.ID] = true
+	}
+	for _, id := range sortedKeys(req.FollowUpAnswers) {
+		if !applicable[id] {
+			v.add(keyPath(fieldPath(prefix, "follow_up_answers"), id), "вопрос %q не применим к выбранным критериям и ответам", id)
+		}
+	}
+	return v.list
+}
+
+// validateAHPJudgments проверяет каждое попарное сравнение; полнота набора проверяется
+// после перевода идентификаторов. Возвращает false, если в сравнениях есть ошибки.
+func (v *requestValidator) validateAHPJudgments(path string, judgments []AHPJudgment) bool {
+	before := len(v.list)
+	seen := make(map[[2]string]bool)
+	for i, j := range judgments {
+		itemPath := indexPath(path, i)
+		a, okA := v.selectedCriterion(fieldPath(itemPath, "a"), j.A)
+		b, okB := v.selectedCriterion(fieldPath(itemPath, "b"), j.B)
+		if j.Value < 1.0/9-1e-9 || j.Value > 9+1e-9 {
+			v.add(fieldPath(itemPath, "value"), "значение сравнения должно быть от 1/9 до 9")
+		}
+		if !okA || !okB {
+			continue
+		}
+		if a.Name == b.Name {
+			v.add(itemPath, "критерий %s сравнивается сам с собой", j.A)
+			continue
+		}
+		key := [2]string{min(a.Name, b.Name), max(a.Name, b.Name)}
+		if seen[key] {
+			v.add(itemPath, "сравнение %s/%s указано дважды", j.A, j.B)
+		}
+		seen[key] = true
+	}
+	return len(v.list) == before
+}
+
+func sortedKeys[V any](values map[string]V) []string {
+	keys := make([]string, 0, len(values))
+	for key := range values {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// validateScoreValues проверяет варианты и диапазон баллов.
+func validateScoreValues(errs *fieldErrors, path string, scores Scores) {
+	for _, id := range sortedKeys(scores) {
+		if _, ok := findDeploymentOption(id); !ok {
+			errs.add(keyPath(path, id), "неизвестный вариант развертывания %q, допустимые значения: %s",
+				id, strings.Join(deploymentOptionIDs(), ", "))
+			continue
+		}
+		if score := scores[id]; score < minScore || score > maxScore {
+			errs.add(keyPath(path, id), "балл должен быть от %d до %d", minScore, maxScore)
+		}
+	}
+}
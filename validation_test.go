@@ -0,0 +1,96 @@
+package main
+
+import (
+	"net/http"
+	"strings"
+	"testing"
+)
+
+func TestValidationRejectsCriterionNames(t *testing.T) {
+	logger = NewLogger(true)
+	valid := strings.Join(criterionIDs(defaultCriteria), ", ")
+
+	tests := []struct {
+		name string
+		req  RecommendationRequest
+		path string
+		key  string
+	}{
+		{
+			name: "название в selected_criteria",
+			req: RecommendationRequest{
+				SelectedCriteria:   []string{"Латентность"},
+				CriteriaPriorities: map[string]int{"latency": 3},
+			},
+			path: "selected_criteria[0]",
+			key:  "Латентность",
+		},
+		{
+			name: "название в criteria_priorities",
+			req: RecommendationRequest{
+				SelectedCriteria:   []string{"latency"},
+				CriteriaPriorities: map[string]int{"Латентность": 3},
+			},
+			path: `criteria_priorities["Латентность"]`,
+			key:  "Латентность",
+		},
+		{
+			name: "неизвестный идентификатор",
+			req: RecommendationRequest{
+				SelectedCriteria:   []string{"no_such_criterion"},
+				CriteriaPriorities: map[string]int{},
+			},
+			path: "selected_criteria[0]",
+			key:  "no_such_criterion",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			errs := validateRecommendationRequest(langRU, "", &tt.req)
+			want := `неизвестный критерий "` + tt.key + `", допустимые значения: ` + valid
+			for _, err := range errs {
+				if err.Path == tt.path && err.Message == want {
+					return
+				}
+			}
+			t.Errorf("нет ошибки %s: %q, получено %+v", tt.path, want, errs)
+		})
+	}
+}
+
+func TestLegacyRoutesAcceptCriterionNames(t *testing.T) {
+	server := newTestAPIServer(t)
+
+	tests := []struct {
+		name    string
+		path    string
+		body    string
+		status  int
+		warning bool
+	}{
+		{"названия на устаревшем маршруте", "/api/recommend",
+			`{"selected_criteria": ["Латентность"], "criteria_priorities": {"Латентность": 3}}`, http.StatusOK, true},
+		{"идентификаторы на устаревшем маршруте", "/api/recommend",
+			`{"selected_criteria": ["latency"], "criteria_priorities": {"latency": 3}}`, http.StatusOK, false},
+		{"название и идентификатор одного критерия", "/api/recommend",
+			`{"selected_criteria": ["Латентность", "latency"], "criteria_priorities": {"latency": 3}}`, http.StatusBadRequest, true},
+		{"названия в v1", "/api/v1/recommend",
+			`{"selected_criteria": ["Латентность"], "criteria_priorities": {"Латентность": 3}}`, http.StatusBadRequest, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			resp, err := server.Client().Post(server.URL+tt.path, "application/json", strings.NewReader(tt.body))
+			if err != nil {
+				t.Fatal(err)
+			}
+			resp.Body.Close()
+			if resp.StatusCode != tt.status {
+				t.Errorf("код %d, ожидался %d", resp.StatusCode, tt.status)
+			}
+			if warning := resp.Header.Get("Warning") != ""; warning != tt.warning {
+				t.Errorf("заголовок Warning = %q, ожидался: %t", resp.Header.Get("Warning"), tt.warning)
+			}
+		})
+	}
+}
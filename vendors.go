@@ -0,0 +1,357 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+// Vendor — поставщик или продукт второго этапа, относящийся к варианту развертывания Option.
+type Vendor struct {
+	ID           string              `json:"id"`
+	Name         string              `json:"name"`
+	Option       string              `json:"option"`
+	Description  string              `json:"description"`
+	Translations map[Lang]VendorText `json:"translations,omitempty"`
+}
+
+type VendorText struct {
+	Name        string `json:"name,omitempty"`
+	Description string `json:"description,omitempty"`
+}
+
+var (
+	vendors        = getDefaultVendors()
+	vendorCriteria = getDefaultVendorCriteria()
+)
+
+func getDefaultVendors() []Vendor {
+	return []Vendor{
+		{ID: "patroni_bare_metal", Name: "Patroni на собственных серверах", Option: "on_prem", Description: "Кластер PostgreSQL с автоматическим failover на своем оборудовании.",
+			Translations: map[Lang]VendorText{langEN: {Name: "Patroni on own servers", Description: "PostgreSQL cluster with automatic failover on own hardware."}}},
+		{ID: "postgres_pro", Name: "Postgres Pro Enterprise", Option: "on_prem", Description: "Коммерческая сборка PostgreSQL с сертификатами ФСТЭК и поддержкой.",
+			Translations: map[Lang]VendorText{langEN: {Description: "Commercial PostgreSQL distribution with FSTEC certificates and support."}}},
+		{ID: "openstack_trove", Name: "OpenStack Trove", Option: "private", Description: "DBaaS поверх собственного OpenStack.",
+			Translations: map[Lang]VendorText{langEN: {Description: "DBaaS on top of your own OpenStack."}}},
+		{ID: "patroni_private", Name: "Self-hosted Patroni", Option: "private", Description: "Patroni на виртуальных машинах частного облака.",
+			Translations: map[Lang]VendorText{langEN: {Description: "Patroni on private cloud virtual machines."}}},
+		{ID: "cloudnative_pg", Name: "CloudNativePG в Kubernetes", Option: "private", Description: "Оператор PostgreSQL для собственного кластера Kubernetes.",
+			Translations: map[Lang]VendorText{langEN: {Name: "CloudNativePG on Kubernetes", Description: "PostgreSQL operator for your own Kubernetes cluster."}}},
+		{ID: "yandex_mpg", Name: "Yandex Managed Service for PostgreSQL", Option: "public", Description: "Управляемый PostgreSQL в Yandex Cloud.",
+			Translations: map[Lang]VendorText{langEN: {Description: "Managed PostgreSQL in Yandex Cloud."}}},
+		{ID: "vk_cloud_db", Name: "VK Cloud Databases", Option: "public", Description: "Управляемые СУБД в VK Cloud.",
+			Translations: map[Lang]VendorText{langEN: {Description: "Managed databases in VK Cloud."}}},
+		{ID: "cloudru_mpg", Name: "Cloud.ru Managed PostgreSQL", Option: "public", Description: "Управляемый PostgreSQL в Cloud.ru.",
+			Translations: map[Lang]VendorText{langEN: {Description: "Managed PostgreSQL in Cloud.ru."}}},
+	}
+}
+
+// getDefaultVendorCriteria задает критерии второго этапа; баллы указаны по идентификаторам поставщиков.
+func getDefaultVendorCriteria() []Criterion {
+	return []Criterion{
+		{
+			ID:          "operational_effort",
+			Name:        "Трудозатраты на эксплуатацию",
+			Description: "Насколько важно минимизировать ручную работу команды: обновления, бэкапы, failover.",
+			Translations: map[Lang]CriterionText{
+				langEN: {Name: "Operational effort", Description: "How important it is to minimize the team's manual work: upgrades, backups, failover."},
+			},
+			BaseScores: Scores{
+				"patroni_bare_metal": 3, "postgres_pro": 5,
+				"openstack_trove": 6, "patroni_private": 4, "cloudnative_pg": 6,
+				"yandex_mpg": 9, "vk_cloud_db": 8, "cloudru_mpg": 8,
+			},
+		},
+		{
+			ID:          "cost_of_ownership",
+			Name:        "Стоимость владения",
+			Description: "Насколько важна низкая совокупная стоимость лицензий и услуг.",
+			Translations: map[Lang]CriterionText{
+				langEN: {Name: "Cost of ownership", Description: "How important a low total cost of licenses and services is."},
+			},
+			BaseScores: Scores{
+				"patroni_bare_metal": 8, "postgres_pro": 4,
+				"openstack_trove": 6, "patroni_private": 8, "cloudnative_pg": 8,
+				"yandex_mpg": 6, "vk_cloud_db": 7, "cloudru_mpg": 7,
+			},
+		},
+		{
+			ID:          "certification",
+			Name:        "Сертификация и соответствие",
+			Description: "Требуются ли сертификаты ФСТЭК, аттестация по 152-ФЗ и поддержка вендора.",
+			Translations: map[Lang]CriterionText{
+				langEN: {Name: "Certification and compliance", Description: "Whether FSTEC certificates, 152-FZ attestation and vendor support are required."},
+			},
+			BaseScores: Scores{
+				"patroni_bare_metal": 5, "postgres_pro": 10,
+				"openstack_trove": 5, "patroni_private": 5, "cloudnative_pg": 4,
+				"yandex_mpg": 8, "vk_cloud_db": 7, "cloudru_mpg": 8,
+			},
+		},
+		{
+			ID:          "configuration_control",
+			Name:        "Контроль над конфигурацией",
+			Description: "Насколько важен доступ к настройкам СУБД, расширениям и версиям.",
+			Translations: map[Lang]CriterionText{
+				langEN: {Name: "Configuration control", Description: "How important access to database settings, extensions and versions is."},
+			},
+			BaseScores: Scores{
+				"patroni_bare_metal": 10, "postgres_pro": 9,
+				"openstack_trove": 6, "patroni_private": 9, "cloudnative_pg": 8,
+				"yandex_mpg": 5, "vk_cloud_db": 5, "cloudru_mpg": 5,
+			},
+		},
+		{
+			ID:          "ecosystem",
+			Name:        "Экосистема и интеграции",
+			Description: "Нужны ли готовые интеграции: мониторинг, IAM, аналитика, смежные сервисы.",
+			Translations: map[Lang]CriterionText{
+				langEN: {Name: "Ecosystem and integrations", Description: "Whether ready-made integrations are needed: monitoring, IAM, analytics, adjacent services."},
+			},
+			BaseScores: Scores{
+				"patroni_bare_metal": 4, "postgres_pro": 6,
+				"openstack_trove": 6, "patroni_private": 5, "cloudnative_pg": 7,
+				"yandex_mpg": 9, "vk_cloud_db": 7, "cloudru_mpg": 7,
+			},
+		},
+	}
+}
+
+func findVendorCriterionByName(name string) Criterion {
+	for _, crit := range vendorCriteria {
+		if crit.Name == name {
+			return crit
+		}
+	}
+	return Criterion{}
+}
+
+func findDeploymentOptionByName(name string) (DeploymentOption, bool) {
+	for _, option := range deploymentOptions {
+		if option.Name == name {
+			return option, true
+		}
+	}
+	return DeploymentOption{}, false
+}
+
+func vendorsForOption(optionID string) []Vendor {
+	var result []Vendor
+	for _, vendor := range vendors {
+		if vendor.Option == optionID {
+			result = append(result, vendor)
+		}
+	}
+	return result
+}
+
+func validateVendorRequest(errs *fieldErrors, path string, req *VendorRequest) {
+	if req.Option != "" {
+		if _, ok := findDeploymentOption(req.Option); !ok {
+			errs.add(fieldPath(path, "option"), "неизвестный вариант развертывания %q, допустимые значения: %s",
+				req.Option, strings.Join(deploymentOptionIDs(), ", "))
+		}
+	}
+	for _, key := range sortedKeys(req.CriteriaPriorities) {
+		itemPath := keyPath(fieldPath(path, "criteria_priorities"), key)
+		if _, ok := lookupCriterion(vendorCriteria, key); !ok {
+			errs.add(itemPath, "неизвестный критерий поставщиков %q, допустимые значения: %s",
+				key, strings.Join(criterionIDs(vendorCriteria), ", "))
+		} else if !scaleContains(priorityScale, req.CriteriaPriorities[key]) {
+			errs.add(itemPath, "приоритет должен быть от %d до %d", priorityScale.Min, priorityScale.Max)
+		}
+	}
+}
+
+// evaluateVendors ранжирует поставщиков выбранного варианта развертывания той же
+// взвешенной суммой, что и первый этап: итог = Σ вес × балл, вес = приоритет / Σ приоритетов.
+// Названия поставщиков в шортлисте выводятся на языке lang.
+func evaluateVendors(lang Lang, optionID string, priorities map[string]int) *VendorShortlist {
+	candidates := vendorsForOption(optionID)
+	if len(candidates) == 0 {
+		return nil
+	}
+
+	shortlist := &VendorShortlist{Option: optionID}
+	totals := make(map[string]float64, len(candidates))
+
+	prioritySum := 0
+	for _, crit := range vendorCriteria {
+		prio, ok := priorities[crit.Name]
+		if !ok {
+			if len(priorities) > 0 {
+				continue
+			}
+			prio = 1
+		}
+		prioritySum += prio
+		shortlist.Details = append(shortlist.Details, VendorCriterionDetail{ID: crit.ID, Name: crit.Name, Priority: prio, Scores: Scores{}, Weighted: Scores{}})
+	}
+
+	for i := range shortlist.Details {
+		detail := &shortlist.Details[i]
+		detail.Weight = float64(detail.Priority) / float64(prioritySum)
+		crit := findVendorCriterionByName(detail.Name)
+		for _, vendor := range candidates {
+			score := crit.BaseScores[vendor.ID]
+			detail.Scores[vendor.ID] = score
+			detail.Weighted[vendor.ID] = score * detail.Weight
+			totals[vendor.ID] += score * detail.Weight
+		}
+	}
+
+	for _, vendor := range candidates {
+		shortlist.Shortlist = append(shortlist.Shortlist, VendorRanking{ID: vendor.ID, Name: vendor.text(lang).Name, Total: totals[vendor.ID]})
+	}
+	sort.SliceStable(shortlist.Shortlist, func(i, j int) bool {
+		return shortlist.Shortlist[i].Total > shortlist.Shortlist[j].Total
+	})
+	// Поставщики с равным итогом делят место.
+	for i := range shortlist.Shortlist {
+		shortlist.Shortlist[i].Rank = i + 1
+		if i > 0 && shortlist.Shortlist[i-1].Total-shortlist.Shortlist[i].Total <= scoreEpsilon {
+			shortlist.Shortlist[i].Rank = shortlist.Shortlist[i-1].Rank
+		}
+	}
+
+	return shortlist
+}
+
+// vendorStageOption возвращает вариант развертывания для второго этапа: явно заданный
+// или победителя первого этапа. При ничьей второй этап не проводится.
+func vendorStageOption(req *VendorRequest, outcome Outcome) string {
+	if req.Option != "" {
+		return req.Option
+	}
+	return outcome.OptionID
+}
+
+func (v Vendor) text(lang Lang) VendorText {
+	t := v.Translations[lang]
+	return VendorText{
+		Name:        firstNonEmpty(t.Name, v.Name),
+		Description: firstNonEmpty(t.Description, v.Description),
+	}
+}
+
+func formatVendorShortlist(lang Lang, shortlist *VendorShortlist) string {
+	option, _ := findDeploymentOption(shortlist.Option)
+
+	var text strings.Builder
+	text.WriteString(tr(lang, "Поставщики для %s:", option.Name) + "\n\n")
+	for _, ranking := range shortlist.Shortlist {
+		text.WriteString(tr(lang, "%d. %s — %s баллов", ranking.Rank, ranking.Name, formatNumber(ranking.Total)) + "\n")
+	}
+	return text.String()
+}
+
+// startVendorStage запускает второй этап из сохраненного результата первого.
+func startVendorStage(bot *tgbotapi.BotAPI, query *tgbotapi.CallbackQuery, chatID int64) {
+	answerID, err := strconv.ParseInt(strings.TrimPrefix(query.Data, "vend_"), 10, 64)
+	if err != nil {
+		logger.Printf("Некорректный callback поставщиков: %s", query.Data)
+		return
+	}
+
+	// Второй этап не должен затирать незаконченный чеклист.
+	if current := userStates[chatID]; current != nil && current.State != stateIdle {
+		sendMessage(bot, tgbotapi.NewMessage(chatID, tr(userLang(chatID),
+			"Сначала завершите текущий чеклист или сбросьте его командой /reset.")))
+		return
+	}
+
+	record, err := loadUserAnswer(chatID, answerID)
+	if err != nil {
+		logger.Printf("Ошибка загрузки записи %d для chatID %d: %v", answerID, chatID, err)
+		sendMessage(bot, tgbotapi.NewMessage(chatID, tr(userLang(chatID), "Запись не найдена.")))
+		return
+	}
+
+	// Берется сохраненная рекомендация: пересчет потерял бы разрешение ничьей через AI.
+	optionID := record.recommendedOption()
+	if optionID == "" || len(vendorsForOption(optionID)) == 0 {
+		sendMessage(bot, tgbotapi.NewMessage(chatID, tr(userLang(chatID), "Для этого результата нет каталога поставщиков.")))
+		return
+	}
+
+	state := newUserState()
+	state.VendorOption = optionID
+	state.VendorPriorities = make(map[string]int)
+	userStates[chatID] = state
+
+	logger.LogTelegramAction("Начат выбор поставщика", map[string]interface{}{
+		"ChatID":   chatID,
+		"AnswerID": answerID,
+		"Вариант":  optionID,
+	})
+
+	transition(bot, chatID, stateVendors)
+}
+
+func askVendorPriority(bot *tgbotapi.BotAPI, chatID int64) {
+	state := userStates[chatID]
+	lang := userLang(chatID)
+
+	for i, crit := range vendorCriteria {
+		if _, ok := state.VendorPriorities[crit.Name]; ok {
+			continue
+		}
+
+		critText := crit.text(lang)
+		text := tr(lang, "Установите приоритет для критерия выбора поставщика:\n\n*%s*\n%s", critText.Name, critText.Description)
+
+		keyboard := tgbotapi.NewInlineKeyboardMarkup(priorityButtonRows(func(p int) string {
+			return fmt.Sprintf("vprio_%d_%d", i, p)
+		})...)
+
+		if state.PriorityMessageID != 0 {
+			editMsg := tgbotapi.NewEditMessageTextAndMarkup(chatID, state.PriorityMessageID, text, keyboard)
+			editMsg.ParseMode = "Markdown"
+			editMessageText(bot, editMsg)
+		} else {
+			msg := tgbotapi.NewMessage(chatID, text)
+			msg.ParseMode = "Markdown"
+			msg.ReplyMarkup = keyboard
+			sendTrackedMessage(bot, msg, &state.PriorityMessageID)
+		}
+		return
+	}
+
+	shortlist := evaluateVendors(lang, state.VendorOption, state.VendorPriorities)
+
+	logger.LogTelegramAction("Результат выбора поставщика", map[string]interface{}{
+		"ChatID":   chatID,
+		"Вариант":  state.VendorOption,
+		"Шортлист": shortlist.Shortlist,
+	})
+
+	sendMessage(bot, tgbotapi.NewMessage(chatID, formatVendorShortlist(lang, shortlist)))
+	delete(userStates, chatID)
+}
+
+func handleVendorPriorityCallback(bot *tgbotapi.BotAPI, chatID int64, callbackData string) {
+	state := userStates[chatID]
+
+	parts := strings.Split(callbackData, "_")
+	if len(parts) != 3 {
+		return
+	}
+	index, indexErr := strconv.Atoi(parts[1])
+	priority, priorityErr := strconv.Atoi(parts[2])
+	if indexErr != nil || priorityErr != nil || index < 0 || index >= len(vendorCriteria) ||
+		!scaleContains(priorityScale, priority) {
+		logger.Printf("Некорректный приоритет поставщиков: %s", callbackData)
+		return
+	}
+
+	state.VendorPriorities[vendorCriteria[index].Name] = priority
+	logger.LogTelegramAction("Установлен приоритет поставщиков", map[string]interface{}{
+		"Критерий":  vendorCriteria[index].Name,
+		"Приоритет": priority,
+	})
+
+	askVendorPriority(bot, chatID)
+}
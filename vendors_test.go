@@ -0,0 +1,28 @@
+package main
+
+import (
+	"fmt"
+	"testing"
+)
+
+func TestVendorPriorityCallbackRejectsInvalidValues(t *testing.T) {
+	logger = NewLogger(true)
+	const chatID = int64(-2)
+	state := newUserState()
+	state.VendorPriorities = make(map[string]int)
+	userStates[chatID] = state
+	t.Cleanup(func() { delete(userStates, chatID) })
+
+	for _, action := range []string{
+		fmt.Sprintf("vprio_0_%d", priorityScale.Min-1),
+		fmt.Sprintf("vprio_0_%d", priorityScale.Max+1),
+		"vprio_0_x",
+		fmt.Sprintf("vprio_%d_3", len(vendorCriteria)),
+		"vprio_-1_3",
+	} {
+		handleVendorPriorityCallback(nil, chatID, action)
+		if len(state.VendorPriorities) != 0 {
+			t.Fatalf("после %q приоритеты = %v, ожидался отказ", action, state.VendorPriorities)
+		}
+	}
+}
@@ -0,0 +1,114 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+)
+
+const recommendationNoOptions = "Нет допустимых вариантов"
+
+// VetoRule — жесткое ограничение каталога. Правило срабатывает, если критерий выбран и
+// его приоритет не ниже MinPriority (для весов AHP — вес не ниже MinWeight) либо
+// специальное значение критерия равно SpecialValue.
+type VetoRule struct {
+	ID           string                `json:"id"`
+	Criterion    string                `json:"criterion"`
+	MinPriority  int                   `json:"min_priority,omitempty"`
+	MinWeight    float64               `json:"min_weight,omitempty"`
+	SpecialValue string                `json:"special_value,omitempty"`
+	Exclude      []string              `json:"exclude"`
+	Reason       string                `json:"reason"`
+	Translations map[Lang]VetoRuleText `json:"translations,omitempty"`
+}
+
+type VetoRuleText struct {
+	Reason string `json:"reason"`
+}
+
+func (rule VetoRule) matches(lang Lang, detail CriterionDetail, specialValues map[string]string) (string, bool) {
+	if rule.SpecialValue != "" {
+		value := specialValues[rule.Criterion]
+		if strings.EqualFold(value, rule.SpecialValue) {
+			return tr(lang, "значение «%s»", specialValueLabel(lang, rule.Criterion, value)), true
+		}
+		return "", false
+	}
+
+	if detail.Priority == 0 {
+		if rule.MinWeight > 0 && detail.Weight >= rule.MinWeight {
+			return tr(lang, "вес %.1f%%", detail.Weight*100), true
+		}
+		return "", false
+	}
+
+	if rule.MinPriority > 0 && detail.Priority >= rule.MinPriority {
+		return tr(lang, "приоритет %d", detail.Priority), true
+	}
+	return "", false
+}
+
+func (rule VetoRule) reason(lang Lang) string {
+	return firstNonEmpty(rule.Translations[lang].Reason, rule.Reason)
+}
+
+// applyVetoRules возвращает варианты развертывания, исключенные правилами каталога.
+// Каждый вариант указывается один раз — по первому сработавшему правилу; условие и
+// причина исключения выводятся на языке lang.
+func applyVetoRules(lang Lang, details []CriterionDetail, specialValues map[string]string) []ExcludedOption {
+	var exclusions []ExcludedOption
+	seen := make(map[string]bool)
+
+	for _, rule := range vetoRules {
+		for _, detail := range details {
+			if detail.Name != rule.Criterion {
+				continue
+			}
+
+			condition, ok := rule.matches(lang, detail, specialValues)
+			if !ok {
+				continue
+			}
+
+			for _, id := range rule.Exclude {
+				option, known := findDeploymentOption(id)
+				if !known || seen[id] {
+					continue
+				}
+				seen[id] = true
+				exclusions = append(exclusions, ExcludedOption{
+					OptionID:    option.ID,
+					Option:      option.Name,
+					RuleID:      rule.ID,
+					CriterionID: criterionID(rule.Criterion),
+					Criterion:   rule.Criterion,
+					Condition:   condition,
+					Reason:      rule.reason(lang),
+				})
+			}
+		}
+	}
+
+	return exclusions
+}
+
+func excludedMask(exclusions []ExcludedOption) []bool {
+	mask := make([]bool, len(deploymentOptions))
+	for _, exclusion := range exclusions {
+		for option, deployment := range deploymentOptions {
+			if deployment.ID == exclusion.OptionID {
+				mask[option] = true
+			}
+		}
+	}
+	return mask
+}
+
+func formatExclusions(lang Lang, exclusions []ExcludedOption) string {
+	var text strings.Builder
+	text.WriteString(tr(lang, "Исключены жёсткими ограничениями:") + "\n")
+	for _, exclusion := range exclusions {
+		text.WriteString(fmt.Sprintf("• %s — «%s» (%s): %s\n",
+			exclusion.Option, criterionLabel(lang, exclusion.Criterion), exclusion.Condition, exclusion.Reason))
+	}
+	return strings.TrimRight(text.String(), "\n")
+}
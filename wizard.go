@@ -0,0 +1,293 @@
+package main
+
+import (
+	"strings"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+const (
+	stateIdle         WizardState = "idle"
+	stateCriteria     WizardState = "criteria"
+	statePriorities   WizardState = "priorities"
+	stateAHP          WizardState = "ahp"
+	stateSpecial      WizardState = "special_values"
+	stateFollowUps    WizardState = "follow_ups"
+	stateCost         WizardState = "cost"
+	stateOverrideAsk  WizardState = "override_question"
+	stateOverrideEdit WizardState = "override_edit"
+	stateResult       WizardState = "result"
+	stateTieBreak     WizardState = "tie_break"
+	stateReview       WizardState = "review"
+	stateProfileName  WizardState = "profile_name"
+	stateVendors      WizardState = "vendor_priorities"
+)
+
+// wizardStep описывает состояние чеклиста:
+//   - enter показывает вопрос состояния; если вопрос не нужен (например, нет специальных
+//     критериев), enter сразу переходит к следующему состоянию;
+//   - callbacks — префиксы кнопок, которые принимает handle, остальные кнопки отклоняются;
+//   - text обрабатывает текстовый ввод, nil — текст в этом состоянии игнорируется;
+//   - next — состояния, в которые разрешен переход;
+//   - reset стирает ответы состояния при возврате на шаг назад, чтобы вопрос задавался заново.
+type wizardStep struct {
+	enter     func(bot *tgbotapi.BotAPI, chatID int64)
+	callbacks []string
+	handle    func(bot *tgbotapi.BotAPI, chatID int64, callbackData string)
+	text      func(bot *tgbotapi.BotAPI, chatID int64, text string)
+	next      []WizardState
+	reset     func(state *UserState)
+}
+
+var wizard map[WizardState]wizardStep
+
+// Таблица заполняется в init: обработчики состояний сами вызывают transition,
+// и инициализация переменной напрямую образовала бы цикл.
+func init() {
+	wizard = map[WizardState]wizardStep{
+		stateIdle: {
+			next: []WizardState{stateCriteria, stateReview, stateProfileName, stateVendors},
+		},
+		stateCriteria: {
+			enter:     showCriteriaButtons,
+			callbacks: []string{"crit_", "done_criteria"},
+			handle:    handleCriteriaCallback,
+			next:      []WizardState{statePriorities},
+		},
+		statePriorities: {
+			enter:     enterPriorities,
+			callbacks: []string{"wmode_", "prio_"},
+			handle:    handlePriorityCallback,
+			next:      []WizardState{stateAHP, stateSpecial},
+			reset: func(state *UserState) {
+				state.Weighting = ""
+				state.CriteriaPriorities = make(map[string]int)
+				state.CriteriaWeights = nil
+				state.AHPJudgments = nil
+			},
+		},
+		stateAHP: {
+			enter:     startAHP,
+			callbacks: []string{"ahp_", "ahprev_"},
+			handle:    handleAHPCallback,
+			next:      []WizardState{stateSpecial},
+			reset: func(state *UserState) {
+				state.CriteriaWeights = nil
+				state.AHPJudgments = nil
+			},
+		},
+		stateSpecial: {
+			enter:     enterSpecialValues,
+			callbacks: []string{"spec_"},
+			handle:    handleSpecialValueCallback,
+			next:      []WizardState{stateFollowUps},
+			reset:     func(state *UserState) { state.SpecialValues = make(map[string]string) },
+		},
+		stateFollowUps: {
+			enter:     askFollowUps,
+			callbacks: []string{"fup_"},
+			handle:    handleFollowUpCallback,
+			next:      []WizardState{stateCost},
+			reset:     func(state *UserState) { state.FollowUpAnswers = make(map[string]string) },
+		},
+		stateCost: {
+			enter:     askCostEstimate,
+			callbacks: []string{"cost_"},
+			handle:    handleCostCallback,
+			next:      []WizardState{stateOverrideAsk},
+			reset:     func(state *UserState) { state.Cost = nil },
+		},
+		stateOverrideAsk: {
+			enter:     askOverride,
+			callbacks: []string{"override_yes", "override_no"},
+			handle:    handleOverrideCallback,
+			next:      []WizardState{stateOverrideEdit, stateResult},
+		},
+		stateOverrideEdit: {
+			enter:     showOverrideCriteriaList,
+			callbacks: []string{"override_select_", "override_cancel", "override_done", "weight_"},
+			handle:    handleOverrideCallback,
+			text:      remindOverrideButtons,
+			next:      []WizardState{stateResult},
+			reset:     func(state *UserState) { state.OverriddenScores = make(map[string]Scores) },
+		},
+		stateResult: {
+			enter: calcAndShowResult,
+			next:  []WizardState{stateTieBreak},
+		},
+		stateTieBreak: {
+			enter:     askTieBreakQuestion,
+			callbacks: []string{"tieb_"},
+			handle:    handleTieBreakCallback,
+			next:      []WizardState{stateResult},
+			reset:     func(state *UserState) { state.TieBreakAnswer = "" },
+		},
+		stateReview: {
+			enter:     showReview,
+			callbacks: []string{"review_", "override_yes"},
+			handle:    handleReviewCallback,
+			next:      []WizardState{stateCriteria, statePriorities, stateSpecial, stateFollowUps, stateOverrideEdit, stateResult},
+		},
+		stateProfileName: {
+			enter: askProfileName,
+			text:  saveProfileFromMessage,
+		},
+		stateVendors: {
+			enter:     askVendorPriority,
+			callbacks: []string{"vprio_"},
+			handle:    handleVendorPriorityCallback,
+		},
+	}
+}
+
+// newUserState создает пустое состояние чеклиста.
+func newUserState() *UserState {
+	return &UserState{
+		State:              stateIdle,
+		SelectedCriteria:   []string{},
+		CriteriaPriorities: make(map[string]int),
+		OverriddenScores:   make(map[string]Scores),
+		SpecialValues:      make(map[string]string),
+		FollowUpAnswers:    make(map[string]string),
+	}
+}
+
+// transition переводит чеклист в состояние to и показывает его вопрос.
+// Переход, не описанный в таблице wizard, не выполняется.
+func transition(bot *tgbotapi.BotAPI, chatID int64, to WizardState) {
+	state := userStates[chatID]
+	if state == nil {
+		logger.Printf("Переход в %s без состояния для chatID %d", to, chatID)
+		return
+	}
+
+	from := state.State
+	if !containsState(wizard[from].next, to) {
+		logger.Printf("Недопустимый переход %s → %s для chatID %d", from, to, chatID)
+		return
+	}
+
+	logger.LogTelegramAction("Переход чеклиста", map[string]interface{}{
+		"ChatID": chatID,
+		"Из":     from,
+		"В":      to,
+	})
+
+	enterState(bot, chatID, state, to)
+}
+
+// enterState показывает вопрос состояния to. Если после этого чеклист остался в to
+// (вопрос задан и ждет ответа), состояние записывается в историю для шага назад.
+func enterState(bot *tgbotapi.BotAPI, chatID int64, state *UserState, to WizardState) {
+	state.State = to
+	if enter := wizard[to].enter; enter != nil {
+		enter(bot, chatID)
+	}
+	if state.State == to && (wizard[to].handle != nil || wizard[to].text != nil) {
+		state.History = append(state.History, to)
+	}
+}
+
+// goBack возвращает чеклист к предыдущему вопросу: ответы текущего и предыдущего шагов
+// стираются, предыдущий вопрос задается заново. Возвращает false, если возвращаться некуда.
+func goBack(bot *tgbotapi.BotAPI, chatID int64) bool {
+	state := userStates[chatID]
+	if state == nil || len(state.History) < 2 {
+		return false
+	}
+
+	current := state.History[len(state.History)-1]
+	target := state.History[len(state.History)-2]
+	state.History = state.History[:len(state.History)-2]
+	for _, s := range []WizardState{current, target} {
+		if reset := wizard[s].reset; reset != nil {
+			reset(state)
+		}
+	}
+
+	logger.LogTelegramAction("Шаг назад", map[string]interface{}{
+		"ChatID": chatID,
+		"Из":     current,
+		"В":      target,
+	})
+
+	enterState(bot, chatID, state, target)
+	return true
+}
+
+// dispatchCallback передает кнопку обработчику текущего состояния. Кнопки других
+// состояний (например, из старых сообщений) отклоняются с подсказкой.
+func dispatchCallback(bot *tgbotapi.BotAPI, chatID int64, callbackData string) {
+	state := userStates[chatID]
+	step := wizard[state.State]
+
+	if step.handle == nil || !hasAnyPrefix(callbackData, step.callbacks) {
+		logger.Printf("Кнопка %q не относится к состоянию %s для chatID %d", callbackData, state.State, chatID)
+		sendMessage(bot, tgbotapi.NewMessage(chatID,
+			tr(userLang(chatID), "Эта кнопка относится к другому шагу чеклиста. Ответьте на текущий вопрос или начните заново с /start.")))
+		return
+	}
+
+	step.handle(bot, chatID, callbackData)
+}
+
+// dispatchText передает текстовое сообщение обработчику текущего состояния.
+func dispatchText(bot *tgbotapi.BotAPI, chatID int64, text string) {
+	if step := wizard[userStates[chatID].State]; step.text != nil {
+		step.text(bot, chatID, text)
+	}
+}
+
+func containsState(states []WizardState, target WizardState) bool {
+	for _, s := range states {
+		if s == target {
+			return true
+		}
+	}
+	return false
+}
+
+func hasAnyPrefix(s string, prefixes []string) bool {
+	for _, prefix := range prefixes {
+		if strings.HasPrefix(s, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+// enterPriorities выбирает способ задания важности: если она уже задана для всех
+// критериев, чеклист идет дальше, при выбранном AHP — к попарным сравнениям.
+func enterPriorities(bot *tgbotapi.BotAPI, chatID int64) {
+	state := userStates[chatID]
+
+	switch {
+	case prioritiesComplete(state):
+		transition(bot, chatID, stateSpecial)
+	case state.Weighting == weightingAHP && len(state.SelectedCriteria) >= 2:
+		transition(bot, chatID, stateAHP)
+	case state.Weighting == "" && len(state.SelectedCriteria) >= 2:
+		askWeightingMode(bot, chatID)
+	default:
+		state.Weighting = weightingPriority
+		startPrioritySelection(bot, chatID)
+	}
+}
+
+func prioritiesComplete(state *UserState) bool {
+	return ahpWeightsComplete(state) ||
+		(state.Weighting != weightingAHP && len(state.CriteriaPriorities) == len(state.SelectedCriteria))
+}
+
+func enterSpecialValues(bot *tgbotapi.BotAPI, chatID int64) {
+	if !hasMissingSpecialValues(userStates[chatID]) {
+		transition(bot, chatID, stateFollowUps)
+		return
+	}
+	showSpecialCriteriaOptions(bot, chatID)
+}
+
+func remindOverrideButtons(bot *tgbotapi.BotAPI, chatID int64, text string) {
+	sendMessage(bot, tgbotapi.NewMessage(chatID, tr(userLang(chatID), "Пожалуйста, используйте кнопки для переопределения весов.")))
+	showOverrideCriteriaList(bot, chatID)
+}
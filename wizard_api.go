@@ -0,0 +1,466 @@
+package main
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+// stateMu защищает состояния чеклиста (userStates, userLangs) и веб-сессии: бот и HTTP API
+// работают с одной машиной состояний из разных горутин.
+var stateMu sync.Mutex
+
+// Сообщения Telegram имеют положительные идентификаторы, сообщения веб-сессии — отрицательные.
+func isTelegramMessage(id int) bool { return id > 0 }
+
+func isWebMessage(id int) bool { return id < 0 }
+
+// keepMessageIDs забывает сообщения с кнопками, отправленные в другой канал, чтобы вопрос
+// был показан заново новым сообщением, а не правкой сообщения, которого здесь нет.
+func (s *UserState) keepMessageIDs(keep func(id int) bool) {
+	for _, id := range []*int{
+		&s.CriteriaMessageID, &s.PriorityMessageID, &s.SpecialMessageID,
+		&s.OverrideMessageID, &s.AHPMessageID, &s.CostMessageID,
+	} {
+		if *id != 0 && !keep(*id) {
+			*id = 0
+		}
+	}
+}
+
+// wizardSessionTTL — время жизни веб-сессии без запросов.
+const wizardSessionTTL = 2 * time.Hour
+
+// handoffTokenTTL — срок действия кода, которым чеклист из Telegram продолжается на сайте.
+const handoffTokenTTL = 10 * time.Minute
+
+type handoffToken struct {
+	chatID  int64
+	expires time.Time
+}
+
+// handoffTokens — выданные ботом коды передачи чеклиста; у чата действует только последний код.
+var handoffTokens = make(map[string]handoffToken)
+
+// issueHandoffToken выдает чату chatID одноразовый код передачи чеклиста на сайт.
+func issueHandoffToken(chatID int64, now time.Time) (string, error) {
+	for token, h := range handoffTokens {
+		if h.chatID == chatID || now.After(h.expires) {
+			delete(handoffTokens, token)
+		}
+	}
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	token := hex.EncodeToString(b)
+	handoffTokens[token] = handoffToken{chatID: chatID, expires: now.Add(handoffTokenTTL)}
+	return token, nil
+}
+
+// redeemHandoffToken погашает код и возвращает чат, которому он выдан.
+func redeemHandoffToken(token string, now time.Time) (int64, bool) {
+	h, ok := handoffTokens[token]
+	delete(handoffTokens, token)
+	if !ok || now.After(h.expires) {
+		return 0, false
+	}
+	return h.chatID, true
+}
+
+// sendHandoffToken отвечает на команду /web кодом для продолжения чеклиста на сайте.
+func sendHandoffToken(bot *tgbotapi.BotAPI, chatID int64) {
+	lang := userLang(chatID)
+	token, err := issueHandoffToken(chatID, time.Now())
+	if err != nil {
+		logger.Printf("Ошибка генерации кода передачи чеклиста для chatID %d: %v", chatID, err)
+		sendMessage(bot, tgbotapi.NewMessage(chatID, tr(lang, "Не удалось выдать код, попробуйте еще раз.")))
+		return
+	}
+
+	logger.LogTelegramAction("Выдан код передачи чеклиста на сайт", map[string]interface{}{
+		"ChatID": chatID,
+	})
+	msg := tgbotapi.NewMessage(chatID, tr(lang,
+		"Код для продолжения чеклиста на сайте: `%s`\nКод действует %d минут и подходит один раз. Никому его не сообщайте.",
+		token, int(handoffTokenTTL.Minutes())))
+	msg.ParseMode = "Markdown"
+	sendSecretMessage(bot, msg, token)
+}
+
+// wizardSession — веб-сессия чеклиста. Состояние чеклиста хранится в userStates, как у бота;
+// сессия хранит сообщения, которые бот отправил бы в чат.
+type wizardSession struct {
+	id       string
+	chatID   int64
+	webOnly  bool
+	messages map[int]*WizardMessage
+	lastID   int
+	screen   []int
+	touched  []int
+	result   *RecommendationResponse
+	answerID int64
+	updated  time.Time
+	// client — клиент API, начавший сессию; сессия доступна только с его ключом.
+	client *apiClient
+}
+
+var (
+	wizardSessions = make(map[string]*wizardSession)
+	// wizardOutputs — сессии, в которые на время запроса API перенаправлены сообщения бота.
+	wizardOutputs = make(map[int64]*wizardSession)
+)
+
+func newWizardSessionID() string {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		logger.Printf("Ошибка генерации идентификатора сессии: %v", err)
+		return hex.EncodeToString([]byte(time.Now().Format(time.RFC3339Nano)))
+	}
+	return hex.EncodeToString(b)
+}
+
+// cleanupWizardSessions удаляет просроченные сессии вместе с состояниями чеклистов,
+// начатых на сайте.
+func cleanupWizardSessions(now time.Time) {
+	for id, session := range wizardSessions {
+		if now.Sub(session.updated) > wizardSessionTTL {
+			session.close()
+			delete(wizardSessions, id)
+		}
+	}
+}
+
+// allows — клиент client может обращаться к сессии.
+func (s *wizardSession) allows(client *apiClient) bool {
+	return s.client == nil || client != nil && client.keyID == s.client.keyID
+}
+
+func (s *wizardSession) close() {
+	if s.webOnly {
+		delete(userStates, s.chatID)
+		delete(userLangs, s.chatID)
+	}
+}
+
+// run выполняет шаг машины состояний, перехватывая сообщения бота. Экран сессии — сообщения,
+// отправленные или измененные на этом шаге; если шаг ничего не показал, экран прежний.
+func (s *wizardSession) run(step func()) {
+	wizardOutputs[s.chatID] = s
+	defer delete(wizardOutputs, s.chatID)
+
+	s.touched = nil
+	step()
+	if len(s.touched) > 0 {
+		s.screen = s.touched
+	}
+	s.updated = time.Now()
+}
+
+func (s *wizardSession) touch(id int) {
+	for _, touched := range s.touched {
+		if touched == id {
+			return
+		}
+	}
+	s.touched = append(s.touched, id)
+}
+
+func (s *wizardSession) message(id int) *WizardMessage {
+	message := s.messages[id]
+	if message == nil {
+		message = &WizardMessage{ID: id}
+		s.messages[id] = message
+	}
+	s.touch(id)
+	return message
+}
+
+func (s *wizardSession) send(msg tgbotapi.MessageConfig) tgbotapi.Message {
+	s.lastID--
+	message := s.message(s.lastID)
+	message.Text, message.ParseMode = msg.Text, msg.ParseMode
+	switch markup := msg.ReplyMarkup.(type) {
+	case tgbotapi.InlineKeyboardMarkup:
+		message.Buttons = wizardButtons(&markup)
+	case *tgbotapi.InlineKeyboardMarkup:
+		message.Buttons = wizardButtons(markup)
+	}
+	return tgbotapi.Message{MessageID: s.lastID, Chat: &tgbotapi.Chat{ID: s.chatID}, Text: msg.Text}
+}
+
+// editText, как и Telegram, убирает кнопки, если новая разметка не передана.
+func (s *wizardSession) editText(msg tgbotapi.EditMessageTextConfig) tgbotapi.Message {
+	message := s.message(msg.MessageID)
+	message.Text, message.ParseMode = msg.Text, msg.ParseMode
+	message.Buttons = wizardButtons(msg.ReplyMarkup)
+	return tgbotapi.Message{MessageID: msg.MessageID, Chat: &tgbotapi.Chat{ID: s.chatID}, Text: msg.Text}
+}
+
+func (s *wizardSession) editMarkup(msg tgbotapi.EditMessageReplyMarkupConfig) tgbotapi.Message {
+	message := s.message(msg.MessageID)
+	message.Buttons = wizardButtons(msg.ReplyMarkup)
+	return tgbotapi.Message{MessageID: msg.MessageID, Chat: &tgbotapi.Chat{ID: s.chatID}, Text: message.Text}
+}
+
+func wizardButtons(markup *tgbotapi.InlineKeyboardMarkup) [][]WizardButton {
+	if markup == nil {
+		return nil
+	}
+	var rows [][]WizardButton
+	for _, row := range markup.InlineKeyboard {
+		var buttons []WizardButton
+		for _, button := range row {
+			if button.CallbackData != nil {
+				buttons = append(buttons, WizardButton{Text: button.Text, Action: *button.CallbackData})
+			}
+		}
+		if len(buttons) > 0 {
+			rows = append(rows, buttons)
+		}
+	}
+	return rows
+}
+
+// answers — кнопки экрана, которые принимает текущее состояние чеклиста.
+func (s *wizardSession) answers() []WizardButton {
+	answers := []WizardButton{}
+	state := userStates[s.chatID]
+	if state == nil {
+		return answers
+	}
+	step := wizard[state.State]
+	if step.handle == nil {
+		return answers
+	}
+	for _, id := range s.screen {
+		for _, row := range s.messages[id].Buttons {
+			for _, button := range row {
+				if hasAnyPrefix(button.Action, step.callbacks) {
+					answers = append(answers, button)
+				}
+			}
+		}
+	}
+	return answers
+}
+
+func (s *wizardSession) view() WizardView {
+	view := WizardView{
+		SessionID: s.id,
+		State:     stateIdle,
+		Messages:  []WizardMessage{},
+		Answers:   s.answers(),
+		Result:    s.result,
+		AnswerID:  s.answerID,
+		Language:  userLang(s.chatID),
+	}
+	if !s.webOnly {
+		view.UserID = s.chatID
+	}
+	for _, id := range s.screen {
+		view.Messages = append(view.Messages, *s.messages[id])
+	}
+
+	state := userStates[s.chatID]
+	if state == nil || state.State == stateIdle {
+		view.Finished = true
+		return view
+	}
+	view.State = state.State
+	view.AcceptsText = wizard[state.State].text != nil
+	view.CanGoBack = len(state.History) >= 2
+	view.CanFinalize = canFinalize(state)
+	return view
+}
+
+// canFinalize — из текущего состояния разрешен переход к расчету результата.
+func canFinalize(state *UserState) bool {
+	return containsState(wizard[state.State].next, stateResult)
+}
+
+// startWizardSession начинает новый чеклист или продолжает чеклист пользователя Telegram userID,
+// предъявившего код передачи.
+func startWizardSession(lang Lang, userID int64, client *apiClient) *wizardSession {
+	now := time.Now()
+	cleanupWizardSessions(now)
+
+	session := &wizardSession{
+		id:       newWizardSessionID(),
+		chatID:   userID,
+		messages: make(map[int]*WizardMessage),
+		updated:  now,
+		client:   client,
+	}
+	if userID == 0 {
+		// Отрицательные chat ID у Telegram только у групп, и они по модулю много меньше.
+		session.chatID = -now.UnixNano()
+		session.webOnly = true
+		userLangs[session.chatID] = lang
+	}
+	wizardSessions[session.id] = session
+
+	session.run(func() {
+		state := userStates[session.chatID]
+		// Результат, который еще отправляется в Telegram, на сайт не переносится.
+		if state == nil || state.State == stateIdle || state.State == stateResult {
+			userStates[session.chatID] = newUserState()
+			transition(nil, session.chatID, stateCriteria)
+			return
+		}
+
+		// Чеклист начат в Telegram: текущий вопрос показывается заново на сайте.
+		state.keepMessageIDs(isWebMessage)
+		if n := len(state.History); n > 0 && state.History[n-1] == state.State {
+			state.History = state.History[:n-1]
+		}
+		enterState(nil, session.chatID, state, state.State)
+	})
+
+	logger.LogTelegramAction("Создана веб-сессия чеклиста", map[string]interface{}{
+		"SessionID": session.id,
+		"ChatID":    session.chatID,
+		"Состояние": userStates[session.chatID].State,
+	})
+	return session
+}
+
+// wizardSessionsHandler обслуживает веб-сессии чеклиста (пути относительно префикса версии API):
+//   - POST /wizard/sessions — начать сессию;
+//   - GET /wizard/sessions/{id} — текущий вопрос;
+//   - POST /wizard/sessions/{id}/answer — ответить кнопкой или текстом;
+//   - POST /wizard/sessions/{id}/back — вернуться к предыдущему вопросу;
+//   - POST /wizard/sessions/{id}/finalize — рассчитать результат;
+//   - DELETE /wizard/sessions/{id} — завершить сессию.
+func wizardSessionsHandler(w http.ResponseWriter, r *http.Request) {
+	lang := requestLang(r)
+	w.Header().Set("Content-Language", string(lang))
+
+	_, path, _ := strings.Cut(r.URL.Path, "/wizard/sessions")
+	path = strings.Trim(path, "/")
+	if path == "" {
+		if r.Method != "POST" {
+			http.Error(w, tr(lang, "Метод не поддерживается"), http.StatusMethodNotAllowed)
+			return
+		}
+		var req WizardSessionRequest
+		if r.ContentLength != 0 {
+			if errs := decodeJSONBody(lang, r, &req); len(errs) > 0 {
+				writeValidationErrors(w, lang, errs)
+				return
+			}
+		}
+
+		stateMu.Lock()
+		defer stateMu.Unlock()
+		var userID int64
+		if req.HandoffToken != "" {
+			chatID, ok := redeemHandoffToken(req.HandoffToken, time.Now())
+			if !ok {
+				writeValidationErrors(w, lang, []FieldError{{Path: "handoff_token",
+					Message: tr(lang, "код недействителен или просрочен, получите новый командой бота /web")}})
+				return
+			}
+			userID = chatID
+		}
+		writeJSON(w, http.StatusCreated, startWizardSession(lang, userID, apiClientFrom(r)).view())
+		return
+	}
+
+	id, action, _ := strings.Cut(path, "/")
+
+	stateMu.Lock()
+	defer stateMu.Unlock()
+
+	session := wizardSessions[id]
+	if session != nil && time.Since(session.updated) > wizardSessionTTL {
+		session.close()
+		delete(wizardSessions, id)
+		session = nil
+	}
+	if session == nil || !session.allows(apiClientFrom(r)) {
+		http.Error(w, tr(lang, "Сессия не найдена"), http.StatusNotFound)
+		return
+	}
+
+	switch {
+	case action == "" && r.Method == "GET":
+		session.updated = time.Now()
+		writeJSON(w, http.StatusOK, session.view())
+	case action == "" && r.Method == "DELETE":
+		session.close()
+		delete(wizardSessions, id)
+		w.WriteHeader(http.StatusNoContent)
+	case action == "answer" && r.Method == "POST":
+		wizardAnswer(w, r, lang, session)
+	case action == "back" && r.Method == "POST":
+		if state := userStates[session.chatID]; state == nil || len(state.History) < 2 {
+			http.Error(w, tr(lang, "Возвращаться некуда"), http.StatusConflict)
+			return
+		}
+		session.run(func() { goBack(nil, session.chatID) })
+		writeJSON(w, http.StatusOK, session.view())
+	case action == "finalize" && r.Method == "POST":
+		if state := userStates[session.chatID]; state == nil || !canFinalize(state) {
+			http.Error(w, tr(lang, "Расчет недоступен: сначала ответьте на вопросы чеклиста"), http.StatusConflict)
+			return
+		}
+		session.run(func() { transition(nil, session.chatID, stateResult) })
+		finishResults()
+		writeJSON(w, http.StatusOK, session.view())
+	case action == "" || action == "answer" || action == "back" || action == "finalize":
+		http.Error(w, tr(lang, "Метод не поддерживается"), http.StatusMethodNotAllowed)
+	default:
+		http.NotFound(w, r)
+	}
+}
+
+// wizardAnswer передает ответ машине состояний так же, как нажатие кнопки или сообщение в Telegram.
+func wizardAnswer(w http.ResponseWriter, r *http.Request, lang Lang, session *wizardSession) {
+	var req WizardAnswerRequest
+	if errs := decodeJSONBody(lang, r, &req); len(errs) > 0 {
+		writeValidationErrors(w, lang, errs)
+		return
+	}
+
+	state := userStates[session.chatID]
+	if state == nil || state.State == stateIdle {
+		http.Error(w, tr(lang, "Чеклист завершен"), http.StatusConflict)
+		return
+	}
+
+	errs := &fieldErrors{lang: lang}
+	switch {
+	case (req.Action == "") == (req.Text == ""):
+		errs.add("", "укажите action или text")
+	case req.Action != "":
+		accepted := false
+		for _, answer := range session.answers() {
+			accepted = accepted || answer.Action == req.Action
+		}
+		if !accepted {
+			errs.add("action", "ответ не относится к текущему вопросу")
+		}
+	case wizard[state.State].text == nil:
+		errs.add("text", "текущий вопрос не принимает текстовый ответ")
+	}
+	if len(errs.list) > 0 {
+		writeValidationErrors(w, lang, errs.list)
+		return
+	}
+
+	session.run(func() {
+		if req.Action != "" {
+			dispatchCallback(nil, session.chatID, req.Action)
+		} else {
+			dispatchText(nil, session.chatID, req.Text)
+		}
+	})
+	finishResults()
+	writeJSON(w, http.StatusOK, session.view())
+}
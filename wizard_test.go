@@ -0,0 +1,236 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+// newTestSession начинает веб-сессию чеклиста: сообщения бота перехватываются сессией,
+// и машину состояний можно прогонять без Telegram.
+func newTestSession(t *testing.T) *wizardSession {
+	t.Helper()
+	logger = NewLogger(true)
+	session := startWizardSession(langRU, 0, nil)
+	t.Cleanup(func() {
+		session.close()
+		delete(wizardSessions, session.id)
+	})
+	return session
+}
+
+func TestWizardTableIsConsistent(t *testing.T) {
+	for from, step := range wizard {
+		for _, to := range step.next {
+			if _, ok := wizard[to]; !ok {
+				t.Errorf("переход %s → %s ведет в состояние, которого нет в таблице", from, to)
+			}
+		}
+		if step.handle != nil && len(step.callbacks) == 0 {
+			t.Errorf("состояние %s принимает кнопки, но не перечисляет их префиксы", from)
+		}
+	}
+}
+
+func TestWizardRejectsInvalidTransitions(t *testing.T) {
+	tests := []struct {
+		from, to WizardState
+	}{
+		{stateIdle, stateResult},
+		{stateIdle, statePriorities},
+		{stateCriteria, stateResult},
+		{stateCriteria, stateCost},
+		{statePriorities, stateCriteria},
+		{stateSpecial, stateResult},
+		{stateCost, statePriorities},
+		{stateOverrideAsk, stateCriteria},
+		{stateOverrideEdit, stateOverrideAsk},
+		{stateResult, stateCriteria},
+		{stateTieBreak, stateCriteria},
+		{stateProfileName, stateResult},
+		{stateVendors, stateResult},
+	}
+
+	session := newTestSession(t)
+	for _, tt := range tests {
+		t.Run(string(tt.from)+"→"+string(tt.to), func(t *testing.T) {
+			if containsState(wizard[tt.from].next, tt.to) {
+				t.Fatalf("переход %s → %s разрешен таблицей", tt.from, tt.to)
+			}
+
+			state := newUserState()
+			state.State = tt.from
+			state.History = []WizardState{stateCriteria, tt.from}
+			userStates[session.chatID] = state
+
+			session.run(func() { transition(nil, session.chatID, tt.to) })
+
+			if state.State != tt.from {
+				t.Errorf("состояние = %s, ожидалось %s", state.State, tt.from)
+			}
+			if want := []WizardState{stateCriteria, tt.from}; !reflect.DeepEqual(state.History, want) {
+				t.Errorf("история = %v, ожидалось %v", state.History, want)
+			}
+			if len(session.touched) != 0 {
+				t.Errorf("недопустимый переход отправил сообщения: %v", session.touched)
+			}
+		})
+	}
+}
+
+func TestWizardWalk(t *testing.T) {
+	session := newTestSession(t)
+	state := userStates[session.chatID]
+
+	steps := []struct {
+		action  string
+		state   WizardState
+		history []WizardState
+	}{
+		{"crit_Юрисдикция данных", stateCriteria, []WizardState{stateCriteria}},
+		{"done_criteria", statePriorities, []WizardState{stateCriteria, statePriorities}},
+		// Особых значений и уточняющих вопросов у критерия нет: состояния пропускаются.
+		{"prio_Юрисдикция данных_5", stateCost, []WizardState{stateCriteria, statePriorities, stateCost}},
+		{"cost_no", stateOverrideAsk, []WizardState{stateCriteria, statePriorities, stateCost, stateOverrideAsk}},
+	}
+	for _, step := range steps {
+		session.run(func() { dispatchCallback(nil, session.chatID, step.action) })
+		if state.State != step.state || !reflect.DeepEqual(state.History, step.history) {
+			t.Fatalf("после %q: состояние %s, история %v; ожидалось %s, %v",
+				step.action, state.State, state.History, step.state, step.history)
+		}
+	}
+
+	// Кнопка из сообщения другого шага не меняет состояние.
+	session.run(func() { dispatchCallback(nil, session.chatID, "crit_Латентность") })
+	if state.State != stateOverrideAsk || contains(state.SelectedCriteria, "Латентность") {
+		t.Fatalf("кнопка другого шага принята: состояние %s, критерии %v", state.State, state.SelectedCriteria)
+	}
+
+	var back bool
+	session.run(func() { back = goBack(nil, session.chatID) })
+	if !back {
+		t.Fatal("шаг назад не выполнен")
+	}
+	if want := []WizardState{stateCriteria, statePriorities, stateCost}; state.State != stateCost || !reflect.DeepEqual(state.History, want) {
+		t.Fatalf("после шага назад: состояние %s, история %v; ожидалось %s, %v", state.State, state.History, stateCost, want)
+	}
+	if state.Cost != nil {
+		t.Errorf("ответ на вопрос о стоимости не сброшен: %+v", state.Cost)
+	}
+}
+
+func TestWizardRejectsInvalidPriority(t *testing.T) {
+	session := newTestSession(t)
+	state := userStates[session.chatID]
+	for _, action := range []string{"crit_Юрисдикция данных", "crit_Латентность", "done_criteria"} {
+		session.run(func() { dispatchCallback(nil, session.chatID, action) })
+	}
+
+	for _, action := range []string{
+		"prio_Начальные инвестиции_3", // критерий не выбран
+		"prio_Латентность_0",
+		"prio_Латентность_6",
+		"prio_Латентность_x",
+	} {
+		session.run(func() { dispatchCallback(nil, session.chatID, action) })
+		if state.State != statePriorities || len(state.CriteriaPriorities) != 0 {
+			t.Fatalf("после %q: состояние %s, приоритеты %v; ожидался отказ", action, state.State, state.CriteriaPriorities)
+		}
+		if view := session.view(); len(view.Answers) == 0 || !strings.HasPrefix(view.Answers[0].Action, "prio_Юрисдикция данных_") {
+			t.Errorf("после %q вопрос о приоритете не задан заново: %+v", action, view.Answers)
+		}
+	}
+
+	session.run(func() { dispatchCallback(nil, session.chatID, "prio_Латентность_2") })
+	if want := map[string]int{"Латентность": 2}; !reflect.DeepEqual(state.CriteriaPriorities, want) {
+		t.Errorf("приоритеты = %v, ожидалось %v", state.CriteriaPriorities, want)
+	}
+}
+
+func TestHandoffTokenIsOneTime(t *testing.T) {
+	now := time.Now()
+	token, err := issueHandoffToken(42, now)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if chatID, ok := redeemHandoffToken(token, now); !ok || chatID != 42 {
+		t.Fatalf("redeemHandoffToken = %d, %v; ожидалось 42, true", chatID, ok)
+	}
+	if _, ok := redeemHandoffToken(token, now); ok {
+		t.Error("код принят повторно")
+	}
+
+	expired, _ := issueHandoffToken(42, now)
+	if _, ok := redeemHandoffToken(expired, now.Add(handoffTokenTTL+time.Second)); ok {
+		t.Error("принят просроченный код")
+	}
+
+	first, _ := issueHandoffToken(42, now)
+	second, _ := issueHandoffToken(42, now)
+	if _, ok := redeemHandoffToken(first, now); ok {
+		t.Error("принят код, замененный новым")
+	}
+	if _, ok := redeemHandoffToken(second, now); !ok {
+		t.Error("последний код не принят")
+	}
+}
+
+func TestWizardSessionRejectsUserID(t *testing.T) {
+	logger = NewLogger(true)
+	for _, body := range []string{`{"user_id": 42}`, `{"handoff_token": "0123456789abcdef"}`} {
+		req := httptest.NewRequest("POST", "/api/v1/wizard/sessions", strings.NewReader(body))
+		rec := httptest.NewRecorder()
+		wizardSessionsHandler(rec, req)
+		if rec.Code != http.StatusBadRequest {
+			t.Errorf("%s: статус %d, ожидался 400", body, rec.Code)
+		}
+	}
+}
+
+func TestFlushOutboxReleasesStateMu(t *testing.T) {
+	logger = NewLogger(true)
+	var requests []string
+	lockedDuringRequest := false
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if stateMu.TryLock() {
+			stateMu.Unlock()
+		} else {
+			lockedDuringRequest = true
+		}
+		requests = append(requests, r.URL.Path[strings.LastIndex(r.URL.Path, "/")+1:])
+		w.Header().Set("Content-Type", "application/json")
+		fmt.Fprintf(w, `{"ok": true, "result": {"message_id": %d, "chat": {"id": 1}}}`, 40+len(requests))
+	}))
+	defer server.Close()
+	bot := &tgbotapi.BotAPI{Token: "test", Client: server.Client()}
+	bot.SetAPIEndpoint(server.URL + "/bot%s/%s")
+
+	state := newUserState()
+	stateMu.Lock()
+	sendMessage(bot, tgbotapi.NewMessage(1, "первое"))
+	sendTrackedMessage(bot, tgbotapi.NewMessage(1, "второе"), &state.CriteriaMessageID)
+	editMessageText(bot, tgbotapi.NewEditMessageText(1, 41, "первое, исправленное"))
+	if len(requests) != 0 || state.CriteriaMessageID != 0 {
+		stateMu.Unlock()
+		t.Fatalf("запросы к Telegram выполнены под stateMu: %v", requests)
+	}
+	flushOutbox()
+	stateMu.Unlock()
+
+	if want := []string{"sendMessage", "sendMessage", "editMessageText"}; !reflect.DeepEqual(requests, want) {
+		t.Errorf("запросы = %v, ожидалось %v", requests, want)
+	}
+	if lockedDuringRequest {
+		t.Error("stateMu удерживается во время запроса к Telegram")
+	}
+	if state.CriteriaMessageID != 42 {
+		t.Errorf("CriteriaMessageID = %d, ожидалось 42", state.CriteriaMessageID)
+	}
+}